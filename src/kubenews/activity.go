@@ -0,0 +1,115 @@
+package kubenews
+
+import (
+	"fmt"
+	"time"
+)
+
+// WeekActivity is the number of issues opened and closed in a week.
+type WeekActivity struct {
+	Week   time.Time `json:"week"`
+	Opened int       `json:"opened"`
+	Closed int       `json:"closed"`
+}
+
+// startOfWeek returns midnight UTC on the Monday of t's week.
+func startOfWeek(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// WeeklyActivity counts the issues opened and closed in each week from the
+// week containing from up to to. Weeks start on Monday.
+func WeeklyActivity(issues []Issue, from, to time.Time) []WeekActivity {
+	weeks := []WeekActivity{}
+	index := map[time.Time]int{}
+	for w := startOfWeek(from); w.Before(to); w = w.AddDate(0, 0, 7) {
+		index[w] = len(weeks)
+		weeks = append(weeks, WeekActivity{Week: w})
+	}
+
+	for _, issue := range issues {
+		if issue.CreatedAt != nil {
+			if i, ok := index[startOfWeek(*issue.CreatedAt)]; ok && issue.CreatedAt.Before(to) {
+				weeks[i].Opened++
+			}
+		}
+		if issue.ClosedAt != nil {
+			if i, ok := index[startOfWeek(*issue.ClosedAt)]; ok && issue.ClosedAt.Before(to) {
+				weeks[i].Closed++
+			}
+		}
+	}
+
+	return weeks
+}
+
+// svgChart is the geometry of a bar chart drawn as SVG.
+type svgChart struct {
+	Width  int
+	Height int
+	Bars   []svgBar
+	Labels []svgLabel
+	Max    int
+}
+
+type svgBar struct {
+	X, Y, Width, Height int
+	Class               string
+	Title               string
+}
+
+type svgLabel struct {
+	X, Y int
+	Text string
+}
+
+// newActivityChart lays out a chart of opened and closed issues per week as
+// pairs of bars.
+func newActivityChart(weeks []WeekActivity) svgChart {
+	const (
+		barWidth = 10
+		gap      = 6
+		plot     = 200
+		margin   = 20
+	)
+
+	c := svgChart{Height: plot + margin*2}
+	for _, w := range weeks {
+		if w.Opened > c.Max {
+			c.Max = w.Opened
+		}
+		if w.Closed > c.Max {
+			c.Max = w.Closed
+		}
+	}
+
+	scale := func(n int) int {
+		if c.Max == 0 {
+			return 0
+		}
+		return n * plot / c.Max
+	}
+
+	x := margin
+	for i, w := range weeks {
+		week := w.Week.Format(dateFormat)
+		h := scale(w.Opened)
+		c.Bars = append(c.Bars, svgBar{X: x, Y: margin + plot - h, Width: barWidth, Height: h, Class: "opened",
+			Title: fmt.Sprintf("week of %s: %d opened", week, w.Opened)})
+		h = scale(w.Closed)
+		c.Bars = append(c.Bars, svgBar{X: x + barWidth, Y: margin + plot - h, Width: barWidth, Height: h,
+			Class: "closed", Title: fmt.Sprintf("week of %s: %d closed", week, w.Closed)})
+
+		if i%4 == 0 {
+			c.Labels = append(c.Labels, svgLabel{X: x, Y: margin*2 + plot - 4, Text: w.Week.Format("Jan 2")})
+		}
+
+		x += barWidth*2 + gap
+	}
+
+	c.Width = x + margin
+	return c
+}
@@ -0,0 +1,799 @@
+package kubenews
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+)
+
+var (
+	// defaultPerPage is the page size when a request doesn't set per_page.
+	defaultPerPage = 30
+
+	// maxPerPage is the largest page size a request can ask for.
+	maxPerPage = 100
+)
+
+// IssueQuery selects issues. Empty fields don't filter.
+type IssueQuery struct {
+	Repository string
+	State      string
+	Labels     []string
+	Milestone  string
+	Assignee   string
+	// PullRequest selects pull requests if true or issues if false.
+	PullRequest *bool
+	// CreatedFrom and CreatedTo select issues created in a time range.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// UpdatedSince selects issues updated at or after a time.
+	UpdatedSince time.Time
+	// Filter is a query, e.g. a saved query from the config.
+	Filter *Query
+	// Sort is created, updated or number. Issues are sorted newest first.
+	Sort    string
+	Page    int
+	PerPage int
+}
+
+// where builds the WHERE clause for the query and its arguments.
+func (q IssueQuery) where() (string, []interface{}, error) {
+	b := &sqlBuilder{}
+
+	if q.Repository != "" {
+		b.add("repository = ?", q.Repository)
+	}
+	if q.State != "" {
+		b.add("state = ?", q.State)
+	}
+	for _, label := range q.Labels {
+		j, err := json.Marshal([]map[string]string{{"Name": label}})
+		if err != nil {
+			return "", nil, errors.Wrap(err, "encode label")
+		}
+		b.add("labels @> ?::jsonb", string(j))
+	}
+	if q.Milestone != "" {
+		b.add("milestone = ?", q.Milestone)
+	}
+	if q.Assignee != "" {
+		b.add("assignee = ?", q.Assignee)
+	}
+	if q.PullRequest != nil {
+		b.add("pull_request = ?", *q.PullRequest)
+	}
+	if !q.CreatedFrom.IsZero() {
+		b.add("created_at >= ?", q.CreatedFrom)
+	}
+	if !q.CreatedTo.IsZero() {
+		b.add("created_at < ?", q.CreatedTo)
+	}
+	if !q.UpdatedSince.IsZero() {
+		b.add("updated_at >= ?", q.UpdatedSince)
+	}
+	if q.Filter != nil {
+		q.Filter.where(b)
+	}
+
+	if len(b.conds) == 0 {
+		return "", b.args, nil
+	}
+
+	return " WHERE " + strings.Join(b.conds, " AND "), b.args, nil
+}
+
+func (q IssueQuery) orderBy() (string, error) {
+	switch q.Sort {
+	case "number", "":
+		return " ORDER BY number DESC", nil
+	case "created":
+		return " ORDER BY created_at DESC NULLS LAST, number DESC", nil
+	case "updated":
+		return " ORDER BY updated_at DESC NULLS LAST, number DESC", nil
+	default:
+		return "", errors.Errorf("unknown sort %q", q.Sort)
+	}
+}
+
+// pageSize returns the page and page size, with defaults applied.
+func (q IssueQuery) pageSize() (int, int) {
+	page, perPage := q.Page, q.PerPage
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+
+	return page, perPage
+}
+
+// Match returns true if an issue matches the query.
+func (q IssueQuery) Match(issue Issue) bool {
+	switch {
+	case q.Repository != "" && issue.Repository != q.Repository,
+		q.State != "" && issue.State != q.State,
+		q.Milestone != "" && issue.Milestone != q.Milestone,
+		q.Assignee != "" && issue.Assignee != q.Assignee,
+		q.PullRequest != nil && issue.PullRequest != *q.PullRequest,
+		!q.CreatedFrom.IsZero() && (issue.CreatedAt == nil || issue.CreatedAt.Before(q.CreatedFrom)),
+		!q.CreatedTo.IsZero() && (issue.CreatedAt == nil || !issue.CreatedAt.Before(q.CreatedTo)),
+		!q.UpdatedSince.IsZero() && (issue.UpdatedAt == nil || issue.UpdatedAt.Before(q.UpdatedSince)),
+		q.Filter != nil && !q.Filter.Match(issue):
+		return false
+	}
+
+	for _, label := range q.Labels {
+		if !hasLabelFilter(label).Match(issue) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sortsBefore orders issues as orderBy does.
+func (q IssueQuery) sortsBefore(a, b Issue) bool {
+	var at, bt *time.Time
+	switch q.Sort {
+	case "created":
+		at, bt = a.CreatedAt, b.CreatedAt
+	case "updated":
+		at, bt = a.UpdatedAt, b.UpdatedAt
+	}
+
+	switch {
+	case at == nil && bt == nil, at != nil && bt != nil && at.Equal(*bt):
+		return a.Number > b.Number
+	case at == nil:
+		return false
+	case bt == nil:
+		return true
+	}
+
+	return at.After(*bt)
+}
+
+// page returns a page of the issues matching the query, along with the
+// total number of matching issues.
+func (q IssueQuery) page(issues []Issue) ([]Issue, int, error) {
+	if _, err := q.orderBy(); err != nil {
+		return nil, 0, err
+	}
+
+	matched := FilterIssues(issues, q)
+	sort.SliceStable(matched, func(i, j int) bool { return q.sortsBefore(matched[i], matched[j]) })
+
+	page, perPage := q.pageSize()
+	start := (page - 1) * perPage
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := start + perPage
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	return matched[start:end], len(matched), nil
+}
+
+// QueryIssues retrieves a page of issues matching a query, along with the
+// total number of matching issues.
+func (s *PostgresStore) QueryIssues(q IssueQuery) ([]Issue, int, error) {
+	where, args, err := q.where()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	order, err := q.orderBy()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := s.DB.Get(&total, countIssuesSQL+where, args...); err != nil {
+		return nil, 0, errors.Wrap(err, "unable to count issues")
+	}
+
+	page, perPage := q.pageSize()
+	limit := fmt.Sprintf(" LIMIT %d OFFSET %d", perPage, (page-1)*perPage)
+
+	issues := []Issue{}
+	if err := s.DB.Select(&issues, selectIssuesSQL+where+order+limit, args...); err != nil {
+		return nil, 0, errors.Wrap(err, "unable to retrieve issues")
+	}
+
+	return issues, total, nil
+}
+
+// QueryIssues retrieves a page of issues matching a query, along with the
+// total number of matching issues. Queries compile to Postgres SQL, so the
+// issues of the repository are loaded and matched here instead.
+func (s *sqlStore) QueryIssues(q IssueQuery) ([]Issue, int, error) {
+	issues := []Issue{}
+	if err := s.selectx(&issues, queryIssuesSQL, q.Repository); err != nil {
+		return nil, 0, errors.Wrap(err, "unable to retrieve issues")
+	}
+
+	return q.page(issues)
+}
+
+// MilestoneSummary is the number of open and closed issues in a milestone.
+type MilestoneSummary struct {
+	Milestone string `db:"milestone" json:"milestone"`
+	Open      int    `db:"open" json:"open"`
+	Closed    int    `db:"closed" json:"closed"`
+}
+
+// Milestones summarizes the milestones in a repository.
+func (s *sqlStore) Milestones(repository string) ([]MilestoneSummary, error) {
+	milestones := []MilestoneSummary{}
+	if err := s.selectx(&milestones, milestonesSQL, repository); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve milestones")
+	}
+
+	return milestones, nil
+}
+
+// StoredLabel is a label in the labels table.
+type StoredLabel struct {
+	Name   string `db:"name" json:"name"`
+	URL    string `db:"url" json:"url"`
+	Color  string `db:"color" json:"color"`
+	Active bool   `db:"active" json:"active"`
+}
+
+// ActiveLabels retrieves the labels used by open issues.
+func (s *sqlStore) ActiveLabels() ([]StoredLabel, error) {
+	labels := []StoredLabel{}
+	if err := s.selectx(&labels, activeLabelsSQL); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve labels")
+	}
+
+	return labels, nil
+}
+
+// apiLabel is the JSON representation of a label.
+type apiLabel struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// apiIssue is the JSON representation of an issue. Labels are stored with
+// upper case keys, so they are converted rather than encoded directly.
+type apiIssue struct {
+	Issue
+	Labels  []apiLabel `json:"labels"`
+	HTMLURL string     `json:"html_url"`
+}
+
+func newAPIIssues(issues []Issue) []apiIssue {
+	out := []apiIssue{}
+	for _, issue := range issues {
+		a := apiIssue{Issue: issue, Labels: []apiLabel{}, HTMLURL: issue.HTMLURL()}
+		for _, label := range issue.Labels {
+			a.Labels = append(a.Labels, apiLabel{Name: label.Name, Color: label.Color})
+		}
+		out = append(out, a)
+	}
+
+	return out
+}
+
+// apiDigest is the JSON representation of a digest.
+type apiDigest struct {
+	Repository string         `json:"repository"`
+	SIG        string         `json:"sig,omitempty"`
+	From       time.Time      `json:"from"`
+	To         time.Time      `json:"to"`
+	Opened     []apiIssue     `json:"opened"`
+	Closed     []apiIssue     `json:"closed"`
+	OpenCount  int            `json:"open_count"`
+	SIGs       []SIGSummary   `json:"sigs"`
+	Metrics    *MetricsReport `json:"metrics,omitempty"`
+}
+
+// apiDigestDelivery is the JSON representation of a sent digest.
+type apiDigestDelivery struct {
+	Repository string    `json:"repository"`
+	List       string    `json:"list"`
+	SIG        string    `json:"sig,omitempty"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Title      string    `json:"title"`
+	Markdown   string    `json:"markdown"`
+	SentAt     time.Time `json:"sent_at"`
+}
+
+// API is a read only JSON API over the datastore. All endpoints require a repo
+// parameter, except labels, and issues, where it is an optional filter. Lists
+// are paginated with page and per_page and return the total in X-Total-Count
+// and page links in Link.
+//
+//	GET /api/v1/issues      state, label (repeatable), milestone, assignee,
+//	                        pull_request, created_from, created_to,
+//	                        updated_since, q (a query, see Query), sort
+//	GET /api/v1/labels
+//	GET /api/v1/milestones
+//	GET /api/v1/sigs        from, to
+//	GET /api/v1/metrics     from, to, group_by
+//	GET /api/v1/digest      from, to, sig, metrics: a digest generated on request
+//	GET /api/v1/digests     list: digests sent to mailing lists
+//
+// Dates are YYYY-MM-DD or RFC 3339 times. Responses carry an ETag and
+// requests with a matching If-None-Match get 304 Not Modified.
+type API struct {
+	SIGs     *SIGMap
+	Taxonomy *Taxonomy
+	// Bots are the accounts whose comments aren't a response. If nil,
+	// DefaultBots are used.
+	Bots Bots
+
+	store Store
+	mux   *http.ServeMux
+}
+
+// NewAPI creates an instance of API over a store.
+func NewAPI(store Store, sigs *SIGMap) *API {
+	a := &API{SIGs: sigs, store: store, mux: http.NewServeMux()}
+	a.mux.HandleFunc("/api/v1/issues", a.issues)
+	a.mux.HandleFunc("/api/v1/labels", a.labels)
+	a.mux.HandleFunc("/api/v1/milestones", a.milestones)
+	a.mux.HandleFunc("/api/v1/sigs", a.sigs)
+	a.mux.HandleFunc("/api/v1/metrics", a.metrics)
+	a.mux.HandleFunc("/api/v1/digest", a.digest)
+	a.mux.HandleFunc("/api/v1/digests", a.digests)
+	return a
+}
+
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeAPIError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	a.mux.ServeHTTP(w, r)
+}
+
+// apiError is an error caused by a bad request.
+type apiError struct {
+	error
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return apiError{errors.Errorf(format, args...)}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+}
+
+// handleError writes an error response. Bad requests are reported to the
+// client and other errors are logged.
+func handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if _, ok := err.(apiError); ok {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.WithError(err).WithField("path", r.URL.Path).Error("api request failed")
+	writeAPIError(w, http.StatusInternalServerError, errors.New("internal error"))
+}
+
+// writeJSON writes v as JSON with an ETag, or 304 Not Modified if the client
+// already has it.
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		handleError(w, r, errors.Wrap(err, "encode response"))
+		return
+	}
+
+	sum := sha1.Sum(buf.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	if r.Method != http.MethodHead {
+		w.Write(buf.Bytes())
+	}
+}
+
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setPageLinks sets the X-Total-Count and Link headers for a page of results.
+func setPageLinks(w http.ResponseWriter, r *http.Request, page, perPage, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	last := (total + perPage - 1) / perPage
+	if last < 1 {
+		last = 1
+	}
+
+	link := func(p int, rel string) string {
+		u := *r.URL
+		q := u.Query()
+		q.Set("page", strconv.Itoa(p))
+		q.Set("per_page", strconv.Itoa(perPage))
+		u.RawQuery = q.Encode()
+		return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+	}
+
+	links := []string{}
+	if page < last {
+		links = append(links, link(page+1, "next"), link(last, "last"))
+	}
+	if page > 1 {
+		links = append(links, link(1, "first"), link(page-1, "prev"))
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+func pagination(v url.Values) (int, int, error) {
+	page, perPage := 1, defaultPerPage
+
+	if s := v.Get("page"); s != "" {
+		p, err := strconv.Atoi(s)
+		if err != nil || p < 1 {
+			return 0, 0, badRequest("invalid page %q", s)
+		}
+		page = p
+	}
+
+	if s := v.Get("per_page"); s != "" {
+		p, err := strconv.Atoi(s)
+		if err != nil || p < 1 {
+			return 0, 0, badRequest("invalid per_page %q", s)
+		}
+		perPage = p
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	return page, perPage, nil
+}
+
+// parseTime parses a date or RFC 3339 time. An empty string is the zero time.
+func parseTime(name, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(dateFormat, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, badRequest("invalid %s %q", name, s)
+	}
+
+	return t, nil
+}
+
+// period parses the from and to parameters. to defaults to now and from to a
+// week before to.
+func period(v url.Values) (time.Time, time.Time, error) {
+	to, err := parseTime("to", v.Get("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+
+	from, err := parseTime("from", v.Get("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -7)
+	}
+
+	return from, to, nil
+}
+
+func requireRepo(v url.Values) (string, error) {
+	repo := v.Get("repo")
+	if repo == "" {
+		return "", badRequest("repo is required")
+	}
+
+	return repo, nil
+}
+
+// ParseIssueQuery parses issue query parameters.
+func ParseIssueQuery(v url.Values) (IssueQuery, error) {
+	q := IssueQuery{
+		Repository: v.Get("repo"),
+		State:      v.Get("state"),
+		Labels:     v["label"],
+		Milestone:  v.Get("milestone"),
+		Assignee:   v.Get("assignee"),
+		Sort:       v.Get("sort"),
+	}
+
+	switch q.State {
+	case "", "open", "closed", IssueDeleted, IssueTransferred:
+	case "all":
+		q.State = ""
+	default:
+		return q, badRequest("invalid state %q", q.State)
+	}
+
+	if q.Sort != "" && q.Sort != "number" && q.Sort != "created" && q.Sort != "updated" {
+		return q, badRequest("invalid sort %q", q.Sort)
+	}
+
+	if s := v.Get("pull_request"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return q, badRequest("invalid pull_request %q", s)
+		}
+		q.PullRequest = &b
+	}
+
+	var err error
+	if q.CreatedFrom, err = parseTime("created_from", v.Get("created_from")); err != nil {
+		return q, err
+	}
+	if q.CreatedTo, err = parseTime("created_to", v.Get("created_to")); err != nil {
+		return q, err
+	}
+	if q.UpdatedSince, err = parseTime("updated_since", v.Get("updated_since")); err != nil {
+		return q, err
+	}
+
+	q.Page, q.PerPage, err = pagination(v)
+	return q, err
+}
+
+func (a *API) issues(w http.ResponseWriter, r *http.Request) {
+	q, err := ParseIssueQuery(r.URL.Query())
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	if expr := r.URL.Query().Get("q"); expr != "" {
+		if q.Filter, err = ParseQuery(expr, a.SIGs); err != nil {
+			handleError(w, r, badRequest("invalid q: %v", err))
+			return
+		}
+	}
+
+	issues, total, err := a.store.QueryIssues(q)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	setPageLinks(w, r, q.Page, q.PerPage, total)
+	writeJSON(w, r, newAPIIssues(issues))
+}
+
+func (a *API) labels(w http.ResponseWriter, r *http.Request) {
+	labels, err := a.store.ActiveLabels()
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, labels)
+}
+
+func (a *API) milestones(w http.ResponseWriter, r *http.Request) {
+	repo, err := requireRepo(r.URL.Query())
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	milestones, err := a.store.Milestones(repo)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, milestones)
+}
+
+// digestOptions parses the parameters shared by the digest endpoints.
+func (a *API) digestOptions(v url.Values) (DigestOptions, error) {
+	repo, err := requireRepo(v)
+	if err != nil {
+		return DigestOptions{}, err
+	}
+
+	from, to, err := period(v)
+	if err != nil {
+		return DigestOptions{}, err
+	}
+
+	return DigestOptions{Repository: repo, From: from, To: to, SIG: v.Get("sig"), SIGs: a.SIGs, Bots: a.Bots}, nil
+}
+
+func (a *API) sigs(w http.ResponseWriter, r *http.Request) {
+	opts, err := a.digestOptions(r.URL.Query())
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	d, err := BuildDigest(a.store, opts)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, d.SIGs)
+}
+
+func (a *API) metrics(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	opts, err := a.digestOptions(v)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	groupBy := v.Get("group_by")
+	switch groupBy {
+	case "", GroupByRepository, GroupBySIG, GroupByKind:
+	default:
+		handleError(w, r, badRequest("invalid group_by %q", groupBy))
+		return
+	}
+
+	h, err := a.store.LoadHistory(opts.Repository, opts.From, opts.To)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	report, err := ComputeMetrics([]*History{h}, MetricsOptions{
+		From:     opts.From,
+		To:       opts.To,
+		GroupBy:  groupBy,
+		SIGs:     a.SIGs,
+		Taxonomy: a.Taxonomy,
+		Bots:     a.Bots,
+	})
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, report)
+}
+
+func (a *API) digest(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	opts, err := a.digestOptions(v)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+	opts.Metrics = v.Get("metrics") == "true"
+
+	d, err := BuildDigest(a.store, opts)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, apiDigest{
+		Repository: d.Repository,
+		SIG:        d.SIG,
+		From:       d.From,
+		To:         d.To,
+		Opened:     newAPIIssues(d.Opened),
+		Closed:     newAPIIssues(d.Closed),
+		OpenCount:  d.OpenCount,
+		SIGs:       d.SIGs,
+		Metrics:    d.Metrics,
+	})
+}
+
+func (a *API) digests(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	repo, err := requireRepo(v)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	page, perPage, err := pagination(v)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	deliveries, total, err := a.store.LoadDigestDeliveries(repo, v.Get("list"), perPage, (page-1)*perPage)
+	if err != nil {
+		handleError(w, r, err)
+		return
+	}
+
+	out := []apiDigestDelivery{}
+	for _, d := range deliveries {
+		out = append(out, apiDigestDelivery{
+			Repository: d.Repository,
+			List:       d.List,
+			SIG:        d.SIG,
+			From:       d.From.Format(dateFormat),
+			To:         d.To.Format(dateFormat),
+			Title:      d.Title,
+			Markdown:   d.Markdown,
+			SentAt:     d.SentAt,
+		})
+	}
+
+	setPageLinks(w, r, page, perPage, total)
+	writeJSON(w, r, out)
+}
+
+var (
+	selectIssuesSQL = `
+  SELECT id, number, state, title, body, created_by, labels, assignee, closed_at,
+    created_at, updated_at, milestone, repository, pull_request
+  FROM issues`
+
+	countIssuesSQL = `SELECT COUNT(*) FROM issues`
+
+	queryIssuesSQL = `
+  SELECT id, number, state, title, body, created_by, labels, assignee, closed_at,
+    created_at, updated_at, milestone, repository, pull_request
+  FROM issues
+  WHERE $1 = '' OR repository = $1`
+
+	milestonesSQL = `
+  SELECT milestone,
+    COUNT(CASE WHEN state = 'open' THEN 1 END) AS open,
+    COUNT(CASE WHEN state = 'closed' THEN 1 END) AS closed
+  FROM issues
+  WHERE repository = $1 AND milestone <> ''
+  GROUP BY milestone
+  ORDER BY milestone`
+
+	activeLabelsSQL = `
+  SELECT name, url, color, active FROM labels
+  WHERE active
+  ORDER BY name`
+)
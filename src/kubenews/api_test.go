@@ -0,0 +1,140 @@
+package kubenews
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var issueColumns = []string{"id", "number", "state", "title", "body", "created_by", "labels", "assignee",
+	"closed_at", "created_at", "updated_at", "milestone", "repository", "pull_request"}
+
+func TestAPIIssues(t *testing.T) {
+	stdlibdb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := sqlx.NewDb(stdlibdb, "mockdriver")
+
+	created := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM issues WHERE repository = \$1 AND state = \$2 AND labels @> \$3::jsonb`).
+			WithArgs("org/repo", "open", `[{"Name":"sig/node"}]`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(`FROM issues WHERE .* ORDER BY updated_at DESC NULLS LAST, number DESC LIMIT 2 OFFSET 0`).
+			WithArgs("org/repo", "open", `[{"Name":"sig/node"}]`).
+			WillReturnRows(sqlmock.NewRows(issueColumns).
+				AddRow(1, 10, "open", "title", "body", "alice", []byte(`[{"URL":"u","Name":"sig/node","Color":"fff"}]`),
+					"", nil, created, created, "v1.7", "org/repo", false))
+	}
+
+	api := NewAPI(NewPostgresStore(db), nil)
+	query := url.Values{"repo": {"org/repo"}, "state": {"open"}, "label": {"sig/node"}, "sort": {"updated"},
+		"per_page": {"2"}}
+
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/issues?"+query.Encode(), nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "3", rec.Header().Get("X-Total-Count"))
+	require.Contains(t, rec.Header().Get("Link"), `rel="next"`)
+	require.Contains(t, rec.Header().Get("Link"), "page=2")
+
+	out := []map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Len(t, out, 1)
+	require.Equal(t, float64(10), out[0]["number"])
+	require.Equal(t, "https://github.com/org/repo/issues/10", out[0]["html_url"])
+	require.Equal(t, []interface{}{map[string]interface{}{"name": "sig/node", "color": "fff"}}, out[0]["labels"])
+
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req := httptest.NewRequest("GET", "/api/v1/issues?"+query.Encode(), nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	require.Empty(t, rec.Body.String())
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIBadRequests(t *testing.T) {
+	api := NewAPI(nil, nil)
+
+	for _, path := range []string{
+		"/api/v1/issues?state=merged",
+		"/api/v1/issues?page=0",
+		"/api/v1/issues?created_from=yesterday",
+		"/api/v1/milestones",
+		"/api/v1/metrics?repo=org/repo&group_by=color",
+	} {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code, path)
+	}
+
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/issues", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestAPIMetricsBots(t *testing.T) {
+	s := NewMemoryStore()
+	created := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) *time.Time {
+		t := created.Add(time.Duration(hours) * time.Hour)
+		return &t
+	}
+
+	_, err := s.SaveIssues([]Issue{{Number: 1, State: "open", Title: "crash", User: "alice",
+		Repository: "org/repo", Labels: Labels{}, CreatedAt: at(0), UpdatedAt: at(3)}})
+	require.NoError(t, err)
+	_, err = s.SaveComments([]Comment{
+		{ID: 1, Repository: "org/repo", IssueNumber: 1, User: "triage-bot", Body: "/assign", CreatedAt: at(1)},
+		{ID: 2, Repository: "org/repo", IssueNumber: 1, User: "bob", Body: "looking", CreatedAt: at(3)},
+	})
+	require.NoError(t, err)
+
+	api := NewAPI(s, nil)
+	api.Bots = NewBots([]string{"triage-bot"})
+
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/metrics?repo=org/repo&from=2017-03-01&to=2017-03-08", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// the bot's comment isn't the first response
+	report := MetricsReport{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.Len(t, report.Rows, 1)
+	require.Equal(t, 1, report.Rows[0].FirstResponse.Count)
+	require.Equal(t, 3*time.Hour, report.Rows[0].FirstResponse.Median)
+}
+
+func TestAPIIssuesRemovedStates(t *testing.T) {
+	s := NewMemoryStore()
+	_, err := s.SaveIssues([]Issue{
+		{Number: 1, State: "open", Title: "crash", Repository: "org/repo", Labels: Labels{}},
+		{Number: 2, State: IssueDeleted, Title: "spam", Repository: "org/repo", Labels: Labels{}},
+		{Number: 3, State: IssueTransferred, Title: "moved", Repository: "org/repo", Labels: Labels{}},
+	})
+	require.NoError(t, err)
+
+	api := NewAPI(s, nil)
+	for state, number := range map[string]float64{IssueDeleted: 2, IssueTransferred: 3} {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/issues?repo=org/repo&state="+state, nil))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		out := []map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+		require.Len(t, out, 1, state)
+		require.Equal(t, number, out[0]["number"], state)
+	}
+}
@@ -0,0 +1,144 @@
+package kubenews
+
+var (
+	// digestTemplate is the default digest layout.
+	digestTemplate = `# {{md .Title}}
+
+{{date .From}} to {{date .To}}
+
+{{len .Opened}} opened, {{len .Closed}} closed, {{.OpenCount}} open
+
+* [SIGs](#sigs)
+* [Opened](#opened)
+* [Closed](#closed)
+{{- if .Metrics}}
+* [Metrics](#metrics)
+{{- end}}
+{{- if .TopFlakes}}
+* [Top flakes](#top-flakes)
+{{- end}}
+{{- if .PossibleDuplicates}}
+* [Possible duplicates](#possible-duplicates)
+{{- end}}
+
+## SIGs
+
+| SIG | Opened | Closed | Open |
+| --- | ---: | ---: | ---: |
+{{range .SIGs -}}
+| {{cell .Name}} | {{.Opened}} | {{.Closed}} | {{.Open}} |
+{{end}}
+## Opened
+
+{{range .Opened -}}
+* {{link .}}
+{{with snippet .Body 200}}  {{md .}}
+{{end -}}
+{{else -}}
+None
+{{end}}
+## Closed
+
+{{range .Closed -}}
+* {{link .}}{{range $.FixedBy .}}, fixed by [#{{.PullRequest.Number}}]({{.PullRequest.HTMLURL}}){{with .PullRequest.Milestone}} in {{md .}}{{end}}{{end}}
+{{else -}}
+None
+{{end}}
+{{with .Metrics -}}
+## Metrics
+
+Issues opened in the period, by {{.GroupBy}}.
+
+| {{.GroupBy}} | Issues | First response | Triage | Close |
+| --- | ---: | ---: | ---: | ---: |
+{{range .Rows -}}
+| {{cell .Group}} | {{.Issues}} | {{median .FirstResponse}} | {{median .Triage}} | {{median .Close}} |
+{{end}}
+{{end -}}
+{{with .TopFlakes -}}
+## Top flakes
+
+| Test | Failures | Open | Closed | Jobs | Issues |
+| --- | ---: | ---: | ---: | --- | --- |
+{{range . -}}
+| {{cell .Name}} | {{.Occurrences}} | {{.Open}} | {{.Closed}} | {{join .Jobs ", "}} | {{refs .Issues}} |
+{{end}}
+{{end -}}
+{{with .PossibleDuplicates -}}
+## Possible duplicates
+
+{{range . -}}
+* {{link .Issue}}
+{{range .Matches}}  * {{link .Issue}} ({{printf "%.2f" .Score}})
+{{end -}}
+{{end}}
+{{end -}}
+`
+
+	// digestSummaryTemplate is a short digest for chat and mailing lists.
+	digestSummaryTemplate = `**{{md .Title}}**, {{date .From}} to {{date .To}}
+
+{{len (issues .Opened)}} {{plural (len (issues .Opened)) "issue" "issues"}} and {{len (pulls .Opened)}} {{plural (len (pulls .Opened)) "pull request" "pull requests"}} opened, {{len .Closed}} closed, {{.OpenCount}} open.
+{{with filter "priority>=critical-urgent" .Opened}}
+New critical issues:
+
+{{range .}}* {{link .}}
+{{end}}
+{{- end}}
+{{- with .SIGs}}
+Most active SIGs:
+{{range .}}{{if or .Opened .Closed}}
+* {{cell .Name}}: {{.Opened}} opened, {{.Closed}} closed{{end}}{{end}}
+{{end -}}
+`
+
+	// digestHTMLTemplate is a digest for HTML email, with inline styles since
+	// many mail clients drop style sheets.
+	digestHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body style="font-family: Helvetica, Arial, sans-serif; color: #24292e; max-width: 720px;">
+<h1 style="font-size: 24px;">{{.Title}}</h1>
+<p>{{date .From}} to {{date .To}}: {{len .Opened}} opened, {{len .Closed}} closed, {{.OpenCount}} open</p>
+
+<h2 style="font-size: 18px;">SIGs</h2>
+<table style="border-collapse: collapse;">
+<tr><th align="left">SIG</th><th align="right">Opened</th><th align="right">Closed</th><th align="right">Open</th></tr>
+{{- range .SIGs}}
+<tr><td style="padding: 2px 8px;">{{.Name}}</td><td align="right">{{.Opened}}</td><td align="right">{{.Closed}}</td><td align="right">{{.Open}}</td></tr>
+{{- end}}
+</table>
+
+<h2 style="font-size: 18px;">Opened</h2>
+{{- range .Opened}}
+<p><a href="{{.HTMLURL}}" style="color: #0366d6;">#{{.Number}}</a> {{.Title}}
+{{- with snippet .Body 200}}<br><span style="color: #6a737d;">{{.}}</span>{{end}}</p>
+{{- else}}
+<p>None</p>
+{{- end}}
+
+<h2 style="font-size: 18px;">Closed</h2>
+<ul>
+{{- range .Closed}}
+<li><a href="{{.HTMLURL}}" style="color: #0366d6;">#{{.Number}}</a> {{.Title}}
+{{- range $.FixedBy .}}, fixed by <a href="{{.PullRequest.HTMLURL}}" style="color: #0366d6;">#{{.PullRequest.Number}}</a>{{with .PullRequest.Milestone}} in {{.}}{{end}}{{end}}</li>
+{{- else}}
+<li>None</li>
+{{- end}}
+</ul>
+{{- with .TopFlakes}}
+
+<h2 style="font-size: 18px;">Top flakes</h2>
+<ul>
+{{- range .}}
+<li>{{.Name}}: {{.Occurrences}} {{plural .Occurrences "failure" "failures"}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`
+)
@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"kubenews"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	viper.SetDefault("sig_label_prefix", kubenews.DefaultSIGPrefix)
+	viper.SetDefault("flake_labels", kubenews.DefaultFlakeLabels)
+}
+
+// sigMap loads the SIG mapping from the config.
+func sigMap() *kubenews.SIGMap {
+	sigs := []kubenews.SIG{}
+	if err := viper.UnmarshalKey("sigs", &sigs); err != nil {
+		log.WithError(err).Fatal("unable to read sigs from config")
+	}
+
+	return kubenews.NewSIGMap(viper.GetString("sig_label_prefix"), sigs)
+}
+
+// taxonomy loads the label taxonomy from the config.
+func taxonomy() *kubenews.Taxonomy {
+	rules := []kubenews.TaxonomyRule{}
+	if err := viper.UnmarshalKey("taxonomy", &rules); err != nil {
+		log.WithError(err).Fatal("unable to read taxonomy from config")
+	}
+
+	return kubenews.NewTaxonomy(rules)
+}
+
+// labelFilter parses a label filter expression. It returns nil if the
+// expression is empty.
+func labelFilter(expr string) kubenews.IssueFilter {
+	if expr == "" {
+		return nil
+	}
+
+	filter, err := kubenews.ParseLabelFilter(taxonomy(), expr)
+	if err != nil {
+		log.WithError(err).Fatal("invalid filter")
+	}
+
+	return filter
+}
+
+// issueQuery parses a query, or looks up a saved query by name in the config.
+// It returns nil if expr is empty.
+func issueQuery(expr string) *kubenews.Query {
+	if expr == "" {
+		return nil
+	}
+
+	query, err := kubenews.ParseQuery(savedQuery(expr), sigMap())
+	if err != nil {
+		log.WithError(err).Fatal("invalid query")
+	}
+
+	return query
+}
+
+// savedQuery returns the expression of a saved query, or expr if there is no
+// saved query by that name.
+func savedQuery(expr string) string {
+	if saved, ok := viper.GetStringMapString("queries")[expr]; ok {
+		return saved
+	}
+
+	return expr
+}
+
+// savedQueries parses the saved queries in the config.
+func savedQueries() map[string]*kubenews.Query {
+	queries := map[string]*kubenews.Query{}
+	for name := range viper.GetStringMapString("queries") {
+		queries[name] = issueQuery(name)
+	}
+
+	return queries
+}
+
+// bots loads the automation accounts from the config.
+func bots() kubenews.Bots {
+	return kubenews.NewBots(viper.GetStringSlice("bots"))
+}
+
+// flakeLabels loads the labels of flaky test reports from the config.
+func flakeLabels() []string {
+	return viper.GetStringSlice("flake_labels")
+}
+
+// mailingLists loads the digest mailing lists from the config.
+func mailingLists() []kubenews.MailingList {
+	lists := []kubenews.MailingList{}
+	if err := viper.UnmarshalKey("email.lists", &lists); err != nil {
+		log.WithError(err).Fatal("unable to read email lists from config")
+	}
+
+	return lists
+}
+
+// smtpConfig loads the SMTP server settings from the config.
+func smtpConfig() kubenews.SMTPConfig {
+	config := kubenews.SMTPConfig{}
+	if err := viper.UnmarshalKey("email.smtp", &config); err != nil {
+		log.WithError(err).Fatal("unable to read smtp settings from config")
+	}
+
+	return config
+}
+
+// webhookRoutes loads the chat webhook routes from the config.
+func webhookRoutes() []kubenews.WebhookRoute {
+	routes := []kubenews.WebhookRoute{}
+	if err := viper.UnmarshalKey("webhooks.routes", &routes); err != nil {
+		log.WithError(err).Fatal("unable to read webhook routes from config")
+	}
+
+	return routes
+}
+
+// alertRules loads the alert rules from the config. If none are configured,
+// the default rules are used.
+func alertRules() []kubenews.AlertRule {
+	rules := []kubenews.AlertRule{}
+	if err := viper.UnmarshalKey("webhooks.alerts", &rules); err != nil {
+		log.WithError(err).Fatal("unable to read alert rules from config")
+	}
+
+	if len(rules) == 0 {
+		return kubenews.DefaultAlertRules
+	}
+
+	return rules
+}
+
+// dateRange parses from and to dates. If to is empty, it defaults to now. If
+// from is empty, it defaults to the given number of days before to.
+func dateRange(from, to string, days int) (time.Time, time.Time) {
+	end := time.Now().UTC()
+	if to != "" {
+		end = parseDate(to)
+	}
+
+	start := end.AddDate(0, 0, -days)
+	if from != "" {
+		start = parseDate(from)
+	}
+
+	return start, end
+}
+
+func parseDate(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		log.WithError(err).Fatal("invalid date")
+	}
+
+	return t
+}
+
+// syncSchedules loads the repositories `run` syncs from the config. If none
+// are configured, the default schedules are used.
+func syncSchedules() []kubenews.SyncSchedule {
+	schedules := []kubenews.SyncSchedule{}
+	if err := viper.UnmarshalKey("run.sync", &schedules); err != nil {
+		log.WithError(err).Fatal("unable to read sync schedules from config")
+	}
+
+	if len(schedules) == 0 {
+		return kubenews.DefaultSyncSchedules
+	}
+
+	return schedules
+}
+
+// digestSchedules loads the digests `run` publishes from the config.
+func digestSchedules() []kubenews.DigestSchedule {
+	schedules := []kubenews.DigestSchedule{}
+	if err := viper.UnmarshalKey("run.digests", &schedules); err != nil {
+		log.WithError(err).Fatal("unable to read digest schedules from config")
+	}
+
+	return schedules
+}
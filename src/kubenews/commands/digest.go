@@ -0,0 +1,208 @@
+package commands
+
+import (
+	"kubenews"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+)
+
+var (
+	digestRepo   string
+	digestSIG    string
+	digestFilter string
+	digestQuery  string
+	digestFrom   string
+	digestTo     string
+	digestDays   int
+
+	digestDuplicateThreshold float64
+	digestFlakes             int
+	digestMetrics            bool
+
+	digestSend   bool
+	digestDryRun string
+	digestList   string
+	digestForce  bool
+)
+
+func init() {
+	digestCmd.Flags().StringVar(&digestRepo, "repo", "kubernetes/kubernetes", "repository to summarize")
+	digestCmd.Flags().StringVar(&digestSIG, "sig", "", "only include issues for this SIG")
+	digestCmd.Flags().StringVar(&digestFilter, "filter", "", "label filter, e.g. \"kind=bug priority>=important-soon\"")
+	digestCmd.Flags().StringVar(&digestQuery, "query", "", "query or saved query name, e.g. \"is:issue -label:lifecycle/frozen\"")
+	digestCmd.Flags().StringVar(&digestFrom, "from", "", "start date (YYYY-MM-DD)")
+	digestCmd.Flags().StringVar(&digestTo, "to", "", "end date (YYYY-MM-DD), defaults to now")
+	digestCmd.Flags().IntVar(&digestDays, "days", 7, "days to summarize when --from is not set")
+	digestCmd.Flags().Float64Var(&digestDuplicateThreshold, "duplicate-threshold", kubenews.DefaultDuplicateThreshold,
+		"flag new issues at least this similar to open issues, 0 disables")
+	digestCmd.Flags().IntVar(&digestFlakes, "flakes", 10, "number of top flaky tests to include, 0 disables")
+	digestCmd.Flags().BoolVar(&digestMetrics, "metrics", false, "include response and close times by SIG")
+	digestCmd.Flags().BoolVar(&digestSend, "send", false, "email the digest to the lists in the config")
+	digestCmd.Flags().StringVar(&digestDryRun, "dry-run", "", "write emails to .eml files in this directory instead of sending")
+	digestCmd.Flags().StringVar(&digestList, "list", "", "only send to this list")
+	digestCmd.Flags().BoolVar(&digestForce, "force", false, "send even if the digest was already sent to a list")
+	addOutputFlags(digestCmd)
+	RootCmd.AddCommand(digestCmd)
+}
+
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Generate a digest of issue activity",
+	Long:  "Summarize issues opened and closed over a period, broken down by SIG",
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		from, to := dateRange(digestFrom, digestTo, digestDays)
+
+		opts := kubenews.DigestOptions{
+			Repository: digestRepo,
+			From:       from,
+			To:         to,
+			SIG:        digestSIG,
+			SIGs:       sigMap(),
+			Filter:     kubenews.AllOf(labelFilter(digestFilter), issueQuery(digestQuery)),
+
+			DuplicateThreshold: digestDuplicateThreshold,
+			FlakeLabels:        flakeLabels(),
+			FlakeLimit:         digestFlakes,
+			Metrics:            digestMetrics,
+			Bots:               bots(),
+		}
+
+		if digestSend || digestDryRun != "" {
+			err := withLock(context.Background(), repoLocker(store), opts.Repository, "digest --send", func() error {
+				return sendDigests(store, opts, digestDryRun, digestList, digestForce)
+			})
+			if err != nil {
+				log.WithError(err).Fatal("unable to send digests")
+			}
+			return
+		}
+
+		digest, err := kubenews.BuildDigest(store, opts)
+		if err != nil {
+			log.WithError(err).Fatal("unable to build digest")
+		}
+
+		writeReport(digest)
+	},
+}
+
+// sendDigests emails a digest to each mailing list in the config, or to the
+// list named only if set. Lists with a SIG get the digest for that SIG. Lists
+// which already received the digest for the period, or may have from a run
+// which was interrupted while sending, are skipped unless force is set. With
+// dryRun set, emails are written to .eml files in that directory.
+func sendDigests(store kubenews.Store, opts kubenews.DigestOptions, dryRun, only string, force bool) error {
+	var mailer kubenews.Mailer = kubenews.NewSMTPMailer(smtpConfig())
+	if dryRun != "" {
+		mailer = &kubenews.EMLMailer{Dir: dryRun}
+	} else if err := store.Migrate(); err != nil {
+		return errors.Wrap(err, "unable to migrate database")
+	}
+
+	from := viper.GetString("email.from")
+	if from == "" {
+		return errors.New("email.from is not set in the config")
+	}
+
+	lists := mailingLists()
+	if len(lists) == 0 {
+		return errors.New("no email lists in the config")
+	}
+
+	for _, list := range lists {
+		if only != "" && list.Name != only {
+			continue
+		}
+
+		logger := log.WithField("list", list.Name)
+
+		if dryRun == "" && !force {
+			delivery, err := store.FindDigestDelivery(opts.Repository, list.Name, opts.From, opts.To)
+			if err != nil {
+				return errors.Wrapf(err, "unable to check digest delivery to %s", list.Name)
+			}
+			if delivery != nil && delivery.Status == kubenews.DeliveryPending {
+				logger.WithField("startedAt", delivery.SentAt).
+					Warn("digest may have been sent by an interrupted run, use --force to send it again")
+				kubenews.ObserveDigestPublish(opts.Repository, "email", kubenews.DigestSkipped)
+				continue
+			}
+			if delivery != nil {
+				logger.WithField("sentAt", delivery.SentAt).Info("digest already sent")
+				kubenews.ObserveDigestPublish(opts.Repository, "email", kubenews.DigestSkipped)
+				continue
+			}
+		}
+
+		listOpts := opts
+		if list.SIG != "" {
+			listOpts.SIG = list.SIG
+		}
+
+		digest, err := kubenews.BuildDigest(store, listOpts)
+		if err != nil {
+			return errors.Wrapf(err, "unable to build digest for %s", list.Name)
+		}
+
+		r, html := applyTemplate(digest)
+		msg, err := kubenews.NewReportMessage(r, html, htmlLayout())
+		if err != nil {
+			return errors.Wrapf(err, "unable to render digest for %s", list.Name)
+		}
+		msg.From = from
+		msg.To = list.To
+
+		if dryRun != "" {
+			if err := mailer.Send(msg); err != nil {
+				return errors.Wrapf(err, "unable to send digest to %s", list.Name)
+			}
+			logger.WithField("dir", dryRun).Info("wrote digest email")
+			continue
+		}
+
+		delivery := &kubenews.DigestDelivery{
+			Repository: opts.Repository,
+			List:       list.Name,
+			SIG:        digest.SIG,
+			From:       opts.From,
+			To:         opts.To,
+			Title:      digest.Title(),
+			Markdown:   msg.Text,
+			Recipients: strings.Join(list.To, ", "),
+			MessageID:  msg.ID,
+			SentAt:     msg.Date,
+			Status:     kubenews.DeliveryPending,
+		}
+
+		// record the delivery before sending, so if recording it fails the
+		// list isn't emailed again by the next run
+		if err := store.RecordDigestDelivery(delivery); err != nil {
+			return errors.Wrapf(err, "unable to record digest delivery to %s", list.Name)
+		}
+
+		if err := mailer.Send(msg); err != nil {
+			kubenews.ObserveDigestPublish(opts.Repository, "email", kubenews.DigestFailed)
+			if err := store.DeleteDigestDelivery(opts.Repository, list.Name, opts.From, opts.To); err != nil {
+				logger.WithError(err).Error("unable to delete pending digest delivery")
+			}
+			return errors.Wrapf(err, "unable to send digest to %s", list.Name)
+		}
+		kubenews.ObserveDigestPublish(opts.Repository, "email", kubenews.DigestSent)
+
+		delivery.Status = kubenews.DeliverySent
+		if err := store.RecordDigestDelivery(delivery); err != nil {
+			return errors.Wrapf(err, "unable to record digest delivery to %s", list.Name)
+		}
+
+		logger.WithField("recipients", len(list.To)).Info("sent digest")
+	}
+
+	return nil
+}
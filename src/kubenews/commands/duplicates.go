@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"kubenews"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	duplicatesRepo      string
+	duplicatesSIG       string
+	duplicatesThreshold float64
+)
+
+func init() {
+	duplicatesCmd.Flags().StringVar(&duplicatesRepo, "repo", "kubernetes/kubernetes", "repository")
+	duplicatesCmd.Flags().StringVar(&duplicatesSIG, "sig", "", "only include issues for this SIG")
+	duplicatesCmd.Flags().Float64Var(&duplicatesThreshold, "threshold", kubenews.DefaultDuplicateThreshold, "minimum similarity between 0 and 1")
+	addOutputFlags(duplicatesCmd)
+	RootCmd.AddCommand(duplicatesCmd)
+}
+
+var duplicatesCmd = &cobra.Command{
+	Use:   "duplicates",
+	Short: "List likely duplicate issues",
+	Long:  "List clusters of open issues with similar titles and bodies",
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		open, err := store.OpenIssues(duplicatesRepo)
+		if err != nil {
+			log.WithError(err).Fatal("unable to load open issues")
+		}
+
+		if duplicatesSIG != "" {
+			open = sigMap().FilterSIG(open, duplicatesSIG)
+		}
+
+		report := kubenews.FindDuplicates(duplicatesRepo, open, duplicatesThreshold)
+		writeReport(report)
+	},
+}
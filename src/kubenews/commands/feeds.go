@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"kubenews"
+	"net/http"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	feedsRepo    string
+	feedsSIGs    []string
+	feedsLabels  []string
+	feedsOut     string
+	feedsServe   string
+	feedsBaseURL string
+	feedsDays    int
+	feedsLimit   int
+)
+
+func init() {
+	feedsCmd.Flags().StringVar(&feedsRepo, "repo", "kubernetes/kubernetes", "repository")
+	feedsCmd.Flags().StringSliceVar(&feedsSIGs, "sig", nil, "write a feed for this SIG, may be repeated; defaults to the configured SIGs")
+	feedsCmd.Flags().StringSliceVar(&feedsLabels, "label", nil, "also write a feed for this label, may be repeated")
+	feedsCmd.Flags().StringVar(&feedsOut, "out", "", "write feeds to static files in this directory")
+	feedsCmd.Flags().StringVar(&feedsServe, "serve", "", "serve feeds over HTTP on this address, e.g. :8080")
+	feedsCmd.Flags().StringVar(&feedsBaseURL, "base-url", "http://localhost:8080", "URL the feeds are published under")
+	feedsCmd.Flags().IntVar(&feedsDays, "days", 30, "days of issues to include")
+	feedsCmd.Flags().IntVar(&feedsLimit, "limit", 50, "maximum entries per feed, 0 for no limit")
+	RootCmd.AddCommand(feedsCmd)
+}
+
+var feedsCmd = &cobra.Command{
+	Use:   "feeds",
+	Short: "Generate Atom feeds of issue activity",
+	Long: `Generate Atom feeds of new issues for a repository, each SIG, each --label
+and each saved query, and of the digests sent to each configured mailing list.
+Feeds are written as static files with --out or served over HTTP with --serve.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if (feedsOut == "") == (feedsServe == "") {
+			log.Fatal("exactly one of --out or --serve is required")
+		}
+
+		store := openStore()
+
+		if err := store.Migrate(); err != nil {
+			log.WithError(err).Fatal("unable to migrate database")
+		}
+
+		feeds := &kubenews.Feeds{
+			Store:   store,
+			SIGs:    sigMap(),
+			BaseURL: feedsBaseURL,
+			Days:    feedsDays,
+			Limit:   feedsLimit,
+			Queries: savedQueries(),
+		}
+
+		if feedsServe != "" {
+			log.WithField("addr", feedsServe).Info("serving feeds")
+			if err := http.ListenAndServe(feedsServe, feeds); err != nil {
+				log.WithError(err).Fatal("unable to serve feeds")
+			}
+			return
+		}
+
+		lists := []string{}
+		for _, list := range mailingLists() {
+			lists = append(lists, list.Name)
+		}
+
+		sigs := feedsSIGs
+		if len(sigs) == 0 {
+			sigs = feeds.SIGs.Names()
+		}
+
+		queries := []string{}
+		for name := range feeds.Queries {
+			queries = append(queries, name)
+		}
+		sort.Strings(queries)
+
+		paths := kubenews.FeedPaths(feedsRepo, sigs, feedsLabels, queries, lists)
+		if err := feeds.WriteFiles(feedsOut, paths); err != nil {
+			log.WithError(err).Fatal("unable to write feeds")
+		}
+	},
+}
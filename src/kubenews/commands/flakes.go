@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"kubenews"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flakesRepo  string
+	flakesSIG   string
+	flakesLimit int
+	flakesFrom  string
+	flakesTo    string
+	flakesDays  int
+)
+
+func init() {
+	flakesCmd.Flags().StringVar(&flakesRepo, "repo", "kubernetes/kubernetes", "repository")
+	flakesCmd.Flags().StringVar(&flakesSIG, "sig", "", "only include flakes for this SIG")
+	flakesCmd.Flags().IntVar(&flakesLimit, "limit", 20, "number of tests to list, 0 for all")
+	flakesCmd.Flags().StringVar(&flakesFrom, "from", "", "start date (YYYY-MM-DD)")
+	flakesCmd.Flags().StringVar(&flakesTo, "to", "", "end date (YYYY-MM-DD), defaults to now")
+	flakesCmd.Flags().IntVar(&flakesDays, "days", 7, "days to count when --from is not set")
+	addOutputFlags(flakesCmd)
+	RootCmd.AddCommand(flakesCmd)
+}
+
+var flakesCmd = &cobra.Command{
+	Use:   "flakes",
+	Short: "List the most reported flaky tests",
+	Long:  "Group flaky and failing test reports by test name and count their failures",
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		h, err := store.LoadLabeledHistory(flakesRepo, flakeLabels())
+		if err != nil {
+			log.WithError(err).Fatal("unable to load flake reports")
+		}
+
+		if flakesSIG != "" {
+			h.Issues = sigMap().FilterSIG(h.Issues, flakesSIG)
+		}
+
+		from, to := dateRange(flakesFrom, flakesTo, flakesDays)
+		flakes := kubenews.TopFlakes(h, from, to, flakesLimit)
+		writeReport(&kubenews.FlakeReport{Repository: flakesRepo, From: from, To: to, Flakes: flakes})
+	},
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"kubenews"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	metricsRepos   []string
+	metricsGroupBy string
+	metricsFormat  string
+	metricsFrom    string
+	metricsTo      string
+	metricsDays    int
+)
+
+func init() {
+	metricsCmd.Flags().StringSliceVar(&metricsRepos, "repo", []string{"kubernetes/kubernetes"}, "repositories to measure")
+	metricsCmd.Flags().StringVar(&metricsGroupBy, "by", kubenews.GroupByRepository, "group by repository, sig or kind")
+	metricsCmd.Flags().StringVar(&metricsFormat, "format", "table", "output format: table, json or csv")
+	metricsCmd.Flags().StringVar(&metricsFrom, "from", "", "include issues created on or after this date (YYYY-MM-DD)")
+	metricsCmd.Flags().StringVar(&metricsTo, "to", "", "include issues created before this date (YYYY-MM-DD), defaults to now")
+	metricsCmd.Flags().IntVar(&metricsDays, "days", 30, "days to measure when --from is not set")
+	RootCmd.AddCommand(metricsCmd)
+}
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Compute backlog metrics",
+	Long:  "Compute median and p90 time to first response, time to triage and time to close",
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		from, to := dateRange(metricsFrom, metricsTo, metricsDays)
+
+		histories := []*kubenews.History{}
+		for _, repo := range metricsRepos {
+			h, err := store.LoadHistory(repo, from, to)
+			if err != nil {
+				log.WithError(err).WithField("repo", repo).Fatal("unable to load issue history")
+			}
+			histories = append(histories, h)
+		}
+
+		report, err := kubenews.ComputeMetrics(histories, kubenews.MetricsOptions{
+			From:     from,
+			To:       to,
+			GroupBy:  metricsGroupBy,
+			SIGs:     sigMap(),
+			Taxonomy: taxonomy(),
+			Bots:     bots(),
+		})
+		if err != nil {
+			log.WithError(err).Fatal("unable to compute metrics")
+		}
+
+		if err := report.Write(os.Stdout, metricsFormat); err != nil {
+			log.WithError(err).Fatal("unable to write metrics")
+		}
+	},
+}
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(migrateCmd)
+}
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Create database tables",
+	Long:  "Create the database tables kubenews needs if they don't exist",
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		if err := store.Migrate(); err != nil {
+			log.WithError(err).Fatal("unable to migrate database")
+		}
+	},
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"html/template"
+	"kubenews"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	outputFormat   string
+	outputLayout   string
+	outputTemplate string
+)
+
+// addOutputFlags adds the flags which control how a report is written.
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&outputFormat, "format", kubenews.FormatMarkdown, "output format: markdown or html")
+	cmd.Flags().StringVar(&outputLayout, "layout", "", "HTML layout template, defaults to a built in layout")
+	cmd.Flags().StringVar(&outputTemplate, "template", "", "report template file or built in template name")
+}
+
+// htmlLayout loads the HTML layout from --layout. It returns nil if no layout
+// is set.
+func htmlLayout() *template.Template {
+	if outputLayout == "" {
+		return nil
+	}
+
+	layout, err := kubenews.ParseHTMLLayout(outputLayout)
+	if err != nil {
+		log.WithError(err).Fatal("unable to load layout")
+	}
+
+	return layout
+}
+
+// applyTemplate applies the template selected by --template to a report. A
+// Markdown template is returned as part of the report. An HTML template is
+// returned separately, since it replaces the HTML rendering of the report.
+func applyTemplate(r kubenews.Report) (kubenews.Report, *kubenews.Template) {
+	if outputTemplate == "" {
+		return r, nil
+	}
+
+	t, err := kubenews.LoadTemplate(outputTemplate, kubenews.TemplateFuncs(sigMap(), taxonomy()))
+	if err != nil {
+		log.WithError(err).Fatal("unable to load template")
+	}
+
+	if t.IsHTML() {
+		return r, t
+	}
+
+	return kubenews.WithTemplate(r, t), nil
+}
+
+// writeReport writes a report to stdout in the format selected by --format,
+// using the template selected by --template.
+func writeReport(r kubenews.Report) {
+	r, html := applyTemplate(r)
+	if html != nil {
+		if err := html.Execute(os.Stdout, r); err != nil {
+			log.WithError(err).Fatal("unable to write report")
+		}
+		return
+	}
+
+	if err := kubenews.WriteReport(os.Stdout, r, outputFormat, htmlLayout()); err != nil {
+		log.WithError(err).Fatal("unable to write report")
+	}
+}
@@ -0,0 +1,15 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(publishCmd)
+}
+
+var publishCmd = &cobra.Command{
+	Use:   "publish",
+	Short: "Publish to chat webhooks",
+	Long:  "Post digests and alerts to Slack compatible incoming webhooks",
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"encoding/json"
+	"kubenews"
+	"os"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+var (
+	publishAlertsRepo   string
+	publishAlertsSince  time.Duration
+	publishAlertsDryRun bool
+)
+
+func init() {
+	publishAlertsCmd.Flags().StringVar(&publishAlertsRepo, "repo", "kubernetes/kubernetes", "repository")
+	publishAlertsCmd.Flags().DurationVar(&publishAlertsSince, "since", 24*time.Hour, "only alert on issues updated within this duration")
+	publishAlertsCmd.Flags().BoolVar(&publishAlertsDryRun, "dry-run", false, "print alerts instead of posting them")
+	publishCmd.AddCommand(publishAlertsCmd)
+}
+
+var publishAlertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "Post alerts about issues matching alert rules",
+	Long: `Post an alert to the matching webhook routes for each open issue matching an
+alert rule, e.g. new priority/critical-urgent issues. Each issue is alerted on
+once per rule and route. Run it after update to alert on newly imported issues.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		if err := store.Migrate(); err != nil {
+			log.WithError(err).Fatal("unable to migrate database")
+		}
+
+		ctx := context.Background()
+		if publishAlertsDryRun {
+			if err := publishAlerts(ctx, store, publishAlertsRepo, publishAlertsSince, true); err != nil {
+				log.WithError(err).Fatal("unable to publish alerts")
+			}
+			return
+		}
+
+		err := withLock(ctx, repoLocker(store), publishAlertsRepo, "publish alerts", func() error {
+			return publishAlerts(ctx, store, publishAlertsRepo, publishAlertsSince, false)
+		})
+		if err != nil {
+			log.WithError(err).Fatal("unable to publish alerts")
+		}
+	},
+}
+
+// publishAlerts posts an alert to the matching webhook routes for each open
+// issue of a repository updated within since and matching an alert rule. With
+// dryRun the alerts are printed to stdout instead.
+func publishAlerts(ctx context.Context, store kubenews.Store, repo string, since time.Duration, dryRun bool) error {
+	routes := webhookRoutes()
+	if len(routes) == 0 && !dryRun {
+		return errors.New("no webhook routes in the config")
+	}
+
+	open, err := store.OpenIssues(repo)
+	if err != nil {
+		return errors.Wrap(err, "unable to load open issues")
+	}
+
+	sigs := sigMap()
+	updatedSince := time.Now().Add(-since)
+	publisher := kubenews.NewWebhookPublisher()
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	for _, rule := range alertRules() {
+		filter, err := kubenews.ParseLabelFilter(taxonomy(), rule.Filter)
+		if err != nil {
+			return errors.Wrapf(err, "invalid filter for alert %s", rule.Name)
+		}
+
+		for _, issue := range kubenews.AlertCandidates(open, kubenews.AllOf(filter, issueQuery(rule.Query)), updatedSince) {
+			msg := kubenews.NewAlertWebhookMessage(issue, rule, sigs)
+
+			if dryRun {
+				if err := enc.Encode(msg); err != nil {
+					return errors.Wrap(err, "unable to write alert")
+				}
+				continue
+			}
+
+			for _, route := range routes {
+				if !route.Matches(issue, sigs) {
+					continue
+				}
+
+				logger := log.WithFields(log.Fields{"alert": rule.Name, "route": route.Name, "issue": issue.Number})
+
+				sent, err := store.AlertSent(issue.Repository, issue.Number, rule.Name, route.Name)
+				if err != nil {
+					return errors.Wrapf(err, "unable to check alert %s for #%d", rule.Name, issue.Number)
+				}
+				if sent {
+					continue
+				}
+
+				if err := publisher.Publish(ctx, route.URL, msg); err != nil {
+					logger.WithError(err).Error("unable to publish alert")
+					continue
+				}
+
+				if err := store.RecordAlert(issue.Repository, issue.Number, rule.Name, route.Name, time.Now()); err != nil {
+					return errors.Wrapf(err, "unable to record alert %s for #%d", rule.Name, issue.Number)
+				}
+
+				logger.Info("published alert")
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"kubenews"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+var (
+	publishDigestRepo  string
+	publishDigestFrom  string
+	publishDigestTo    string
+	publishDigestDays  int
+	publishDigestLimit int
+	publishDigestRoute string
+)
+
+func init() {
+	publishDigestCmd.Flags().StringVar(&publishDigestRepo, "repo", "kubernetes/kubernetes", "repository to summarize")
+	publishDigestCmd.Flags().StringVar(&publishDigestFrom, "from", "", "start date (YYYY-MM-DD)")
+	publishDigestCmd.Flags().StringVar(&publishDigestTo, "to", "", "end date (YYYY-MM-DD), defaults to now")
+	publishDigestCmd.Flags().IntVar(&publishDigestDays, "days", 7, "days to summarize when --from is not set")
+	publishDigestCmd.Flags().IntVar(&publishDigestLimit, "limit", 10, "maximum opened and closed issues to list")
+	publishDigestCmd.Flags().StringVar(&publishDigestRoute, "route", "", "only post to this route")
+	publishCmd.AddCommand(publishDigestCmd)
+}
+
+var publishDigestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Post a condensed digest to chat webhooks",
+	Long:  "Post a condensed digest to each webhook route, including only the issues the route is for",
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		from, to := dateRange(publishDigestFrom, publishDigestTo, publishDigestDays)
+		ctx := context.Background()
+		err := withLock(ctx, repoLocker(store), publishDigestRepo, "publish digest", func() error {
+			return publishDigests(ctx, store, publishDigestRepo, from, to, publishDigestLimit, publishDigestRoute)
+		})
+		if err != nil {
+			log.WithError(err).Fatal("unable to publish digests")
+		}
+	},
+}
+
+// publishDigests posts a digest of a repository to each webhook route in the
+// config, or to the route named only if set.
+func publishDigests(ctx context.Context, store kubenews.Store, repo string, from, to time.Time, limit int, only string) error {
+	routes := webhookRoutes()
+	if len(routes) == 0 {
+		return errors.New("no webhook routes in the config")
+	}
+
+	sigs := sigMap()
+	publisher := kubenews.NewWebhookPublisher()
+
+	for _, route := range routes {
+		if only != "" && route.Name != only {
+			continue
+		}
+
+		opts := kubenews.DigestOptions{
+			Repository:  repo,
+			From:        from,
+			To:          to,
+			SIGs:        sigs,
+			Filter:      route.Filter(sigs),
+			FlakeLabels: flakeLabels(),
+		}
+		if len(route.SIGs) == 1 && len(route.Labels) == 0 {
+			opts.SIG = route.SIGs[0]
+		}
+
+		digest, err := kubenews.BuildDigest(store, opts)
+		if err != nil {
+			return errors.Wrapf(err, "unable to build digest for %s", route.Name)
+		}
+
+		if err := publisher.Publish(ctx, route.URL, kubenews.NewDigestWebhookMessage(digest, limit)); err != nil {
+			kubenews.ObserveDigestPublish(repo, "webhook", kubenews.DigestFailed)
+			return errors.Wrapf(err, "unable to publish digest to %s", route.Name)
+		}
+		kubenews.ObserveDigestPublish(repo, "webhook", kubenews.DigestSent)
+
+		log.WithField("route", route.Name).Info("published digest")
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"kubenews"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	releaseNotesRepo      string
+	releaseNotesMilestone string
+	releaseNotesFrom      string
+	releaseNotesTo        string
+)
+
+func init() {
+	releaseNotesCmd.Flags().StringVar(&releaseNotesRepo, "repo", "kubernetes/kubernetes", "repository")
+	releaseNotesCmd.Flags().StringVar(&releaseNotesMilestone, "milestone", "", "collect notes for pull requests in this milestone, e.g. v1.7")
+	releaseNotesCmd.Flags().StringVar(&releaseNotesFrom, "from", "", "collect notes for pull requests merged on or after this date (YYYY-MM-DD)")
+	releaseNotesCmd.Flags().StringVar(&releaseNotesTo, "to", "", "collect notes for pull requests merged before this date (YYYY-MM-DD), defaults to now")
+	addOutputFlags(releaseNotesCmd)
+	RootCmd.AddCommand(releaseNotesCmd)
+}
+
+var releaseNotesCmd = &cobra.Command{
+	Use:   "release-notes",
+	Short: "Collect release notes from merged pull requests",
+	Long:  "Collect release notes from merged pull requests and group them by SIG and kind",
+	Run: func(cmd *cobra.Command, args []string) {
+		if releaseNotesMilestone == "" && releaseNotesFrom == "" {
+			log.Fatal("either --milestone or --from is required")
+		}
+
+		store := openStore()
+
+		opts := kubenews.ReleaseNotesOptions{
+			Repository: releaseNotesRepo,
+			Milestone:  releaseNotesMilestone,
+			SIGs:       sigMap(),
+			Taxonomy:   taxonomy(),
+		}
+		if releaseNotesMilestone == "" {
+			opts.From, opts.To = dateRange(releaseNotesFrom, releaseNotesTo, 0)
+		}
+
+		prs, err := store.LoadMergedPullRequests(opts.Repository, opts.Milestone, opts.From, opts.To)
+		if err != nil {
+			log.WithError(err).Fatal("unable to load merged pull requests")
+		}
+
+		notes := kubenews.NewReleaseNotes(prs, opts)
+		writeReport(notes)
+	},
+}
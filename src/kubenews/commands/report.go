@@ -0,0 +1,15 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(reportCmd)
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate reports",
+	Long:  "Generate reports from the local data store",
+}
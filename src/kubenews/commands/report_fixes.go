@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"kubenews"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fixesRepo    string
+	fixesPending bool
+)
+
+func init() {
+	fixesCmd.Flags().StringVar(&fixesRepo, "repo", "kubernetes/kubernetes", "repository")
+	fixesCmd.Flags().BoolVar(&fixesPending, "pending", false, "only include issues whose fixing pull requests are still open")
+	addOutputFlags(fixesCmd)
+	reportCmd.AddCommand(fixesCmd)
+}
+
+var fixesCmd = &cobra.Command{
+	Use:   "fixes",
+	Short: "List open issues with fixing pull requests",
+	Long: `List open issues which pull requests say they fix, e.g. with "Fixes #1234"
+in their description, and whether each fix was merged. With --pending, only
+issues whose fixes are all still awaiting merge are listed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		open, err := store.OpenIssues(fixesRepo)
+		if err != nil {
+			log.WithError(err).Fatal("unable to load open issues")
+		}
+
+		fixes, err := store.LoadFixes(fixesRepo)
+		if err != nil {
+			log.WithError(err).Fatal("unable to load fixes")
+		}
+
+		writeReport(kubenews.FindFixes(fixesRepo, open, fixes, fixesPending))
+	},
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"kubenews"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	staleRepo  string
+	staleSIG   string
+	staleQuery string
+	staleDays  int
+)
+
+func init() {
+	staleCmd.Flags().StringVar(&staleRepo, "repo", "kubernetes/kubernetes", "repository")
+	staleCmd.Flags().StringVar(&staleSIG, "sig", "", "only include issues for this SIG")
+	staleCmd.Flags().StringVar(&staleQuery, "query", "", "only include issues matching this query or saved query")
+	staleCmd.Flags().IntVar(&staleDays, "days", 30, "days without human activity")
+	addOutputFlags(staleCmd)
+	reportCmd.AddCommand(staleCmd)
+}
+
+var staleCmd = &cobra.Command{
+	Use:   "stale",
+	Short: "List stale issues and neglected pull requests",
+	Long:  "List open issues and pull requests without human activity, grouped by SIG and assignee",
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		h, err := store.LoadOpenHistory(staleRepo)
+		if err != nil {
+			log.WithError(err).Fatal("unable to load open issues")
+		}
+
+		if query := issueQuery(staleQuery); query != nil {
+			h.Issues = kubenews.FilterIssues(h.Issues, query)
+		}
+
+		report := kubenews.FindStale(staleRepo, h, kubenews.StaleOptions{
+			Days:     staleDays,
+			SIG:      staleSIG,
+			SIGs:     sigMap(),
+			Bots:     bots(),
+			Taxonomy: taxonomy(),
+		})
+
+		writeReport(report)
+	},
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"kubenews"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	trendRepo      string
+	trendDimension string
+	trendValue     string
+	trendFormat    string
+	trendFrom      string
+	trendTo        string
+	trendDays      int
+	trendWidth     int
+)
+
+func init() {
+	trendCmd.Flags().StringVar(&trendRepo, "repo", "kubernetes/kubernetes", "repository")
+	trendCmd.Flags().StringVar(&trendDimension, "dimension", kubenews.SnapshotRepository, "repository, label, sig or milestone")
+	trendCmd.Flags().StringVar(&trendValue, "value", "", "dimension value, e.g. sig/storage; defaults to the repository")
+	trendCmd.Flags().StringVar(&trendFormat, "format", "chart", "output format: chart or csv")
+	trendCmd.Flags().StringVar(&trendFrom, "from", "", "start date (YYYY-MM-DD)")
+	trendCmd.Flags().StringVar(&trendTo, "to", "", "end date (YYYY-MM-DD), defaults to now")
+	trendCmd.Flags().IntVar(&trendDays, "days", 90, "days to plot when --from is not set")
+	trendCmd.Flags().IntVar(&trendWidth, "width", 60, "width of the chart bars")
+	reportCmd.AddCommand(trendCmd)
+}
+
+var trendCmd = &cobra.Command{
+	Use:   "trend",
+	Short: "Plot the open backlog over time",
+	Long:  "Plot daily open backlog snapshots for a repository, label, SIG or milestone",
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		value := trendValue
+		switch {
+		case trendDimension == kubenews.SnapshotRepository && value == "":
+			value = trendRepo
+		case trendDimension == kubenews.SnapshotSIG:
+			value = sigMap().Canonical(value)
+		}
+
+		from, to := dateRange(trendFrom, trendTo, trendDays)
+		trend, err := store.LoadTrend(trendRepo, trendDimension, value, from, to)
+		if err != nil {
+			log.WithError(err).Fatal("unable to load trend")
+		}
+
+		if trendFormat == "csv" {
+			err = trend.WriteCSV(os.Stdout)
+		} else {
+			err = trend.WriteChart(os.Stdout, trendWidth)
+		}
+		if err != nil {
+			log.WithError(err).Fatal("unable to write trend")
+		}
+	},
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	log "github.com/Sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -11,9 +12,33 @@ var RootCmd = &cobra.Command{
 	Short: "Kubenews generates summaries for the Kubernetes project",
 }
 
+var configFile string
+
 func init() {
+	cobra.OnInitialize(initConfig)
+
 	viper.SetEnvPrefix("KUBENEWS")
+	RootCmd.PersistentFlags().StringVar(&configFile, "config", "", "config file (default is ./kubenews.yaml or $HOME/.kubenews/kubenews.yaml)")
 	RootCmd.PersistentFlags().String("github_token", "", "Github Token")
 	viper.BindPFlag("github_token", RootCmd.PersistentFlags().Lookup("github_token"))
 	viper.BindEnv("github_token")
 }
+
+func initConfig() {
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+	}
+
+	viper.SetConfigName("kubenews")
+	viper.AddConfigPath(".")
+	viper.AddConfigPath("$HOME/.kubenews")
+
+	if err := viper.ReadInConfig(); err != nil {
+		if configFile != "" {
+			log.WithError(err).Fatal("unable to read config")
+		}
+		return
+	}
+
+	log.WithField("config", viper.ConfigFileUsed()).Debug("using config file")
+}
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"fmt"
+	"kubenews"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+)
+
+var runHealthAddr string
+
+func init() {
+	runCmd.Flags().StringVar(&runHealthAddr, "health-addr", ":8081", "address to serve the health check and metrics on")
+	RootCmd.AddCommand(runCmd)
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Sync and publish on a schedule",
+	Long: `Run as a long lived process which syncs repositories and publishes digests on
+the schedules under run in the config. The health of each job is served at
+/healthz and Prometheus metrics at /metrics. On SIGTERM or SIGINT, running jobs
+finish before kubenews exits.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		if err := store.Migrate(); err != nil {
+			log.WithError(err).Fatal("unable to migrate database")
+		}
+
+		gh := kubenews.NewGithub(viper.GetString("github_token"))
+		locker := repoLocker(store)
+		scheduler := kubenews.NewScheduler(append(syncJobs(gh, store, locker), digestJobs(store, locker)...))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+		go func() {
+			sig := <-signals
+			log.WithField("signal", sig).Info("stopping")
+			cancel()
+
+			sig = <-signals
+			log.WithField("signal", sig).Fatal("stopping without waiting for running jobs")
+		}()
+
+		mux := http.NewServeMux()
+		mux.Handle("/healthz", scheduler)
+		mux.Handle("/metrics", &kubenews.MetricsHandler{Store: store})
+		server := &http.Server{Addr: runHealthAddr, Handler: mux}
+		go func() {
+			log.WithField("addr", runHealthAddr).Info("serving health check")
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.WithError(err).Fatal("unable to serve health check")
+			}
+		}()
+
+		scheduler.Run(ctx)
+		server.Close()
+		log.Info("stopped")
+	},
+}
+
+// syncJobs creates a job for each synced resource of each repository.
+func syncJobs(gh *kubenews.Github, store kubenews.Store, locker kubenews.Locker) []kubenews.Job {
+	jobs := []kubenews.Job{}
+	for _, s := range syncSchedules() {
+		for resource, expr := range s.Resources() {
+			repo, resource := s.Repository, resource
+			name := fmt.Sprintf("sync %s %s", repo, resource)
+			jobs = append(jobs, kubenews.Job{
+				Name:      name,
+				Schedule:  parseSchedule(expr),
+				Immediate: true,
+				Run: func(ctx context.Context) error {
+					return withLock(ctx, locker, repo, name, func() error {
+						if resource == kubenews.ReconcileResource {
+							_, err := kubenews.Reconcile(gh, store, repo)
+							return err
+						}
+						_, err := kubenews.SyncResource(gh, store, repo, resource)
+						return err
+					})
+				},
+			})
+		}
+	}
+
+	return jobs
+}
+
+// digestJobs creates a job for each scheduled digest.
+func digestJobs(store kubenews.Store, locker kubenews.Locker) []kubenews.Job {
+	jobs := []kubenews.Job{}
+	for _, d := range digestSchedules() {
+		d := d
+		if d.Repository == "" {
+			d.Repository = "kubernetes/kubernetes"
+		}
+		if d.Days == 0 {
+			d.Days = 7
+		}
+		if !d.Email && !d.Webhooks {
+			log.WithField("digest", d.Name).Fatal("digest schedule has neither email nor webhooks set")
+		}
+
+		name := fmt.Sprintf("digest %s", d.Name)
+		jobs = append(jobs, kubenews.Job{
+			Name:     name,
+			Schedule: parseSchedule(d.Schedule),
+			Run: func(ctx context.Context) error {
+				return withLock(ctx, locker, d.Repository, name, func() error {
+					return publishScheduledDigest(ctx, store, d)
+				})
+			},
+		})
+	}
+
+	return jobs
+}
+
+// publishScheduledDigest publishes a digest of the last days to the email
+// lists and webhook routes.
+func publishScheduledDigest(ctx context.Context, store kubenews.Store, d kubenews.DigestSchedule) error {
+	from, to := dateRange("", "", d.Days)
+
+	if d.Webhooks {
+		if err := publishDigests(ctx, store, d.Repository, from, to, 10, ""); err != nil {
+			return err
+		}
+	}
+
+	if d.Email {
+		opts := kubenews.DigestOptions{
+			Repository:  d.Repository,
+			From:        from,
+			To:          to,
+			SIGs:        sigMap(),
+			FlakeLabels: flakeLabels(),
+			FlakeLimit:  10,
+		}
+		if err := sendDigests(store, opts, "", "", false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseSchedule(expr string) kubenews.Schedule {
+	schedule, err := kubenews.ParseSchedule(expr)
+	if err != nil {
+		log.WithError(err).Fatal("invalid schedule")
+	}
+
+	return schedule
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"kubenews"
+	"os"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchRepo   string
+	searchState  string
+	searchLabels []string
+	searchQuery  string
+	searchLimit  int
+	searchFormat string
+)
+
+func init() {
+	searchCmd.Flags().StringVar(&searchRepo, "repo", "kubernetes/kubernetes", "repository")
+	searchCmd.Flags().StringVar(&searchState, "state", "", "only include issues in this state: open or closed")
+	searchCmd.Flags().StringSliceVar(&searchLabels, "label", nil, "only include issues with this label, may be repeated")
+	searchCmd.Flags().StringVar(&searchQuery, "query", "", "only include issues matching this query or saved query")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 20, "maximum number of results")
+	searchCmd.Flags().StringVar(&searchFormat, "format", "text", "output format: text or json")
+	RootCmd.AddCommand(searchCmd)
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search QUERY",
+	Short: "Search issues",
+	Long: `Search issue titles, bodies and comments. Results are ranked by relevance,
+with matched words in snippets wrapped in **. Qualifiers in the query filter the
+results, e.g. "etcd quorum is:open label:sig/api-machinery".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			log.Fatal("search text is required")
+		}
+
+		store := openStore()
+
+		if err := store.Migrate(); err != nil {
+			log.WithError(err).Fatal("unable to migrate database")
+		}
+
+		db := postgresDB(store, "search")
+
+		expr := strings.Join(args, " ")
+		if searchQuery != "" {
+			expr += " " + savedQuery(searchQuery)
+		}
+
+		query, err := kubenews.ParseQuery(expr, sigMap())
+		if err != nil {
+			log.WithError(err).Fatal("invalid query")
+		}
+
+		results, err := kubenews.SearchIssues(db, kubenews.SearchQuery{
+			Repository: searchRepo,
+			Text:       strings.Join(query.Words(), " "),
+			State:      searchState,
+			Labels:     searchLabels,
+			Filter:     query,
+			Limit:      searchLimit,
+		})
+		if err != nil {
+			log.WithError(err).Fatal("unable to search issues")
+		}
+
+		if err := kubenews.SearchResults(results).Write(os.Stdout, searchFormat); err != nil {
+			log.WithError(err).Fatal("unable to write results")
+		}
+	},
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"kubenews"
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr    string
+	serveBaseURL string
+	serveRepo    string
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "address to listen on")
+	serveCmd.Flags().StringVar(&serveBaseURL, "base-url", "http://localhost:8080", "URL the server is reachable at")
+	serveCmd.Flags().StringVar(&serveRepo, "repo", "kubernetes/kubernetes", "repository shown on the dashboard")
+	RootCmd.AddCommand(serveCmd)
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a web dashboard, a read only JSON API and Atom feeds",
+	Long: `Serve a web dashboard of digests, backlogs, trends and issue history, a read
+only JSON API over the synced data under /api/v1/ and Atom feeds under /feeds/,
+so dashboards can be built without database credentials. Prometheus metrics are
+served at /metrics.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		if err := store.Migrate(); err != nil {
+			log.WithError(err).Fatal("unable to migrate database")
+		}
+
+		sigs := sigMap()
+
+		mux := http.NewServeMux()
+		api := kubenews.NewAPI(store, sigs)
+		api.Taxonomy = taxonomy()
+		api.Bots = bots()
+		mux.Handle("/api/v1/", api)
+		mux.Handle("/feeds/", http.StripPrefix("/feeds", &kubenews.Feeds{
+			Store:   store,
+			SIGs:    sigs,
+			BaseURL: serveBaseURL + "/feeds",
+			Days:    30,
+			Limit:   50,
+			Queries: savedQueries(),
+		}))
+		mux.Handle("/metrics", &kubenews.MetricsHandler{Store: store})
+		mux.Handle("/", kubenews.NewDashboard(store, sigs, taxonomy(), serveRepo))
+
+		log.WithField("addr", serveAddr).Info("serving")
+		if err := http.ListenAndServe(serveAddr, mux); err != nil {
+			log.WithError(err).Fatal("unable to serve")
+		}
+	},
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"kubenews"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotRepos    []string
+	snapshotBackfill bool
+	snapshotFrom     string
+	snapshotTo       string
+)
+
+func init() {
+	snapshotCmd.Flags().StringSliceVar(&snapshotRepos, "repo", []string{"kubernetes/kubernetes"}, "repositories to snapshot")
+	snapshotCmd.Flags().BoolVar(&snapshotBackfill, "backfill", false, "reconstruct past days from the event log")
+	snapshotCmd.Flags().StringVar(&snapshotFrom, "from", "", "first day to backfill (YYYY-MM-DD)")
+	snapshotCmd.Flags().StringVar(&snapshotTo, "to", "", "last day to backfill (YYYY-MM-DD), defaults to today")
+	RootCmd.AddCommand(snapshotCmd)
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Record daily open backlog counts",
+	Long:  "Record open issue counts per repository, label, SIG and milestone for trend reports",
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		if err := store.Migrate(); err != nil {
+			log.WithError(err).Fatal("unable to migrate database")
+		}
+
+		today := time.Now().UTC()
+		days := []time.Time{today}
+		if snapshotBackfill {
+			if snapshotFrom == "" {
+				log.Fatal("--backfill requires --from")
+			}
+
+			from, to := dateRange(snapshotFrom, snapshotTo, 0)
+
+			days = []time.Time{}
+			for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+				days = append(days, day)
+			}
+		}
+
+		sigs := sigMap()
+		for _, repo := range snapshotRepos {
+			var h *kubenews.History
+			var err error
+			if snapshotBackfill {
+				h, err = store.LoadRepositoryHistory(repo)
+			} else {
+				var open []kubenews.Issue
+				open, err = store.OpenIssues(repo)
+				h = kubenews.NewHistory(open, nil, nil)
+			}
+			if err != nil {
+				log.WithError(err).WithField("repo", repo).Fatal("unable to load issues")
+			}
+
+			for _, day := range days {
+				snapshots := kubenews.BacklogSnapshots(day, repo, h, sigs)
+				if err := store.SaveSnapshots(snapshots); err != nil {
+					log.WithError(err).WithField("repo", repo).Fatal("unable to save snapshots")
+				}
+			}
+		}
+	},
+}
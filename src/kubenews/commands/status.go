@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"kubenews"
+	"os"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	statusFormat string
+	statusRepo   string
+	statusLimit  int
+)
+
+func init() {
+	viper.SetDefault("status.stale_after", "1h")
+
+	statusCmd.Flags().StringVar(&statusFormat, "format", "text", "output format: text or json")
+	statusCmd.Flags().StringVar(&statusRepo, "repo", "", "only show sync runs of a repository")
+	statusCmd.Flags().IntVar(&statusLimit, "limit", 20, "number of recent sync runs to show")
+	statusCmd.Flags().Duration("stale", time.Hour, "show resources not synced successfully for this long as stale")
+	viper.BindPFlag("status.stale_after", statusCmd.Flags().Lookup("stale"))
+	RootCmd.AddCommand(statusCmd)
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of sync and publish jobs",
+	Long: `Show how fresh the synced data of each repository is, the most recent syncs
+and their failures, and which repositories are locked by running sync and
+publish jobs, by which process and for how long. Locks are only visible across
+processes with the postgres database driver.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openStore()
+
+		if err := store.Migrate(); err != nil {
+			log.WithError(err).Fatal("unable to migrate database")
+		}
+
+		freshness, err := store.LoadSyncFreshness()
+		if err != nil {
+			log.WithError(err).Fatal("unable to load sync freshness")
+		}
+
+		runs, err := store.LoadSyncRuns(statusRepo, statusLimit)
+		if err != nil {
+			log.WithError(err).Fatal("unable to load sync runs")
+		}
+
+		status := &kubenews.Status{
+			Freshness:  freshness,
+			Runs:       runs,
+			Leases:     []kubenews.Lease{},
+			StaleAfter: viper.GetDuration("status.stale_after"),
+		}
+		if pg, ok := store.(*kubenews.PostgresStore); ok {
+			leases, err := kubenews.LoadLeases(pg.DB)
+			if err != nil {
+				log.WithError(err).Fatal("unable to load locks")
+			}
+			status.Leases = leases
+		}
+
+		if err := status.Write(os.Stdout, statusFormat); err != nil {
+			log.WithError(err).Fatal("unable to write status")
+		}
+	},
+}
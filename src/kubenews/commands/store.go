@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"kubenews"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+)
+
+func init() {
+	viper.SetDefault("database.driver", "postgres")
+	viper.SetDefault("database.path", "kubenews.db")
+	viper.SetDefault("lock.policy", kubenews.LockWait)
+	viper.SetDefault("lock.timeout", "30m")
+}
+
+// openStore opens the store selected by database.driver in the config:
+// postgres, or sqlite in the file at database.path.
+func openStore() kubenews.Store {
+	switch driver := viper.GetString("database.driver"); driver {
+	case "postgres":
+		db, err := kubenews.NewDB()
+		if err != nil {
+			log.WithError(err).Fatal("unable to connect to database")
+		}
+		return kubenews.NewPostgresStore(db)
+	case "sqlite":
+		store, err := kubenews.NewSQLiteStore(viper.GetString("database.path"))
+		if err != nil {
+			log.WithError(err).Fatal("unable to open database")
+		}
+		return store
+	default:
+		log.WithField("driver", driver).Fatal("unknown database driver")
+		return nil
+	}
+}
+
+// postgresDB returns the Postgres connection of a store, for full-text
+// search, which needs Postgres.
+func postgresDB(store kubenews.Store, feature string) *sqlx.DB {
+	pg, ok := store.(*kubenews.PostgresStore)
+	if !ok {
+		log.Fatalf("%s requires the postgres database driver", feature)
+	}
+
+	return pg.DB
+}
+
+// repoLocker returns the locker for jobs on a store, following the lock
+// policy in the config. Postgres stores are locked across processes, other
+// stores only within this process.
+func repoLocker(store kubenews.Store) kubenews.Locker {
+	opts := kubenews.LockOptions{
+		Policy:  viper.GetString("lock.policy"),
+		Timeout: viper.GetDuration("lock.timeout"),
+	}
+	if opts.Policy != kubenews.LockWait && opts.Policy != kubenews.LockSkip {
+		log.WithField("policy", opts.Policy).Fatal("lock.policy must be wait or skip")
+	}
+
+	if pg, ok := store.(*kubenews.PostgresStore); ok {
+		return kubenews.NewAdvisoryLocker(pg.DB, opts)
+	}
+
+	return kubenews.NewLocalLocker(opts)
+}
+
+// withLock runs fn holding the lock for a repository. If the lock is held and
+// the policy is to skip, or ctx is done while waiting for it, fn isn't run
+// and a warning is logged.
+func withLock(ctx context.Context, locker kubenews.Locker, repo, job string, fn func() error) error {
+	err := kubenews.WithLock(ctx, locker, repo, job, fn)
+	switch {
+	case err == kubenews.ErrLocked:
+		log.WithFields(log.Fields{"repo": repo, "job": job}).Warn("repository is locked by another job, skipping")
+		return nil
+	case err != nil && err == ctx.Err():
+		log.WithFields(log.Fields{"repo": repo, "job": job}).Warn("stopped waiting for lock, skipping")
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"fmt"
+	"kubenews"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(templatesCmd)
+}
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates [name]",
+	Short: "List or print the built in report templates",
+	Long: `List the built in report templates, or print one to use as the starting
+point for a custom template. Pass a template to report commands with --template.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			for _, name := range kubenews.BuiltinTemplateNames() {
+				fmt.Println(name)
+			}
+			return
+		}
+
+		if len(args) > 1 {
+			log.Fatal("expected a single template name")
+		}
+
+		source, ok := kubenews.BuiltinTemplates[args[0]]
+		if !ok {
+			log.WithField("template", args[0]).Fatal("unknown template")
+		}
+
+		fmt.Print(source)
+	},
+}
@@ -2,49 +2,74 @@ package commands
 
 import (
 	"kubenews"
+	"net/http"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/net/context"
 )
 
+var updateReconcile bool
+
 func init() {
+	updateCmd.Flags().BoolVar(&updateReconcile, "reconcile", false,
+		"also walk every open issue to find deleted and transferred ones")
+	updateCmd.Flags().String("pushgateway", "", "push metrics to this Prometheus pushgateway URL after updating")
+	viper.BindPFlag("metrics.pushgateway", updateCmd.Flags().Lookup("pushgateway"))
 	RootCmd.AddCommand(updateCmd)
 }
 
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update kubernetes issues",
-	Long:  "Retrieve new kubernetes issues and update local data store",
+	Long: `Retrieve new kubernetes issues and update local data store. Updates only
+fetch what changed since the last one, so they never learn that an issue was
+deleted or transferred to another repository. With --reconcile, every open
+issue is compared with the data store: transferred issues are followed to their
+new repository and deleted ones are marked as deleted.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		githubToken := viper.GetString("github_token")
 
-		db, err := kubenews.NewDB()
-		if err != nil {
-			log.WithError(err).Fatal("unable to connect to database")
+		store := openStore()
+
+		if err := store.Migrate(); err != nil {
+			log.WithError(err).Fatal("unable to migrate database")
 		}
 
 		gh := kubenews.NewGithub(githubToken)
 		repo := "kubernetes/kubernetes"
 
-		lastUpdate, err := kubenews.LastIssueUpdate(db, repo)
-		if err != nil {
-			log.WithError(err).Fatal("unable to retrieve last issue update")
-		}
-		log.WithFields(log.Fields{
-			"lastUpdate": lastUpdate.At,
-			"repo":       repo}).Info("issues last update")
+		err := withLock(context.Background(), repoLocker(store), repo, "update", func() error {
+			if err := kubenews.Sync(gh, store, repo); err != nil {
+				return err
+			}
+			if !updateReconcile {
+				return nil
+			}
 
-		issues, err := gh.ListRepoIssues(repo, lastUpdate.At)
+			_, err := kubenews.Reconcile(gh, store, repo)
+			return errors.Wrap(err, "reconcile")
+		})
+		pushMetrics("kubenews_update")
 		if err != nil {
-			log.WithError(err).Fatal("list all issues")
+			log.WithError(err).WithField("repo", repo).Fatal("unable to update")
 		}
+	},
+}
 
-		log.WithField("issueCount", len(issues)).Info("triaging issues")
+// pushMetrics pushes the metrics of a one-shot job to the pushgateway in the
+// config, if there is one.
+func pushMetrics(job string) {
+	gateway := viper.GetString("metrics.pushgateway")
+	if gateway == "" {
+		return
+	}
 
-		if err := kubenews.ImportIssues(db, repo, issues); err != nil {
-			log.WithError(err).Fatal("cannot import issues")
-		}
-
-	},
+	client := &http.Client{Timeout: 30 * time.Second}
+	if err := kubenews.PushMetrics(client, gateway, job); err != nil {
+		log.WithError(err).WithField("pushgateway", gateway).Warn("unable to push metrics")
+	}
 }
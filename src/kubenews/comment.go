@@ -0,0 +1,136 @@
+package kubenews
+
+import (
+	"database/sql"
+	"path"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// Comment is a comment on a Github issue or pull request.
+type Comment struct {
+	ID          int        `db:"id"`
+	Repository  string     `db:"repository"`
+	IssueNumber int        `db:"issue_number"`
+	User        string     `db:"created_by"`
+	Body        string     `db:"body"`
+	CreatedAt   *time.Time `db:"created_at"`
+	UpdatedAt   *time.Time `db:"updated_at"`
+}
+
+// LastCommentUpdate retrieves the last time a comment was updated for a repository.
+func (s *sqlStore) LastCommentUpdate(repository string) (*LastUpdate, error) {
+	lastUpdate := LastUpdate{}
+	if err := s.get(&lastUpdate, lastCommentUpdateSQL, repository); err != nil {
+		if err == sql.ErrNoRows {
+			return &LastUpdate{Repository: repository}, nil
+		}
+
+		return nil, errors.Wrap(err, "unable to retrieve last comment update")
+	}
+
+	return &lastUpdate, nil
+}
+
+// ImportComments imports comments to a store. If the comment exists, it is
+// updated. The references in each comment are saved too.
+func ImportComments(s Store, repository string, inComments []github.IssueComment) (Saved, error) {
+	comments := []Comment{}
+	sources := []ReferenceSource{}
+	for _, in := range inComments {
+		comment, err := ConvertComment(repository, in)
+		if err != nil {
+			log.WithError(err).Warn("skipping comment")
+			continue
+		}
+
+		comments = append(comments, comment)
+		sources = append(sources, commentReferences(comment))
+	}
+
+	saved, err := s.SaveComments(comments)
+	if err != nil {
+		return saved, err
+	}
+
+	return saved, s.SaveReferences(sources)
+}
+
+// SaveComments inserts or updates comments.
+func (s *sqlStore) SaveComments(comments []Comment) (Saved, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return Saved{}, errors.Wrap(err, "import comment failure")
+	}
+
+	saved := Saved{}
+	log.WithField("commentCount", len(comments)).Info("updating or importing comments")
+	for _, comment := range comments {
+		inserted, err := s.upsert(tx, upsertCommentSQL, comment.ID, comment.Repository, comment.IssueNumber,
+			comment.User, comment.Body, comment.CreatedAt, comment.UpdatedAt)
+		if err != nil {
+			tx.Rollback()
+			return Saved{}, errors.Wrap(err, "insert comment")
+		}
+		saved.count(inserted)
+	}
+
+	return saved, tx.Commit()
+}
+
+// ConvertComment converts a comment from the github api client to our format.
+func ConvertComment(repository string, in github.IssueComment) (Comment, error) {
+	if in.ID == nil || in.IssueURL == nil {
+		return Comment{}, errors.New("comment is missing id or issue url")
+	}
+
+	number, err := strconv.Atoi(path.Base(*in.IssueURL))
+	if err != nil {
+		return Comment{}, errors.Wrapf(err, "invalid issue url %s", *in.IssueURL)
+	}
+
+	comment := Comment{
+		ID:          *in.ID,
+		Repository:  repository,
+		IssueNumber: number,
+		CreatedAt:   in.CreatedAt,
+		UpdatedAt:   in.UpdatedAt,
+	}
+
+	if in.Body != nil {
+		comment.Body = *in.Body
+	}
+
+	if in.User != nil && in.User.Login != nil {
+		comment.User = *in.User.Login
+	}
+
+	return comment, nil
+}
+
+var (
+	upsertCommentSQL = upsert{insert: `
+  INSERT INTO comments
+  (id, repository, issue_number, created_by, body, created_at, updated_at)
+
+  VALUES
+  ($1, $2, $3, $4, $5, $6, $7)
+
+  ON conflict (id)
+  DO UPDATE SET (body, updated_at) = ($5, $7)
+  WHERE comments.id = $1`,
+
+		update: `
+  UPDATE comments SET (body, updated_at) = ($5, $7)
+  WHERE id = $1`,
+	}
+
+	lastCommentUpdateSQL = `
+  SELECT updated_at FROM comments
+  WHERE repository = $1
+  ORDER BY updated_at desc limit 1`
+)
@@ -0,0 +1,344 @@
+package kubenews
+
+import (
+	"bytes"
+	"database/sql"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+)
+
+// LabelChange is a label being added to or removed from an issue.
+type LabelChange struct {
+	At    time.Time
+	Actor string
+	Label string
+	Added bool
+}
+
+// LabelHistory extracts label changes from an issue's events.
+func LabelHistory(events []IssueEvent) []LabelChange {
+	changes := []LabelChange{}
+	for _, e := range events {
+		if (e.Event != "labeled" && e.Event != "unlabeled") || e.CreatedAt == nil {
+			continue
+		}
+
+		changes = append(changes, LabelChange{
+			At:    *e.CreatedAt,
+			Actor: e.Actor,
+			Label: e.Label,
+			Added: e.Event == "labeled",
+		})
+	}
+
+	return changes
+}
+
+// LoadIssue retrieves an issue by number. It returns nil if it doesn't exist.
+func (s *sqlStore) LoadIssue(repository string, number int) (*Issue, error) {
+	issue := &Issue{}
+	err := s.get(issue, issueSQL, repository, number)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve issue")
+	}
+
+	return issue, nil
+}
+
+// LoadIssueEvents retrieves the events for an issue, oldest first.
+func (s *sqlStore) LoadIssueEvents(repository string, number int) ([]IssueEvent, error) {
+	events := []IssueEvent{}
+	if err := s.selectx(&events, issueEventsSQL, repository, number); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve events")
+	}
+
+	return events, nil
+}
+
+// Dashboard is a server rendered web UI over the datastore, for people who
+// don't use the CLI.
+type Dashboard struct {
+	SIGs     *SIGMap
+	Taxonomy *Taxonomy
+	// Repository is shown when a request doesn't pick one with ?repo=.
+	Repository string
+
+	store Store
+	mux   *http.ServeMux
+	pages map[string]*template.Template
+}
+
+// NewDashboard creates an instance of Dashboard over a store.
+func NewDashboard(store Store, sigs *SIGMap, taxonomy *Taxonomy, repository string) *Dashboard {
+	d := &Dashboard{
+		SIGs:       sigs.orDefault(),
+		Taxonomy:   taxonomy,
+		Repository: repository,
+		store:      store,
+		mux:        http.NewServeMux(),
+		pages:      map[string]*template.Template{},
+	}
+	if d.Taxonomy == nil {
+		d.Taxonomy = NewTaxonomy(nil)
+	}
+
+	funcs := template.FuncMap(TemplateFuncs(d.SIGs, d.Taxonomy))
+	funcs["markdown"] = func(s string) template.HTML { return template.HTML(MarkdownToHTML([]byte(s))) }
+	layout := template.Must(template.New("layout").Funcs(funcs).Parse(dashboardLayout))
+	for name, source := range dashboardPages {
+		d.pages[name] = template.Must(template.Must(layout.Clone()).Parse(source))
+	}
+
+	d.mux.HandleFunc("/", d.index)
+	d.mux.HandleFunc("/digest", d.digest)
+	d.mux.HandleFunc("/digests/", d.storedDigest)
+	d.mux.HandleFunc("/backlog", d.backlog)
+	d.mux.HandleFunc("/trends", d.trends)
+	d.mux.HandleFunc("/issues/", d.issue)
+	return d
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mux.ServeHTTP(w, r)
+}
+
+// pageData is the data every dashboard page is rendered with.
+type pageData struct {
+	Title      string
+	Repository string
+	Query      url.Values
+	Data       interface{}
+}
+
+func (d *Dashboard) repo(r *http.Request) string {
+	if repo := r.URL.Query().Get("repo"); repo != "" {
+		return repo
+	}
+
+	return d.Repository
+}
+
+func (d *Dashboard) render(w http.ResponseWriter, r *http.Request, page, title string, data interface{}) {
+	var buf bytes.Buffer
+	err := d.pages[page].ExecuteTemplate(&buf, "layout", pageData{
+		Title:      title,
+		Repository: d.repo(r),
+		Query:      r.URL.Query(),
+		Data:       data,
+	})
+	if err != nil {
+		d.fail(w, r, errors.Wrapf(err, "render %s", page))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+func (d *Dashboard) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if _, ok := err.(apiError); ok {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.WithError(err).WithField("path", r.URL.Path).Error("dashboard request failed")
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
+
+func (d *Dashboard) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	digests, _, err := d.store.LoadDigestDeliveries(d.repo(r), "", 20, 0)
+	if err != nil {
+		d.fail(w, r, err)
+		return
+	}
+
+	d.render(w, r, "index", "Digests", digests)
+}
+
+func (d *Dashboard) digest(w http.ResponseWriter, r *http.Request) {
+	from, to, err := period(r.URL.Query())
+	if err != nil {
+		d.fail(w, r, err)
+		return
+	}
+
+	digest, err := BuildDigest(d.store, DigestOptions{
+		Repository: d.repo(r),
+		From:       from,
+		To:         to,
+		SIG:        r.URL.Query().Get("sig"),
+		SIGs:       d.SIGs,
+	})
+	if err != nil {
+		d.fail(w, r, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := digest.WriteMarkdown(&buf); err != nil {
+		d.fail(w, r, err)
+		return
+	}
+
+	d.render(w, r, "digest", digest.Title(), buf.String())
+}
+
+func (d *Dashboard) storedDigest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/digests/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	digest, err := d.store.LoadDigestDelivery(id)
+	if err != nil {
+		d.fail(w, r, err)
+		return
+	}
+	if digest == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	d.render(w, r, "digest", digest.Title, digest.Markdown)
+}
+
+// backlogPage is the data for the backlog page.
+type backlogPage struct {
+	SIG    string
+	Filter string
+	Counts []SIGSummary
+	Issues []Issue
+	Now    time.Time
+}
+
+func (d *Dashboard) backlog(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	page := backlogPage{SIG: v.Get("sig"), Filter: v.Get("filter"), Now: time.Now()}
+
+	open, err := d.store.OpenIssues(d.repo(r))
+	if err != nil {
+		d.fail(w, r, err)
+		return
+	}
+
+	if page.Filter != "" {
+		filter, err := ParseLabelFilter(d.Taxonomy, page.Filter)
+		if err != nil {
+			d.fail(w, r, apiError{err})
+			return
+		}
+		open = FilterIssues(open, filter)
+	}
+
+	counts := d.SIGs.CountBySIG(open)
+	for _, name := range counts.Names() {
+		page.Counts = append(page.Counts, SIGSummary{Name: name, Open: counts[name]})
+	}
+
+	if page.SIG != "" {
+		page.Issues = d.SIGs.FilterSIG(open, page.SIG)
+	}
+
+	d.render(w, r, "backlog", "Open backlog", page)
+}
+
+// trendsPage is the data for the trends page.
+type trendsPage struct {
+	SIG   string
+	Weeks []WeekActivity
+	Chart svgChart
+}
+
+func (d *Dashboard) trends(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	weeks := 12
+	if s := v.Get("weeks"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 104 {
+			d.fail(w, r, badRequest("invalid weeks %q", s))
+			return
+		}
+		weeks = n
+	}
+
+	to := time.Now().UTC()
+	from := startOfWeek(to).AddDate(0, 0, -7*(weeks-1))
+
+	issues, err := d.store.IssuesActiveBetween(d.repo(r), from, to)
+	if err != nil {
+		d.fail(w, r, err)
+		return
+	}
+
+	page := trendsPage{SIG: v.Get("sig")}
+	if page.SIG != "" {
+		issues = d.SIGs.FilterSIG(issues, page.SIG)
+	}
+
+	page.Weeks = WeeklyActivity(issues, from, to)
+	page.Chart = newActivityChart(page.Weeks)
+
+	d.render(w, r, "trends", "Opened and closed per week", page)
+}
+
+// issuePage is the data for the issue detail page.
+type issuePage struct {
+	Issue   Issue
+	History []LabelChange
+}
+
+func (d *Dashboard) issue(w http.ResponseWriter, r *http.Request) {
+	number, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/issues/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	issue, err := d.store.LoadIssue(d.repo(r), number)
+	if err != nil {
+		d.fail(w, r, err)
+		return
+	}
+	if issue == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	events, err := d.store.LoadIssueEvents(issue.Repository, issue.Number)
+	if err != nil {
+		d.fail(w, r, err)
+		return
+	}
+
+	d.render(w, r, "issue", issue.Title, issuePage{Issue: *issue, History: LabelHistory(events)})
+}
+
+var (
+	issueSQL = `
+  SELECT id, number, state, title, body, created_by, labels, assignee, closed_at,
+    created_at, updated_at, milestone, repository, pull_request
+  FROM issues
+  WHERE repository = $1 AND number = $2`
+
+	issueEventsSQL = `
+  SELECT id, repository, issue_number, event, actor, label, milestone, assignee, commit_id, created_at
+  FROM issue_events
+  WHERE repository = $1 AND issue_number = $2
+  ORDER BY created_at, id`
+)
@@ -0,0 +1,150 @@
+package kubenews
+
+var (
+	// dashboardLayout wraps every dashboard page. Pages define "content".
+	dashboardLayout = `{{define "layout"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} · {{.Repository}} · kubenews</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #24292e; }
+header { background: #326ce5; padding: 0.6em 1em; }
+header a { color: #fff; margin-right: 1.2em; text-decoration: none; }
+header form { display: inline; float: right; }
+main { max-width: 1000px; margin: 0 auto; padding: 1em; }
+a { color: #0366d6; }
+table { border-collapse: collapse; }
+th, td { border-bottom: 1px solid #e1e4e8; padding: 4px 10px; text-align: left; vertical-align: top; }
+td.n { text-align: right; }
+.label { display: inline-block; border-radius: 3px; padding: 0 5px; font-size: 12px; background: #e1e4e8; margin: 1px; }
+.muted { color: #6a737d; }
+rect.opened { fill: #28a745; }
+rect.closed { fill: #6f42c1; }
+</style>
+</head>
+<body>
+<header>
+<a href="/?repo={{.Repository}}">Digests</a>
+<a href="/backlog?repo={{.Repository}}">Backlog</a>
+<a href="/trends?repo={{.Repository}}">Trends</a>
+<form action="" method="get">
+{{- range $k, $v := .Query}}{{if ne $k "repo"}}{{range $v}}<input type="hidden" name="{{$k}}" value="{{.}}">{{end}}{{end}}{{end -}}
+<input name="repo" value="{{.Repository}}" size="24" aria-label="repository">
+</form>
+</header>
+<main>
+<h1>{{.Title}}</h1>
+{{template "content" .}}
+</main>
+</body>
+</html>
+{{end}}`
+
+	dashboardPages = map[string]string{
+		"index": `{{define "content"}}
+<p><a href="/digest?repo={{.Repository}}">This week so far</a></p>
+{{with .Data}}
+<table>
+<tr><th>Period</th><th>Digest</th><th>List</th><th>Sent</th></tr>
+{{range .}}
+<tr>
+<td>{{date .From}} to {{date .To}}</td>
+<td><a href="/digests/{{.ID}}?repo={{.Repository}}">{{.Title}}</a></td>
+<td>{{.List}}</td>
+<td class="muted">{{datetime .SentAt}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p class="muted">No digests have been sent yet.</p>
+{{end}}
+{{end}}`,
+
+		"digest": `{{define "content"}}
+{{markdown .Data}}
+{{end}}`,
+
+		"backlog": `{{define "content"}}
+{{$repo := .Repository}}
+{{with .Data}}
+<form action="/backlog" method="get">
+<input type="hidden" name="repo" value="{{$repo}}">
+<input type="hidden" name="sig" value="{{.SIG}}">
+<input name="filter" value="{{.Filter}}" size="40" placeholder="kind=bug priority>=important-soon">
+<button>Filter</button>
+</form>
+<table>
+<tr><th>SIG</th><th>Open</th></tr>
+{{range .Counts}}
+<tr><td><a href="/backlog?repo={{$repo}}&sig={{.Name}}&filter={{$.Data.Filter}}">{{.Name}}</a></td><td class="n">{{.Open}}</td></tr>
+{{end}}
+</table>
+{{if .SIG}}
+<h2>sig/{{.SIG}}: {{len .Issues}} open</h2>
+<table>
+<tr><th>#</th><th>Title</th><th>Kind</th><th>Priority</th><th>Assignee</th><th>Age</th></tr>
+{{range .Issues}}
+<tr>
+<td><a href="/issues/{{.Number}}?repo={{$repo}}">{{.Number}}</a></td>
+<td>{{.Title}}</td>
+<td>{{join (kinds .) ", "}}</td>
+<td>{{priority .}}</td>
+<td>{{.Assignee}}</td>
+<td class="muted">{{with .CreatedAt}}{{since .}}{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+{{end}}
+{{end}}`,
+
+		"trends": `{{define "content"}}
+{{$repo := .Repository}}
+{{with .Data}}
+<form action="/trends" method="get">
+<input type="hidden" name="repo" value="{{$repo}}">
+<input name="sig" value="{{.SIG}}" placeholder="SIG, e.g. node">
+<button>Show</button>
+</form>
+<p><span class="label" style="background: #28a745; color: #fff;">opened</span>
+<span class="label" style="background: #6f42c1; color: #fff;">closed</span>
+<span class="muted">peak {{.Chart.Max}} per week</span></p>
+<svg width="{{.Chart.Width}}" height="{{.Chart.Height}}" role="img" aria-label="issues opened and closed per week">
+{{range .Chart.Bars}}<rect class="{{.Class}}" x="{{.X}}" y="{{.Y}}" width="{{.Width}}" height="{{.Height}}"><title>{{.Title}}</title></rect>
+{{end}}
+{{range .Chart.Labels}}<text x="{{.X}}" y="{{.Y}}" font-size="10">{{.Text}}</text>
+{{end}}
+</svg>
+<table>
+<tr><th>Week of</th><th>Opened</th><th>Closed</th></tr>
+{{range .Weeks}}<tr><td>{{date .Week}}</td><td class="n">{{.Opened}}</td><td class="n">{{.Closed}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{end}}`,
+
+		"issue": `{{define "content"}}
+{{with .Data}}
+{{with .Issue}}
+<p><a href="{{.HTMLURL}}">{{.Repository}}#{{.Number}}</a> · {{.State}}
+{{with .CreatedAt}} · opened {{date .}} by {{end}}{{.User}}
+{{with .Assignee}} · assigned to {{.}}{{end}}
+{{with .Milestone}} · milestone {{.}}{{end}}</p>
+<p>{{range .Labels}}<span class="label" style="background: #{{.Color}};">{{.Name}}</span>{{end}}</p>
+{{markdown .Body}}
+{{end}}
+<h2>Label history</h2>
+{{with .History}}
+<table>
+{{range .}}
+<tr><td class="muted">{{datetime .At}}</td><td>{{.Actor}}</td><td>{{if .Added}}added{{else}}removed{{end}}</td><td><span class="label">{{.Label}}</span></td></tr>
+{{end}}
+</table>
+{{else}}
+<p class="muted">No label changes recorded.</p>
+{{end}}
+{{end}}
+{{end}}`,
+	}
+)
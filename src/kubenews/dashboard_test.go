@@ -0,0 +1,92 @@
+package kubenews
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestWeeklyActivity(t *testing.T) {
+	// 2017-03-06 is a Monday.
+	from := time.Date(2017, 3, 8, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2017, 3, 20, 0, 0, 0, 0, time.UTC)
+
+	day := func(d int) *time.Time {
+		t := time.Date(2017, 3, d, 12, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	issues := []Issue{
+		{Number: 1, CreatedAt: day(6), ClosedAt: day(14)},
+		{Number: 2, CreatedAt: day(12)},
+		{Number: 3, CreatedAt: day(1), ClosedAt: day(7)},
+		{Number: 4, CreatedAt: day(21)},
+	}
+
+	weeks := WeeklyActivity(issues, from, to)
+	require.Equal(t, []WeekActivity{
+		{Week: time.Date(2017, 3, 6, 0, 0, 0, 0, time.UTC), Opened: 2, Closed: 1},
+		{Week: time.Date(2017, 3, 13, 0, 0, 0, 0, time.UTC), Closed: 1},
+	}, weeks)
+
+	chart := newActivityChart(weeks)
+	require.Equal(t, 2, chart.Max)
+	require.Len(t, chart.Bars, 4)
+	require.Equal(t, 200, chart.Bars[0].Height)
+	require.Equal(t, 0, chart.Bars[2].Height)
+}
+
+func TestLabelHistory(t *testing.T) {
+	now := time.Now()
+	changes := LabelHistory([]IssueEvent{
+		{Event: "labeled", Label: "sig/node", Actor: "alice", CreatedAt: &now},
+		{Event: "closed", CreatedAt: &now},
+		{Event: "unlabeled", Label: "sig/node", Actor: "bob", CreatedAt: &now},
+	})
+
+	require.Equal(t, []LabelChange{
+		{At: now, Actor: "alice", Label: "sig/node", Added: true},
+		{At: now, Actor: "bob", Label: "sig/node"},
+	}, changes)
+}
+
+func TestDashboardIssue(t *testing.T) {
+	stdlibdb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := sqlx.NewDb(stdlibdb, "mockdriver")
+
+	created := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM issues WHERE repository = \\$1 AND number = \\$2").WithArgs("org/repo", 10).
+		WillReturnRows(sqlmock.NewRows(issueColumns).
+			AddRow(1, 10, "open", "Kubelet <crash>", "**details**", "alice",
+				[]byte(`[{"URL":"u","Name":"sig/node","Color":"fff"}]`), "", nil, created, created, "", "org/repo", false))
+	mock.ExpectQuery("FROM issue_events").WithArgs("org/repo", 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "repository", "issue_number", "event", "actor", "label",
+			"milestone", "assignee", "commit_id", "created_at"}).
+			AddRow(1, "org/repo", 10, "labeled", "bob", "sig/node", "", "", "", created))
+	mock.ExpectQuery("FROM issues WHERE repository = \\$1 AND number = \\$2").WithArgs("org/repo", 11).
+		WillReturnRows(sqlmock.NewRows(issueColumns))
+
+	d := NewDashboard(NewPostgresStore(db), nil, nil, "org/repo")
+
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest("GET", "/issues/10", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	body := rec.Body.String()
+	require.Contains(t, body, "<h1>Kubelet &lt;crash&gt;</h1>")
+	require.Contains(t, body, "<strong>details</strong>")
+	require.Contains(t, body, "opened 2017-03-01 by alice")
+	require.Contains(t, body, "<td>bob</td><td>added</td>")
+
+	rec = httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest("GET", "/issues/11", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,220 @@
+package kubenews
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// DigestOptions are options for generating a digest.
+type DigestOptions struct {
+	Repository string
+	From       time.Time
+	To         time.Time
+	// SIG limits the digest to a single SIG. If empty, all SIGs are included.
+	SIG  string
+	SIGs *SIGMap
+	// Filter limits the digest to matching issues.
+	Filter IssueFilter
+	// DuplicateThreshold is the similarity above which newly opened issues are
+	// flagged as possible duplicates of open issues. Zero disables it.
+	DuplicateThreshold float64
+	// FlakeLabels are the labels of flaky test reports.
+	FlakeLabels []string
+	// FlakeLimit is the number of flaky tests to include. Zero disables it.
+	FlakeLimit int
+	// Metrics includes response and close times for issues opened in the
+	// period.
+	Metrics bool
+	// Bots are the accounts whose comments aren't a response. If nil,
+	// DefaultBots are used.
+	Bots Bots
+}
+
+// Digest is a summary of issue activity for a repository over a time period.
+type Digest struct {
+	Repository string
+	SIG        string
+	From       time.Time
+	To         time.Time
+	Opened     []Issue
+	Closed     []Issue
+	OpenCount  int
+	SIGs       []SIGSummary
+	// PossibleDuplicates are opened issues which closely match existing ones.
+	PossibleDuplicates []PossibleDuplicate
+	// Metrics are the response and close times for issues opened in the
+	// period, by SIG.
+	Metrics *MetricsReport
+	// TopFlakes are the tests with the most flake reports in the period.
+	TopFlakes []FlakyTest
+	// Fixes are the pull requests which fix issues, by issue number.
+	Fixes map[int][]Fix
+}
+
+// FixedBy returns the merged pull requests which fix an issue.
+func (d *Digest) FixedBy(issue Issue) []Fix {
+	fixes := []Fix{}
+	for _, fix := range d.Fixes[issue.Number] {
+		if fix.Merged() {
+			fixes = append(fixes, fix)
+		}
+	}
+
+	return fixes
+}
+
+// SIGSummary is the activity for a SIG in a digest.
+type SIGSummary struct {
+	Name   string `json:"name"`
+	Opened int    `json:"opened"`
+	Closed int    `json:"closed"`
+	Open   int    `json:"open"`
+}
+
+// BuildDigest builds a digest from the issues in a store.
+func BuildDigest(s Store, opts DigestOptions) (*Digest, error) {
+	active, err := s.IssuesActiveBetween(opts.Repository, opts.From, opts.To)
+	if err != nil {
+		return nil, err
+	}
+
+	open, err := s.OpenIssues(opts.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	d := NewDigest(opts, active, open)
+
+	fixes, err := s.LoadFixes(opts.Repository)
+	if err != nil {
+		return nil, err
+	}
+	d.Fixes = FixesByIssue(fixes)
+
+	if opts.FlakeLimit > 0 {
+		labels := opts.FlakeLabels
+		if len(labels) == 0 {
+			labels = DefaultFlakeLabels
+		}
+
+		h, err := s.LoadLabeledHistory(opts.Repository, labels)
+		if err != nil {
+			return nil, err
+		}
+
+		if d.SIG != "" {
+			h.Issues = opts.SIGs.orDefault().FilterSIG(h.Issues, d.SIG)
+		}
+
+		d.TopFlakes = TopFlakes(h, opts.From, opts.To, opts.FlakeLimit)
+	}
+
+	if opts.Metrics {
+		h, err := s.LoadHistory(opts.Repository, opts.From, opts.To)
+		if err != nil {
+			return nil, err
+		}
+
+		h.Issues = FilterIssues(h.Issues, opts.Filter)
+		if d.SIG != "" {
+			h.Issues = opts.SIGs.orDefault().FilterSIG(h.Issues, d.SIG)
+		}
+
+		d.Metrics, err = ComputeMetrics([]*History{h}, MetricsOptions{
+			From:    opts.From,
+			To:      opts.To,
+			GroupBy: GroupBySIG,
+			SIGs:    opts.SIGs,
+			Bots:    opts.Bots,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// NewDigest creates a digest from issues active during the digest period and
+// the currently open issues.
+func NewDigest(opts DigestOptions, active, open []Issue) *Digest {
+	sigs := opts.SIGs.orDefault()
+
+	d := &Digest{
+		Repository: opts.Repository,
+		From:       opts.From,
+		To:         opts.To,
+		Opened:     []Issue{},
+		Closed:     []Issue{},
+	}
+
+	active = FilterIssues(active, opts.Filter)
+	open = FilterIssues(open, opts.Filter)
+
+	if opts.SIG != "" {
+		d.SIG = sigs.Canonical(opts.SIG)
+		active = sigs.FilterSIG(active, d.SIG)
+		open = sigs.FilterSIG(open, d.SIG)
+	}
+
+	for _, issue := range active {
+		if inPeriod(issue.CreatedAt, opts.From, opts.To) {
+			d.Opened = append(d.Opened, issue)
+		}
+		if inPeriod(issue.ClosedAt, opts.From, opts.To) {
+			d.Closed = append(d.Closed, issue)
+		}
+	}
+
+	d.OpenCount = len(open)
+
+	if opts.DuplicateThreshold > 0 {
+		d.PossibleDuplicates = findPossibleDuplicates(d.Opened, open, opts.DuplicateThreshold)
+	}
+
+	opened := sigs.CountBySIG(d.Opened)
+	closed := sigs.CountBySIG(d.Closed)
+	backlog := sigs.CountBySIG(open)
+
+	names := SIGCounts{}
+	for _, counts := range []SIGCounts{opened, closed, backlog} {
+		for name := range counts {
+			names[name]++
+		}
+	}
+
+	for _, name := range names.Names() {
+		if d.SIG != "" && name != d.SIG {
+			continue
+		}
+
+		d.SIGs = append(d.SIGs, SIGSummary{
+			Name:   name,
+			Opened: opened[name],
+			Closed: closed[name],
+			Open:   backlog[name],
+		})
+	}
+
+	return d
+}
+
+func inPeriod(t *time.Time, from, to time.Time) bool {
+	return t != nil && !t.Before(from) && t.Before(to)
+}
+
+// Title returns the title of the digest.
+func (d *Digest) Title() string {
+	if d.SIG != "" {
+		return fmt.Sprintf("%s: sig/%s digest", d.Repository, d.SIG)
+	}
+
+	return fmt.Sprintf("%s digest", d.Repository)
+}
+
+// WriteMarkdown writes the digest to w as Markdown using the built in digest
+// template.
+func (d *Digest) WriteMarkdown(w io.Writer) error {
+	return digestMarkdown.Execute(w, d)
+}
@@ -0,0 +1,51 @@
+package kubenews
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDigest(t *testing.T) {
+	from := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	before := from.AddDate(0, 0, -10)
+	during := from.AddDate(0, 0, 2)
+
+	opened := labeledIssue(1, "sig/node")
+	opened.CreatedAt = &during
+	opened.State = "open"
+
+	closed := labeledIssue(2, "sig/storage")
+	closed.CreatedAt = &before
+	closed.ClosedAt = &during
+	closed.State = "closed"
+
+	backlog := labeledIssue(3, "sig/node")
+	backlog.CreatedAt = &before
+	backlog.State = "open"
+
+	opts := DigestOptions{Repository: "org/repo", From: from, To: to}
+	d := NewDigest(opts, []Issue{opened, closed}, []Issue{opened, backlog})
+
+	require.Len(t, d.Opened, 1)
+	require.Len(t, d.Closed, 1)
+	require.Equal(t, 2, d.OpenCount)
+	require.Equal(t, []SIGSummary{
+		{Name: "node", Opened: 1, Open: 2},
+		{Name: "storage", Closed: 1},
+	}, d.SIGs)
+
+	opts.SIG = "node"
+	d = NewDigest(opts, []Issue{opened, closed}, []Issue{opened, backlog})
+	require.Len(t, d.Opened, 1)
+	require.Empty(t, d.Closed)
+	require.Equal(t, []SIGSummary{{Name: "node", Opened: 1, Open: 2}}, d.SIGs)
+
+	var buf bytes.Buffer
+	require.NoError(t, d.WriteMarkdown(&buf))
+	require.Contains(t, buf.String(), "# org/repo: sig/node digest")
+	require.Contains(t, buf.String(), "| node | 1 | 0 | 2 |")
+}
@@ -0,0 +1,98 @@
+package kubenews
+
+import (
+	"fmt"
+	"io"
+)
+
+// DuplicateReport lists clusters of likely duplicate open issues.
+type DuplicateReport struct {
+	Repository string
+	Threshold  float64
+	Clusters   []DuplicateCluster
+}
+
+// FindDuplicates clusters likely duplicates among open issues. Pull requests
+// are ignored.
+func FindDuplicates(repository string, open []Issue, threshold float64) *DuplicateReport {
+	issues := []Issue{}
+	for _, issue := range open {
+		if !issue.PullRequest {
+			issues = append(issues, issue)
+		}
+	}
+
+	return &DuplicateReport{
+		Repository: repository,
+		Threshold:  threshold,
+		Clusters:   NewSimilarityIndex(issues).Clusters(threshold),
+	}
+}
+
+// Title returns the title of the report.
+func (r *DuplicateReport) Title() string {
+	return fmt.Sprintf("%s: likely duplicate issues", r.Repository)
+}
+
+// WriteMarkdown writes the report to w as Markdown.
+func (r *DuplicateReport) WriteMarkdown(w io.Writer) error {
+	p := &printer{w: w}
+	p.printf("# %s\n\n", escapeMarkdown(r.Title()))
+
+	if len(r.Clusters) == 0 {
+		p.printf("None\n")
+		return p.err
+	}
+
+	for i, c := range r.Clusters {
+		p.printf("## Cluster %d (similarity %.2f)\n\n", i+1, c.Score())
+		p.issues(c.Issues)
+
+		for _, pair := range c.Pairs {
+			p.printf("* #%d ~ #%d: %.2f\n", pair.A, pair.B, pair.Score)
+		}
+		p.printf("\n")
+	}
+
+	return p.err
+}
+
+// PossibleDuplicate is a newly opened issue which is similar to existing
+// issues.
+type PossibleDuplicate struct {
+	Issue   Issue
+	Matches []Match
+}
+
+// findPossibleDuplicates matches each new issue against the existing issues
+// which were created before it.
+func findPossibleDuplicates(newIssues, existing []Issue, threshold float64) []PossibleDuplicate {
+	candidates := []Issue{}
+	for _, issue := range existing {
+		if !issue.PullRequest {
+			candidates = append(candidates, issue)
+		}
+	}
+
+	index := NewSimilarityIndex(candidates)
+
+	out := []PossibleDuplicate{}
+	for _, issue := range newIssues {
+		if issue.PullRequest || issue.CreatedAt == nil {
+			continue
+		}
+
+		matches := []Match{}
+		for _, m := range index.Similar(issue, threshold) {
+			if m.Issue.CreatedAt != nil && m.Issue.CreatedAt.Before(*issue.CreatedAt) {
+				matches = append(matches, m)
+			}
+		}
+
+		if len(matches) > 0 {
+			out = append(out, PossibleDuplicate{Issue: issue, Matches: matches})
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,413 @@
+package kubenews
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"math"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TLS modes for SMTP connections.
+const (
+	// SMTPStartTLS upgrades a plain connection with STARTTLS. It is the default.
+	SMTPStartTLS = "starttls"
+	// SMTPTLS connects with TLS, usually on port 465.
+	SMTPTLS = "tls"
+	// SMTPNoTLS never uses TLS. It is meant for local SMTP sinks.
+	SMTPNoTLS = "none"
+)
+
+// SMTPConfig are the settings for an SMTP server.
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// TLS is one of starttls, tls or none.
+	TLS string `mapstructure:"tls"`
+	// InsecureSkipVerify disables certificate verification.
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"`
+}
+
+// MailingList is a list of recipients for digests. A list with a SIG only
+// receives the digest for that SIG.
+type MailingList struct {
+	Name string   `mapstructure:"name"`
+	SIG  string   `mapstructure:"sig"`
+	To   []string `mapstructure:"to"`
+}
+
+// Message is a multipart email with a plain text and an HTML part.
+type Message struct {
+	ID      string
+	From    string
+	To      []string
+	Subject string
+	Date    time.Time
+	Text    string
+	HTML    string
+}
+
+// NewReportMessage renders a report as an email. The text part is the report's
+// Markdown. The HTML part is rendered with html if it is set, or from the
+// Markdown with layout otherwise.
+func NewReportMessage(r Report, html *Template, layout *template.Template) (*Message, error) {
+	var text bytes.Buffer
+	if err := r.WriteMarkdown(&text); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	var err error
+	if html != nil {
+		err = html.Execute(&body, r)
+	} else {
+		err = WriteHTML(&body, r, layout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		ID:      newMessageID(),
+		Subject: r.Title(),
+		Date:    time.Now(),
+		Text:    text.String(),
+		HTML:    body.String(),
+	}, nil
+}
+
+func newMessageID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return fmt.Sprintf("<%s.%d@kubenews>", hex.EncodeToString(b), time.Now().Unix())
+}
+
+// Bytes encodes the message in MIME format.
+func (m *Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	headers := []string{
+		"From: " + m.From,
+		"To: " + strings.Join(m.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date: " + m.Date.Format(time.RFC1123Z),
+		"Message-ID: " + m.ID,
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + mw.Boundary(),
+	}
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	}
+
+	for _, part := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "create message part")
+		}
+
+		qw := quotedprintable.NewWriter(w)
+		if _, err := qw.Write([]byte(part.body)); err != nil {
+			return nil, errors.Wrap(err, "write message part")
+		}
+		if err := qw.Close(); err != nil {
+			return nil, errors.Wrap(err, "write message part")
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, errors.Wrap(err, "write message")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Mailer sends email.
+type Mailer interface {
+	Send(m *Message) error
+}
+
+// SMTPMailer sends email through an SMTP server.
+type SMTPMailer struct {
+	Config  SMTPConfig
+	Timeout time.Duration
+}
+
+// NewSMTPMailer creates an instance of SMTPMailer.
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	if config.Port == 0 {
+		config.Port = 587
+		if config.TLS == SMTPTLS {
+			config.Port = 465
+		}
+	}
+
+	return &SMTPMailer{Config: config, Timeout: 30 * time.Second}
+}
+
+// Send sends a message.
+func (s *SMTPMailer) Send(m *Message) error {
+	b, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(s.Config.Host, strconv.Itoa(s.Config.Port))
+	tlsConfig := &tls.Config{ServerName: s.Config.Host, InsecureSkipVerify: s.Config.InsecureSkipVerify}
+
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: s.Timeout}
+	if s.Config.TLS == SMTPTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "unable to connect to %s", addr)
+	}
+
+	// a stalled server mustn't hang the send, and the repository lock with it
+	if err := conn.SetDeadline(time.Now().Add(s.Timeout)); err != nil {
+		conn.Close()
+		return errors.Wrap(err, "set deadline")
+	}
+
+	c, err := smtp.NewClient(conn, s.Config.Host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "smtp handshake")
+	}
+	defer c.Close()
+
+	switch s.Config.TLS {
+	case SMTPStartTLS, "":
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.Errorf("%s does not support STARTTLS", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return errors.Wrap(err, "starttls")
+		}
+	case SMTPTLS, SMTPNoTLS:
+	default:
+		return errors.Errorf("unknown TLS mode %q", s.Config.TLS)
+	}
+
+	if s.Config.Username != "" {
+		auth := smtp.PlainAuth("", s.Config.Username, s.Config.Password, s.Config.Host)
+		if err := c.Auth(auth); err != nil {
+			return errors.Wrap(err, "smtp auth")
+		}
+	}
+
+	if err := c.Mail(m.From); err != nil {
+		return errors.Wrap(err, "smtp mail from")
+	}
+	for _, to := range m.To {
+		if err := c.Rcpt(to); err != nil {
+			return errors.Wrapf(err, "smtp recipient %s", to)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return errors.Wrap(err, "smtp data")
+	}
+	if _, err := w.Write(b); err != nil {
+		return errors.Wrap(err, "smtp data")
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "smtp data")
+	}
+
+	return c.Quit()
+}
+
+// EMLMailer writes messages to .eml files in a directory instead of sending
+// them. It is used for dry runs.
+type EMLMailer struct {
+	Dir string
+}
+
+var unsafeFileRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Send writes a message to a file named after its subject and recipients.
+func (e *EMLMailer) Send(m *Message) error {
+	b, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(e.Dir, 0755); err != nil {
+		return errors.Wrapf(err, "unable to create %s", e.Dir)
+	}
+
+	name := unsafeFileRe.ReplaceAllString(m.Subject+"-"+strings.Join(m.To, "-"), "_") + ".eml"
+	path := filepath.Join(e.Dir, name)
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		return errors.Wrapf(err, "unable to write %s", path)
+	}
+
+	return nil
+}
+
+// Digest delivery statuses. A delivery is pending from just before the
+// digest is sent until it is known to be sent, so a digest which may have
+// been sent isn't sent again.
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+)
+
+// DigestDelivery records a digest sent to a mailing list.
+type DigestDelivery struct {
+	ID         int       `db:"id"`
+	Repository string    `db:"repository"`
+	List       string    `db:"list"`
+	SIG        string    `db:"sig"`
+	From       time.Time `db:"period_from"`
+	To         time.Time `db:"period_to"`
+	Title      string    `db:"title"`
+	Markdown   string    `db:"markdown"`
+	Recipients string    `db:"recipients"`
+	MessageID  string    `db:"message_id"`
+	SentAt     time.Time `db:"sent_at"`
+	Status     string    `db:"status"`
+}
+
+// FindDigestDelivery finds the delivery of a digest for a period to a list.
+// Periods are compared by day. It returns nil if the digest wasn't sent or
+// pending.
+func (s *sqlStore) FindDigestDelivery(repository, list string, from, to time.Time) (*DigestDelivery, error) {
+	d := &DigestDelivery{}
+	err := s.get(d, findDigestDeliverySQL, repository, list, from.Format(dateFormat), to.Format(dateFormat))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve digest delivery")
+	}
+
+	return d, nil
+}
+
+// LoadDigestDelivery retrieves a sent digest by id. It returns nil if it
+// doesn't exist.
+func (s *sqlStore) LoadDigestDelivery(id int) (*DigestDelivery, error) {
+	d := &DigestDelivery{}
+	err := s.get(d, digestDeliverySQL, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve digest")
+	}
+
+	return d, nil
+}
+
+// LoadDigestDeliveries retrieves a page of the digests sent for a
+// repository, newest first, along with the total number sent. An empty list
+// includes every list, and a limit of 0 includes every digest.
+func (s *sqlStore) LoadDigestDeliveries(repository, list string, limit, offset int) ([]DigestDelivery, int, error) {
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+
+	var total int
+	if err := s.get(&total, countDigestsSQL, repository, list); err != nil {
+		return nil, 0, errors.Wrap(err, "unable to count digests")
+	}
+
+	deliveries := []DigestDelivery{}
+	if err := s.selectx(&deliveries, digestsSQL, repository, list, limit, offset); err != nil {
+		return nil, 0, errors.Wrap(err, "unable to retrieve digests")
+	}
+
+	return deliveries, total, nil
+}
+
+// RecordDigestDelivery saves a digest delivery, replacing an earlier delivery
+// for the same period and list. The status defaults to sent.
+func (s *sqlStore) RecordDigestDelivery(d *DigestDelivery) error {
+	err := s.exec(s.db, insertDigestDeliverySQL, d.Repository, d.List, d.SIG, d.From.Format(dateFormat),
+		d.To.Format(dateFormat), d.Title, d.Markdown, d.Recipients, d.MessageID, d.SentAt, deliveryStatus(d))
+	return errors.Wrap(err, "record digest delivery")
+}
+
+// DeleteDigestDelivery deletes the delivery of a digest for a period to a
+// list, e.g. a pending delivery whose send failed.
+func (s *sqlStore) DeleteDigestDelivery(repository, list string, from, to time.Time) error {
+	err := s.exec(s.db, deleteDigestDeliverySQL, repository, list, from.Format(dateFormat), to.Format(dateFormat))
+	return errors.Wrap(err, "delete digest delivery")
+}
+
+func deliveryStatus(d *DigestDelivery) string {
+	if d.Status == "" {
+		return DeliverySent
+	}
+
+	return d.Status
+}
+
+var (
+	findDigestDeliverySQL = `
+  SELECT id, repository, list, sig, period_from, period_to, title, markdown, recipients,
+    message_id, sent_at, status
+  FROM digests
+  WHERE repository = $1 AND list = $2 AND period_from = $3 AND period_to = $4`
+
+	digestDeliverySQL = `
+  SELECT id, repository, list, sig, period_from, period_to, title, markdown, recipients,
+    message_id, sent_at
+  FROM digests
+  WHERE id = $1 AND status = 'sent'`
+
+	countDigestsSQL = `
+  SELECT COUNT(*) FROM digests
+  WHERE repository = $1 AND ($2 = '' OR list = $2) AND status = 'sent'`
+
+	digestsSQL = `
+  SELECT id, repository, list, sig, period_from, period_to, title, markdown, recipients,
+    message_id, sent_at
+  FROM digests
+  WHERE repository = $1 AND ($2 = '' OR list = $2) AND status = 'sent'
+  ORDER BY period_from DESC, list
+  LIMIT $3 OFFSET $4`
+
+	insertDigestDeliverySQL = `
+  INSERT INTO digests (repository, list, sig, period_from, period_to, title, markdown,
+    recipients, message_id, sent_at, status)
+  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+  ON CONFLICT (repository, list, period_from, period_to) DO UPDATE SET
+    sig = $3, title = $6, markdown = $7, recipients = $8, message_id = $9, sent_at = $10, status = $11`
+
+	deleteDigestDeliverySQL = `
+  DELETE FROM digests
+  WHERE repository = $1 AND list = $2 AND period_from = $3 AND period_to = $4`
+)
@@ -0,0 +1,168 @@
+package kubenews
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// smtpSink is a minimal SMTP server which records the messages it receives.
+type smtpSink struct {
+	listener   net.Listener
+	recipients []string
+	messages   chan string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &smtpSink{listener: l, messages: make(chan string, 1)}
+	go s.serve()
+	return s
+}
+
+func (s *smtpSink) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	c := textproto.NewConn(conn)
+	c.PrintfLine("220 localhost sink")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+
+		switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+		case "EHLO", "HELO":
+			c.PrintfLine("250 localhost")
+		case "RCPT":
+			s.recipients = append(s.recipients, line)
+			c.PrintfLine("250 ok")
+		case "DATA":
+			c.PrintfLine("354 go ahead")
+			b, err := c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.messages <- string(b)
+			c.PrintfLine("250 ok")
+		case "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("250 ok")
+		}
+	}
+}
+
+func (s *smtpSink) config() SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return SMTPConfig{Host: host, Port: p, TLS: SMTPNoTLS}
+}
+
+func parseParts(t *testing.T, raw string) (*mail.Message, map[string]string) {
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	parts := map[string]string{}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		b, err := ioutil.ReadAll(p)
+		require.NoError(t, err)
+		parts[strings.SplitN(p.Header.Get("Content-Type"), ";", 2)[0]] = string(b)
+	}
+
+	return msg, parts
+}
+
+func TestSMTPMailer(t *testing.T) {
+	sink := newSMTPSink(t)
+	defer sink.listener.Close()
+
+	msg, err := NewReportMessage(templateDigest(), nil, nil)
+	require.NoError(t, err)
+	msg.From = "kubenews@example.com"
+	msg.To = []string{"dev@example.com", "sig-node@example.com"}
+
+	require.NoError(t, NewSMTPMailer(sink.config()).Send(msg))
+	require.Len(t, sink.recipients, 2)
+
+	header, parts := parseParts(t, <-sink.messages)
+	require.Equal(t, "org/repo digest", header.Header.Get("Subject"))
+	require.Equal(t, msg.ID, header.Header.Get("Message-Id"))
+	require.Contains(t, parts["text/plain"], "# org/repo digest")
+	require.Contains(t, parts["text/html"], "<h1 id=\"org-repo-digest\">org/repo digest</h1>")
+}
+
+func TestSMTPMailerTimeout(t *testing.T) {
+	// a server which accepts connections but never greets
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	p, _ := strconv.Atoi(port)
+	mailer := NewSMTPMailer(SMTPConfig{Host: host, Port: p, TLS: SMTPNoTLS})
+	mailer.Timeout = 20 * time.Millisecond
+
+	msg, err := NewReportMessage(templateDigest(), nil, nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+	require.Error(t, mailer.Send(msg))
+	require.True(t, time.Since(start) < 500*time.Millisecond)
+}
+
+func TestEMLMailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubenews")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	msg, err := NewReportMessage(templateDigest(), nil, nil)
+	require.NoError(t, err)
+	msg.To = []string{"dev@example.com"}
+
+	require.NoError(t, (&EMLMailer{Dir: dir}).Send(msg))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	b, err := ioutil.ReadFile(files[0])
+	require.NoError(t, err)
+	_, parts := parseParts(t, string(b))
+	require.Len(t, parts, 2)
+}
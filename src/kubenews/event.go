@@ -0,0 +1,128 @@
+package kubenews
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// IssueEvent is an event in the history of a Github issue or pull request,
+// e.g. labeled or closed.
+type IssueEvent struct {
+	ID          int        `db:"id"`
+	Repository  string     `db:"repository"`
+	IssueNumber int        `db:"issue_number"`
+	Event       string     `db:"event"`
+	Actor       string     `db:"actor"`
+	Label       string     `db:"label"`
+	Milestone   string     `db:"milestone"`
+	Assignee    string     `db:"assignee"`
+	CommitID    string     `db:"commit_id"`
+	CreatedAt   *time.Time `db:"created_at"`
+}
+
+// LastEventID retrieves the id of the newest event stored for a repository.
+// It returns 0 if there are no events.
+func (s *sqlStore) LastEventID(repository string) (int, error) {
+	var id int
+	if err := s.get(&id, lastEventIDSQL, repository); err != nil {
+		return 0, errors.Wrap(err, "unable to retrieve last event")
+	}
+
+	return id, nil
+}
+
+// ImportEvents imports issue events to a store. Events never change, so
+// existing events are skipped.
+func ImportEvents(s Store, repository string, inEvents []github.IssueEvent) (Saved, error) {
+	events := []IssueEvent{}
+	for _, in := range inEvents {
+		event, err := ConvertEvent(repository, in)
+		if err != nil {
+			log.WithError(err).Warn("skipping event")
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	return s.SaveEvents(events)
+}
+
+// SaveEvents inserts events, skipping existing ones.
+func (s *sqlStore) SaveEvents(events []IssueEvent) (Saved, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return Saved{}, errors.Wrap(err, "import event failure")
+	}
+
+	saved := Saved{}
+	log.WithField("eventCount", len(events)).Info("importing issue events")
+	for _, event := range events {
+		// existing events are skipped, so they change no row
+		n, err := s.affected(tx, insertEventSQL, event.ID, event.Repository, event.IssueNumber,
+			event.Event, event.Actor, event.Label, event.Milestone, event.Assignee,
+			event.CommitID, event.CreatedAt)
+		if err != nil {
+			tx.Rollback()
+			return Saved{}, errors.Wrap(err, "insert event")
+		}
+		saved.Inserted += int(n)
+	}
+
+	return saved, tx.Commit()
+}
+
+// ConvertEvent converts an issue event from the github api client to our format.
+func ConvertEvent(repository string, in github.IssueEvent) (IssueEvent, error) {
+	if in.ID == nil || in.Event == nil || in.Issue == nil || in.Issue.Number == nil {
+		return IssueEvent{}, errors.New("event is missing id, type or issue")
+	}
+
+	event := IssueEvent{
+		ID:          *in.ID,
+		Repository:  repository,
+		IssueNumber: *in.Issue.Number,
+		Event:       *in.Event,
+		CreatedAt:   in.CreatedAt,
+	}
+
+	if in.Actor != nil && in.Actor.Login != nil {
+		event.Actor = *in.Actor.Login
+	}
+
+	if in.Label != nil && in.Label.Name != nil {
+		event.Label = *in.Label.Name
+	}
+
+	if in.Milestone != nil && in.Milestone.Title != nil {
+		event.Milestone = *in.Milestone.Title
+	}
+
+	if in.Assignee != nil && in.Assignee.Login != nil {
+		event.Assignee = *in.Assignee.Login
+	}
+
+	if in.CommitID != nil {
+		event.CommitID = *in.CommitID
+	}
+
+	return event, nil
+}
+
+var (
+	insertEventSQL = `
+  INSERT INTO issue_events
+  (id, repository, issue_number, event, actor, label, milestone, assignee, commit_id, created_at)
+
+  VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+
+  ON conflict (id) DO NOTHING`
+
+	lastEventIDSQL = `
+  SELECT COALESCE(MAX(id), 0) FROM issue_events
+  WHERE repository = $1`
+)
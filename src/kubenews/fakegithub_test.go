@@ -0,0 +1,311 @@
+package kubenews
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGithub is a Github API serving issues, comments and events for org/repo
+// from the fixtures in testdata/github. It paginates like Github, sends rate
+// limit headers and can be scripted to fail requests.
+type fakeGithub struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	data      map[string][]map[string]interface{}
+	failures  map[string][]fakeFailure
+	requests  map[string][]string
+	moved     map[string]string
+	remaining int
+}
+
+// fakeFailure is a scripted failed response.
+type fakeFailure struct {
+	status int
+	// page only fails requests for the page, if set.
+	page int
+	// rateLimited fails as Github does when the rate limit is used up.
+	rateLimited bool
+}
+
+const (
+	fakeIssuesPath   = "/repos/org/repo/issues"
+	fakeCommentsPath = "/repos/org/repo/issues/comments"
+	fakeEventsPath   = "/repos/org/repo/issues/events"
+)
+
+func newFakeGithub(t *testing.T) *fakeGithub {
+	f := &fakeGithub{
+		data:      map[string][]map[string]interface{}{},
+		failures:  map[string][]fakeFailure{},
+		requests:  map[string][]string{},
+		moved:     map[string]string{},
+		remaining: 5000,
+	}
+
+	for path, name := range map[string]string{
+		fakeIssuesPath:   "issues.json",
+		fakeCommentsPath: "comments.json",
+		fakeEventsPath:   "events.json",
+	} {
+		b, err := ioutil.ReadFile(filepath.Join("testdata", "github", name))
+		require.NoError(t, err)
+
+		var items []map[string]interface{}
+		require.NoError(t, json.Unmarshal(b, &items))
+		f.data[path] = items
+	}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+// newTestGithub creates a fake Github and a client for it, with delays
+// shortened so tests run quickly.
+func newTestGithub(t *testing.T) (*fakeGithub, *Github) {
+	restore := []func(){
+		setDuration(&githubRateLimit, time.Millisecond),
+		setDuration(&minThrottleDelay, time.Millisecond),
+		setDuration(&throttleJitter, 0),
+		setDuration(&serverErrorDelay, time.Millisecond),
+	}
+	oldPerPage := perPageCount
+	perPageCount = 2
+
+	f := newFakeGithub(t)
+	gh, err := NewGithubClient(f.Client(), f.URL)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		f.Close()
+		perPageCount = oldPerPage
+		for _, fn := range restore {
+			fn()
+		}
+	})
+
+	return f, gh
+}
+
+func setDuration(d *time.Duration, v time.Duration) func() {
+	old := *d
+	*d = v
+	return func() { *d = old }
+}
+
+// fail scripts the next requests to path to fail.
+func (f *fakeGithub) fail(path string, failures ...fakeFailure) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures[path] = append(f.failures[path], failures...)
+}
+
+// add adds an item to a fixture, e.g. an issue updated since the last sync.
+func (f *fakeGithub) add(path string, item map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.data[path] = append([]map[string]interface{}{item}, f.data[path]...)
+}
+
+// remove removes an issue, as if it was deleted.
+func (f *fakeGithub) remove(number int) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	issues := f.data[fakeIssuesPath]
+	for i, issue := range issues {
+		if fmt.Sprint(issue["number"]) == strconv.Itoa(number) {
+			f.data[fakeIssuesPath] = append(issues[:i:i], issues[i+1:]...)
+			return issue
+		}
+	}
+
+	return nil
+}
+
+// transfer moves an issue to another repository, where it gets a new number.
+// Requests for it in org/repo are redirected, as Github does.
+func (f *fakeGithub) transfer(number int, repository string, newNumber int) {
+	issue := f.remove(number)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	moved := map[string]interface{}{}
+	for k, v := range issue {
+		moved[k] = v
+	}
+	moved["number"] = newNumber
+
+	path := "/repos/" + repository + "/issues"
+	f.data[path] = append(f.data[path], moved)
+	f.moved[fmt.Sprintf("%s/%d", fakeIssuesPath, number)] = fmt.Sprintf("%s/%d", path, newNumber)
+}
+
+// requested returns the query strings of the requests made to path.
+func (f *fakeGithub) requested(path string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.requests[path]...)
+}
+
+func (f *fakeGithub) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests[r.URL.Path] = append(f.requests[r.URL.Path], r.URL.RawQuery)
+
+	page, perPage := 1, 30
+	if v, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && v > 0 {
+		page = v
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("per_page")); err == nil && v > 0 {
+		perPage = v
+	}
+
+	failures := f.failures[r.URL.Path]
+	for i, failure := range failures {
+		if failure.page == 0 || failure.page == page {
+			f.failures[r.URL.Path] = append(failures[:i:i], failures[i+1:]...)
+			f.writeFailure(w, failure)
+			return
+		}
+	}
+
+	if m := fakeIssueRe.FindStringSubmatch(r.URL.Path); m != nil {
+		f.serveIssue(w, r, m[1], m[2])
+		return
+	}
+
+	items, ok := f.data[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if state := r.URL.Query().Get("state"); state == "open" || state == "closed" {
+		var matched []map[string]interface{}
+		for _, item := range items {
+			if item["state"] == state {
+				matched = append(matched, item)
+			}
+		}
+		items = matched
+	}
+
+	if since := r.URL.Query().Get("since"); since != "" {
+		at, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		var updated []map[string]interface{}
+		for _, item := range items {
+			if itemTime(item, "updated_at").Before(at) {
+				continue
+			}
+			updated = append(updated, item)
+		}
+		items = updated
+	}
+
+	lastPage := (len(items) + perPage - 1) / perPage
+	if lastPage == 0 {
+		lastPage = 1
+	}
+
+	start, end := (page-1)*perPage, page*perPage
+	if start > len(items) {
+		start = len(items)
+	}
+	if end > len(items) {
+		end = len(items)
+	}
+
+	if page < lastPage {
+		q := r.URL.Query()
+		link := func(page int, rel string) string {
+			q.Set("page", strconv.Itoa(page))
+			return fmt.Sprintf(`<%s%s?%s>; rel="%s"`, f.URL, r.URL.Path, q.Encode(), rel)
+		}
+		w.Header().Set("Link", link(page+1, "next")+", "+link(lastPage, "last"))
+	}
+
+	f.remaining--
+	f.writeRate(w, f.remaining, time.Now().Add(time.Hour))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items[start:end])
+}
+
+var fakeIssueRe = regexp.MustCompile(`^/repos/([^/]+/[^/]+)/issues/(\d+)$`)
+
+// serveIssue serves an issue of a repository, or redirects to it if it was
+// transferred.
+func (f *fakeGithub) serveIssue(w http.ResponseWriter, r *http.Request, repository, number string) {
+	if to, ok := f.moved[r.URL.Path]; ok {
+		http.Redirect(w, r, to, http.StatusMovedPermanently)
+		return
+	}
+
+	for _, item := range f.data["/repos/"+repository+"/issues"] {
+		if fmt.Sprint(item["number"]) != number {
+			continue
+		}
+
+		issue := map[string]interface{}{"html_url": fmt.Sprintf("https://github.com/%s/issues/%s", repository, number)}
+		for k, v := range item {
+			issue[k] = v
+		}
+
+		f.remaining--
+		f.writeRate(w, f.remaining, time.Now().Add(time.Hour))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(issue)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+func (f *fakeGithub) writeFailure(w http.ResponseWriter, failure fakeFailure) {
+	message := http.StatusText(failure.status)
+
+	if failure.rateLimited {
+		// the limit resets immediately, so the client doesn't refuse to retry
+		f.writeRate(w, 0, time.Now().Add(-time.Second))
+		message = "API rate limit exceeded for 127.0.0.1."
+	} else {
+		f.writeRate(w, f.remaining, time.Now().Add(time.Hour))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(failure.status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+func (f *fakeGithub) writeRate(w http.ResponseWriter, remaining int, reset time.Time) {
+	w.Header().Set("X-RateLimit-Limit", "5000")
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+}
+
+func itemTime(item map[string]interface{}, key string) time.Time {
+	s, _ := item[key].(string)
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
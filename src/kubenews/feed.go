@@ -0,0 +1,351 @@
+package kubenews
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+)
+
+// Feed is an Atom feed.
+type Feed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []FeedLink  `xml:"link"`
+	Author  *FeedPerson `xml:"author,omitempty"`
+	Entries []FeedEntry `xml:"entry"`
+}
+
+// FeedLink is a link in an Atom feed.
+type FeedLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+// FeedPerson is the author of a feed or entry.
+type FeedPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+// FeedText is text content in an Atom feed.
+type FeedText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+// FeedCategory is a category of an entry.
+type FeedCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// FeedEntry is an entry in an Atom feed.
+type FeedEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Links      []FeedLink     `xml:"link"`
+	Author     *FeedPerson    `xml:"author,omitempty"`
+	Categories []FeedCategory `xml:"category"`
+	Content    *FeedText      `xml:"content,omitempty"`
+}
+
+// Write writes the feed to w as XML.
+func (f *Feed) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return errors.Wrap(err, "encode feed")
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func feedTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// NewIssueFeed creates a feed of issues, newest first. Entry IDs are the
+// issues' Github URLs and entries are updated when the issues were.
+func NewIssueFeed(id, title, self string, issues []Issue) *Feed {
+	sorted := append([]Issue{}, issues...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return createdAt(sorted[i]).After(createdAt(sorted[j]))
+	})
+
+	f := &Feed{
+		ID:    id,
+		Title: title,
+		Links: []FeedLink{{Rel: "self", Href: self, Type: "application/atom+xml"}},
+	}
+
+	var updated time.Time
+	for _, issue := range sorted {
+		u := createdAt(issue)
+		if issue.UpdatedAt != nil {
+			u = *issue.UpdatedAt
+		}
+		if u.After(updated) {
+			updated = u
+		}
+
+		entry := FeedEntry{
+			ID:        issue.HTMLURL(),
+			Title:     fmt.Sprintf("#%d %s", issue.Number, issue.Title),
+			Updated:   feedTime(u),
+			Published: feedTime(createdAt(issue)),
+			Links:     []FeedLink{{Rel: "alternate", Href: issue.HTMLURL(), Type: "text/html"}},
+			Author:    &FeedPerson{Name: issue.User, URI: "https://github.com/" + issue.User},
+			Content:   &FeedText{Type: "html", Body: string(MarkdownToHTML([]byte(issue.Body)))},
+		}
+		for _, label := range issue.Labels {
+			entry.Categories = append(entry.Categories, FeedCategory{Term: label.Name})
+		}
+
+		f.Entries = append(f.Entries, entry)
+	}
+
+	f.Updated = feedTime(updated)
+	return f
+}
+
+func createdAt(issue Issue) time.Time {
+	if issue.CreatedAt == nil {
+		return time.Time{}
+	}
+
+	return *issue.CreatedAt
+}
+
+// NewDigestFeed creates a feed of the digests sent to a list, newest first.
+func NewDigestFeed(id, title, self string, digests []DigestDelivery) *Feed {
+	f := &Feed{
+		ID:    id,
+		Title: title,
+		Links: []FeedLink{{Rel: "self", Href: self, Type: "application/atom+xml"}},
+	}
+
+	var updated time.Time
+	for _, d := range digests {
+		if d.SentAt.After(updated) {
+			updated = d.SentAt
+		}
+
+		f.Entries = append(f.Entries, FeedEntry{
+			ID:        fmt.Sprintf("%s/%s", id, d.From.Format(dateFormat)),
+			Title:     fmt.Sprintf("%s, %s to %s", d.Title, d.From.Format(dateFormat), d.To.Format(dateFormat)),
+			Updated:   feedTime(d.SentAt),
+			Published: feedTime(d.SentAt),
+			Author:    &FeedPerson{Name: "kubenews"},
+			Content:   &FeedText{Type: "html", Body: string(MarkdownToHTML([]byte(d.Markdown)))},
+		})
+	}
+
+	f.Updated = feedTime(updated)
+	return f
+}
+
+// Feeds builds Atom feeds from the datastore. Feeds are addressed by path:
+//
+//	/{owner}/{repo}.atom                new issues in a repository
+//	/{owner}/{repo}/sig/{sig}.atom      new issues for a SIG
+//	/{owner}/{repo}/label/{label}.atom  new issues with a label
+//	/{owner}/{repo}/query/{name}.atom   new issues matching a saved query
+//	/{owner}/{repo}/digests/{list}.atom digests sent to a mailing list
+type Feeds struct {
+	Store Store
+	SIGs  *SIGMap
+	// BaseURL is the URL feeds are published under. It is used for feed IDs
+	// and self links.
+	BaseURL string
+	// Days is how far back issue feeds go.
+	Days int
+	// Limit is the maximum number of entries in a feed.
+	Limit int
+	// Queries are the saved queries, by name.
+	Queries map[string]*Query
+}
+
+// Feed builds the feed at a path.
+func (f *Feeds) Feed(p string) (*Feed, error) {
+	p = strings.TrimPrefix(p, "/")
+	if !strings.HasSuffix(p, ".atom") {
+		return nil, errFeedNotFound
+	}
+	parts := strings.SplitN(strings.TrimSuffix(p, ".atom"), "/", 4)
+
+	id := strings.TrimSuffix(f.BaseURL, "/") + "/" + p
+	switch {
+	case len(parts) == 2:
+		repo := parts[0] + "/" + parts[1]
+		issues, err := f.recentIssues(repo)
+		if err != nil {
+			return nil, err
+		}
+		return NewIssueFeed(id, fmt.Sprintf("New issues in %s", repo), id, f.limit(issues)), nil
+
+	case len(parts) == 4 && parts[2] == "sig":
+		repo := parts[0] + "/" + parts[1]
+		issues, err := f.recentIssues(repo)
+		if err != nil {
+			return nil, err
+		}
+		issues = f.SIGs.orDefault().FilterSIG(issues, parts[3])
+		return NewIssueFeed(id, fmt.Sprintf("New sig/%s issues in %s", parts[3], repo), id, f.limit(issues)), nil
+
+	case len(parts) == 4 && parts[2] == "label":
+		repo := parts[0] + "/" + parts[1]
+		issues, err := f.recentIssues(repo)
+		if err != nil {
+			return nil, err
+		}
+		issues = FilterIssues(issues, hasLabelFilter(parts[3]))
+		return NewIssueFeed(id, fmt.Sprintf("New %s issues in %s", parts[3], repo), id, f.limit(issues)), nil
+
+	case len(parts) == 4 && parts[2] == "query":
+		query, ok := f.Queries[parts[3]]
+		if !ok {
+			return nil, errFeedNotFound
+		}
+		repo := parts[0] + "/" + parts[1]
+		issues, err := f.recentIssues(repo)
+		if err != nil {
+			return nil, err
+		}
+		issues = FilterIssues(issues, query)
+		return NewIssueFeed(id, fmt.Sprintf("New issues matching %s in %s", parts[3], repo), id, f.limit(issues)), nil
+
+	case len(parts) == 4 && parts[2] == "digests":
+		repo := parts[0] + "/" + parts[1]
+		digests, _, err := f.Store.LoadDigestDeliveries(repo, parts[3], f.Limit, 0)
+		if err != nil {
+			return nil, err
+		}
+		return NewDigestFeed(id, fmt.Sprintf("%s digests for %s", repo, parts[3]), id, digests), nil
+	}
+
+	return nil, errFeedNotFound
+}
+
+var errFeedNotFound = errors.New("feed not found")
+
+func (f *Feeds) recentIssues(repository string) ([]Issue, error) {
+	return f.Store.RecentIssues(repository, time.Now().AddDate(0, 0, -f.Days))
+}
+
+// RecentIssues retrieves the issues, not pull requests, created in a
+// repository since a time, newest first.
+func (s *sqlStore) RecentIssues(repository string, since time.Time) ([]Issue, error) {
+	issues := []Issue{}
+	if err := s.selectx(&issues, recentIssuesSQL, repository, since); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve issues")
+	}
+
+	return issues, nil
+}
+
+// limit drops the oldest issues from a list ordered newest first.
+func (f *Feeds) limit(issues []Issue) []Issue {
+	if f.Limit > 0 && len(issues) > f.Limit {
+		return issues[:f.Limit]
+	}
+
+	return issues
+}
+
+// ServeHTTP serves feeds by path.
+func (f *Feeds) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	feed, err := f.Feed(r.URL.Path)
+	if err == errFeedNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.WithError(err).WithField("path", r.URL.Path).Error("unable to build feed")
+		http.Error(w, "unable to build feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	feed.Write(w)
+}
+
+// FeedPaths returns the paths of the feeds for a repository, its SIGs, labels
+// and saved queries, and the digests sent to lists.
+func FeedPaths(repository string, sigs, labels, queries, lists []string) []string {
+	paths := []string{repository + ".atom"}
+	for _, sig := range sigs {
+		paths = append(paths, path.Join(repository, "sig", sig+".atom"))
+	}
+	for _, label := range labels {
+		paths = append(paths, path.Join(repository, "label", label+".atom"))
+	}
+	for _, query := range queries {
+		paths = append(paths, path.Join(repository, "query", query+".atom"))
+	}
+	for _, list := range lists {
+		paths = append(paths, path.Join(repository, "digests", list+".atom"))
+	}
+
+	return paths
+}
+
+// WriteFiles writes feeds to static files under dir, at the same paths they
+// are served from.
+func (f *Feeds) WriteFiles(dir string, paths []string) error {
+	for _, p := range paths {
+		feed, err := f.Feed(p)
+		if err != nil {
+			return errors.Wrapf(err, "feed %s", p)
+		}
+
+		file := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return errors.Wrapf(err, "unable to create %s", filepath.Dir(file))
+		}
+
+		out, err := os.Create(file)
+		if err != nil {
+			return errors.Wrapf(err, "unable to create %s", file)
+		}
+
+		err = feed.Write(out)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to write %s", file)
+		}
+
+		log.WithFields(log.Fields{"file": file, "entries": len(feed.Entries)}).Info("wrote feed")
+	}
+
+	return nil
+}
+
+var (
+	recentIssuesSQL = `
+  SELECT id, number, state, title, body, created_by, labels, assignee, closed_at,
+    created_at, updated_at, milestone, repository, pull_request
+  FROM issues
+  WHERE repository = $1 AND created_at >= $2 AND NOT pull_request
+  ORDER BY created_at DESC`
+)
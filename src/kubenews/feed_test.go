@@ -0,0 +1,72 @@
+package kubenews
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIssueFeed(t *testing.T) {
+	created := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+	updated := created.Add(48 * time.Hour)
+	later := created.Add(time.Hour)
+
+	older := labeledIssue(1, "sig/network")
+	older.Repository = "org/repo"
+	older.Title = "Service <LoadBalancer> stuck"
+	older.Body = "**bold** <script>x</script>"
+	older.User = "alice"
+	older.CreatedAt = &created
+	older.UpdatedAt = &updated
+
+	newer := labeledIssue(2, "sig/node")
+	newer.Repository = "org/repo"
+	newer.CreatedAt = &later
+
+	f := NewIssueFeed("https://feeds.example.com/org/repo.atom", "New issues", "https://feeds.example.com/org/repo.atom",
+		[]Issue{older, newer})
+
+	require.Len(t, f.Entries, 2)
+	require.Equal(t, "https://github.com/org/repo/issues/2", f.Entries[0].ID)
+	require.Equal(t, "2017-03-03T12:00:00Z", f.Updated)
+	require.Equal(t, "2017-03-03T12:00:00Z", f.Entries[1].Updated)
+	require.Equal(t, "2017-03-01T12:00:00Z", f.Entries[1].Published)
+	require.Equal(t, "2017-03-01T13:00:00Z", f.Entries[0].Updated)
+	require.Equal(t, []FeedCategory{{Term: "sig/network"}}, f.Entries[1].Categories)
+
+	var buf bytes.Buffer
+	require.NoError(t, f.Write(&buf))
+	require.Contains(t, buf.String(), `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	require.Contains(t, buf.String(), "Service &lt;LoadBalancer&gt; stuck")
+	require.NotContains(t, buf.String(), "<script>")
+
+	parsed := Feed{}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed))
+	require.Equal(t, "<p><strong>bold</strong> x</p>\n", parsed.Entries[1].Content.Body)
+}
+
+func TestFeedPaths(t *testing.T) {
+	require.Equal(t, []string{
+		"org/repo.atom",
+		"org/repo/sig/network.atom",
+		"org/repo/label/kind/bug.atom",
+		"org/repo/query/flaky.atom",
+		"org/repo/digests/dev.atom",
+	}, FeedPaths("org/repo", []string{"network"}, []string{"kind/bug"}, []string{"flaky"}, []string{"dev"}))
+}
+
+func TestNewDigestFeed(t *testing.T) {
+	from := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	sent := from.AddDate(0, 0, 7)
+	f := NewDigestFeed("https://feeds.example.com/org/repo/digests/dev.atom", "Digests", "self", []DigestDelivery{{
+		Title: "org/repo digest", From: from, To: sent, SentAt: sent, Markdown: "# org/repo digest",
+	}})
+
+	require.Len(t, f.Entries, 1)
+	require.Equal(t, "https://feeds.example.com/org/repo/digests/dev.atom/2017-03-01", f.Entries[0].ID)
+	require.Equal(t, "2017-03-08T00:00:00Z", f.Updated)
+	require.Contains(t, f.Entries[0].Content.Body, "<h1")
+}
@@ -0,0 +1,112 @@
+package kubenews
+
+import (
+	"fmt"
+	"io"
+)
+
+// FixReport lists open issues with the pull requests which fix them.
+type FixReport struct {
+	Repository string
+	// Pending is set if the report only has issues whose fixes are all
+	// unmerged, with at least one still open.
+	Pending bool
+	Issues  []FixedIssue
+}
+
+// FixedIssue is an open issue and the pull requests which fix it.
+type FixedIssue struct {
+	Issue Issue
+	Fixes []Fix
+}
+
+// Pending returns true if no fix of the issue was merged and at least one is
+// still open.
+func (i FixedIssue) Pending() bool {
+	open := false
+	for _, fix := range i.Fixes {
+		if fix.Merged() {
+			return false
+		}
+		open = open || fix.Open()
+	}
+
+	return open
+}
+
+// FindFixes finds the open issues which have fixes, in the order of open. If
+// pending is set, only issues whose fixes are pending are included.
+func FindFixes(repository string, open []Issue, fixes []Fix, pending bool) *FixReport {
+	byIssue := FixesByIssue(fixes)
+
+	r := &FixReport{Repository: repository, Pending: pending, Issues: []FixedIssue{}}
+	for _, issue := range open {
+		if issue.PullRequest || len(byIssue[issue.Number]) == 0 {
+			continue
+		}
+
+		fixed := FixedIssue{Issue: issue, Fixes: byIssue[issue.Number]}
+		if pending && !fixed.Pending() {
+			continue
+		}
+
+		r.Issues = append(r.Issues, fixed)
+	}
+
+	return r
+}
+
+// Title returns the title of the report.
+func (r *FixReport) Title() string {
+	if r.Pending {
+		return fmt.Sprintf("%s: open issues with fixes awaiting merge", r.Repository)
+	}
+
+	return fmt.Sprintf("%s: open issues with fixes", r.Repository)
+}
+
+// WriteMarkdown writes the report to w as Markdown.
+func (r *FixReport) WriteMarkdown(w io.Writer) error {
+	p := &printer{w: w}
+	p.printf("# %s\n\n", escapeMarkdown(r.Title()))
+
+	if len(r.Issues) == 0 {
+		p.printf("None\n")
+		return p.err
+	}
+
+	for _, fixed := range r.Issues {
+		p.printf("* %s, fixed by", issueLink(fixed.Issue))
+		for i, fix := range fixed.Fixes {
+			if i > 0 {
+				p.printf(",")
+			}
+
+			state := "open"
+			switch {
+			case fix.Merged() && fix.PullRequest.Milestone != "":
+				state = "merged in " + escapeMarkdown(fix.PullRequest.Milestone)
+			case fix.Merged():
+				state = "merged"
+			case !fix.Open():
+				state = "closed"
+			}
+
+			p.printf(" [%s#%d](%s) (%s)", fixRepository(fixed.Issue, fix), fix.PullRequest.Number,
+				fix.PullRequest.HTMLURL(), state)
+		}
+		p.printf("\n")
+	}
+
+	return p.err
+}
+
+// fixRepository returns the repository of a fix to show before its number,
+// which is empty if it is the repository of the issue.
+func fixRepository(issue Issue, fix Fix) string {
+	if fix.PullRequest.Repository == issue.Repository {
+		return ""
+	}
+
+	return escapeMarkdown(fix.PullRequest.Repository)
+}
@@ -0,0 +1,61 @@
+package kubenews
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindFixes(t *testing.T) {
+	merged := time.Date(2017, 3, 2, 12, 0, 0, 0, time.UTC)
+	issue := func(n int) Issue {
+		return Issue{Number: n, State: "open", Title: "broken", Repository: "org/repo"}
+	}
+	pr := func(repo string, n int, state string) Issue {
+		return Issue{Number: n, State: state, Title: "fix", Repository: repo, PullRequest: true, Milestone: "v1.7"}
+	}
+
+	open := []Issue{issue(1), issue(2), issue(3), issue(4)}
+	fixes := []Fix{
+		{Repository: "org/repo", Number: 1, PullRequest: pr("org/repo", 10, "closed"), MergedAt: &merged},
+		{Repository: "org/repo", Number: 2, PullRequest: pr("org/repo", 11, "open")},
+		{Repository: "org/repo", Number: 2, PullRequest: pr("org/other", 12, "closed")},
+		{Repository: "org/repo", Number: 3, PullRequest: pr("org/repo", 13, "closed")},
+	}
+
+	r := FindFixes("org/repo", open, fixes, false)
+	require.Len(t, r.Issues, 3)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteMarkdown(&buf))
+	require.Contains(t, buf.String(), "# org/repo: open issues with fixes\n")
+	require.Contains(t, buf.String(), "broken, fixed by [#10](https://github.com/org/repo/pull/10) (merged in v1.7)\n")
+	require.Contains(t, buf.String(), "fixed by [#11](https://github.com/org/repo/pull/11) (open),"+
+		" [org/other#12](https://github.com/org/other/pull/12) (closed)\n")
+
+	r = FindFixes("org/repo", open, fixes, true)
+	require.Len(t, r.Issues, 1)
+	require.Equal(t, 2, r.Issues[0].Issue.Number)
+	require.Equal(t, "org/repo: open issues with fixes awaiting merge", r.Title())
+}
+
+func TestDigestFixedBy(t *testing.T) {
+	from := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	during := from.AddDate(0, 0, 2)
+
+	closed := Issue{Number: 1, State: "closed", Title: "broken", Repository: "org/repo", CreatedAt: &from, ClosedAt: &during}
+	d := NewDigest(DigestOptions{Repository: "org/repo", From: from, To: from.AddDate(0, 0, 7)}, []Issue{closed}, nil)
+	d.Fixes = FixesByIssue([]Fix{
+		{Repository: "org/repo", Number: 1, MergedAt: &during,
+			PullRequest: Issue{Number: 2, State: "closed", Repository: "org/repo", PullRequest: true, Milestone: "v1.7"}},
+		{Repository: "org/repo", Number: 1,
+			PullRequest: Issue{Number: 3, State: "open", Repository: "org/repo", PullRequest: true}},
+	})
+	require.Len(t, d.FixedBy(closed), 1)
+
+	var buf bytes.Buffer
+	require.NoError(t, d.WriteMarkdown(&buf))
+	require.Contains(t, buf.String(), "broken, fixed by [#2](https://github.com/org/repo/pull/2) in v1.7\n")
+}
@@ -0,0 +1,250 @@
+package kubenews
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultFlakeLabels are the labels of issues which report flaky or failing tests.
+var DefaultFlakeLabels = []string{"kind/flake", "kind/failing-test"}
+
+var (
+	// e2eSuiteRe matches the title format used by the e2e flake filer, e.g.
+	// "[k8s.io] Pods should be updated {Kubernetes e2e suite}".
+	e2eSuiteRe = regexp.MustCompile(`^(.+?)\s*\{[^}]*suite\}`)
+
+	// e2eTestRe matches ginkgo test names which start with a tag, e.g.
+	// "[sig-network] Services should serve a basic endpoint".
+	e2eTestRe = regexp.MustCompile(`(\[(?:k8s\.io|sig-[a-z-]+)\][^\n{}"]*?)(?:\s*[:{"]|\s*$)`)
+
+	// goTestRe matches go unit test names, e.g. TestKubeletSync.
+	goTestRe = regexp.MustCompile(`\bTest[A-Z0-9][A-Za-z0-9_]*\b`)
+
+	// jobRe matches CI job names, e.g. ci-kubernetes-e2e-gce or
+	// pull-kubernetes-unit.
+	jobRe = regexp.MustCompile(`\b(?:(?:ci|pull|post|periodic)-kubernetes-[a-z0-9.-]*[a-z0-9]|kubernetes-(?:e2e|soak|kubemark|federation|test|build|verify)(?:-[a-z0-9.]+)*)\b`)
+
+	spaceRe = regexp.MustCompile(`\s+`)
+)
+
+// ExtractTests extracts the names of failing tests from a flake report. The
+// title is searched first, then the body.
+func ExtractTests(issue Issue) []string {
+	if tests := extractTests(issue.Title); len(tests) > 0 {
+		return tests
+	}
+
+	return extractTests(issue.Body)
+}
+
+func extractTests(text string) []string {
+	seen := map[string]bool{}
+	tests := []string{}
+	add := func(name string) {
+		name = strings.TrimSpace(spaceRe.ReplaceAllString(name, " "))
+		if name == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		tests = append(tests, name)
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if m := e2eSuiteRe.FindStringSubmatch(line); m != nil {
+			add(strings.TrimPrefix(m[1], "Failed: "))
+			continue
+		}
+
+		for _, m := range e2eTestRe.FindAllStringSubmatch(line, -1) {
+			add(m[1])
+		}
+
+		for _, name := range goTestRe.FindAllString(line, -1) {
+			add(name)
+		}
+	}
+
+	return tests
+}
+
+// ExtractJobs extracts CI job names from a flake report's title and body.
+func ExtractJobs(issue Issue) []string {
+	seen := map[string]bool{}
+	jobs := []string{}
+	for _, job := range jobRe.FindAllString(issue.Title+"\n"+issue.Body, -1) {
+		if !seen[job] {
+			seen[job] = true
+			jobs = append(jobs, job)
+		}
+	}
+
+	sort.Strings(jobs)
+	return jobs
+}
+
+// FlakyTest is a test which has been reported as flaky or failing, grouped
+// across all issues reporting it.
+type FlakyTest struct {
+	Name   string
+	Jobs   []string
+	Issues []Issue
+	// Occurrences is the number of failures reported in the period: new
+	// issues plus comments on existing issues.
+	Occurrences int
+	Open        int
+	Closed      int
+}
+
+// TopFlakes groups flake reports in a history by test and returns the tests
+// with the most occurrences between from and to. A limit of 0 returns all
+// tests with occurrences.
+func TopFlakes(h *History, from, to time.Time, limit int) []FlakyTest {
+	byName := map[string]*FlakyTest{}
+	jobs := map[string]map[string]bool{}
+
+	for _, issue := range h.Issues {
+		occurrences := 0
+		if inPeriod(issue.CreatedAt, from, to) {
+			occurrences++
+		}
+		for _, c := range h.Comments[issue.Number] {
+			if inPeriod(c.CreatedAt, from, to) {
+				occurrences++
+			}
+		}
+
+		for _, name := range ExtractTests(issue) {
+			ft, ok := byName[name]
+			if !ok {
+				ft = &FlakyTest{Name: name}
+				byName[name] = ft
+				jobs[name] = map[string]bool{}
+			}
+
+			ft.Issues = append(ft.Issues, issue)
+			ft.Occurrences += occurrences
+			if issue.State == "open" {
+				ft.Open++
+			} else {
+				ft.Closed++
+			}
+
+			for _, job := range ExtractJobs(issue) {
+				jobs[name][job] = true
+			}
+		}
+	}
+
+	flakes := []FlakyTest{}
+	for name, ft := range byName {
+		if ft.Occurrences == 0 {
+			continue
+		}
+
+		for job := range jobs[name] {
+			ft.Jobs = append(ft.Jobs, job)
+		}
+		sort.Strings(ft.Jobs)
+
+		flakes = append(flakes, *ft)
+	}
+
+	sort.Slice(flakes, func(i, j int) bool {
+		if flakes[i].Occurrences != flakes[j].Occurrences {
+			return flakes[i].Occurrences > flakes[j].Occurrences
+		}
+		return flakes[i].Name < flakes[j].Name
+	})
+
+	if limit > 0 && len(flakes) > limit {
+		flakes = flakes[:limit]
+	}
+
+	return flakes
+}
+
+// LoadLabeledHistory loads the issues for a repository which carry any of
+// labels, with their comments. Events are not loaded.
+func (s *sqlStore) LoadLabeledHistory(repository string, labels []string) (*History, error) {
+	names, err := json.Marshal(labels)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode labels")
+	}
+
+	issues := []Issue{}
+	if err := s.selectx(&issues, labeledIssuesSQL, repository, string(names)); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve issues")
+	}
+
+	comments := []Comment{}
+	if err := s.selectx(&comments, commentsForLabeledIssuesSQL, repository, string(names)); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve comments")
+	}
+
+	return NewHistory(issues, comments, nil), nil
+}
+
+// flakes writes flaky tests as a Markdown table.
+func (p *printer) flakes(flakes []FlakyTest) {
+	if len(flakes) == 0 {
+		p.printf("None\n\n")
+		return
+	}
+
+	p.printf("| Test | Failures | Open | Closed | Jobs | Issues |\n")
+	p.printf("| --- | ---: | ---: | ---: | --- | --- |\n")
+	for _, ft := range flakes {
+		p.printf("| %s | %d | %d | %d | %s | %s |\n", escapeTableCell(ft.Name), ft.Occurrences, ft.Open,
+			ft.Closed, strings.Join(ft.Jobs, ", "), issueRefs(ft.Issues))
+	}
+	p.printf("\n")
+}
+
+// FlakeReport lists the most reported flaky tests over a period.
+type FlakeReport struct {
+	Repository string
+	From       time.Time
+	To         time.Time
+	Flakes     []FlakyTest
+}
+
+// Title returns the title of the report.
+func (r *FlakeReport) Title() string {
+	return fmt.Sprintf("%s: top flakes", r.Repository)
+}
+
+// WriteMarkdown writes the report to w as Markdown.
+func (r *FlakeReport) WriteMarkdown(w io.Writer) error {
+	p := &printer{w: w}
+	p.printf("# %s\n\n", escapeMarkdown(r.Title()))
+	p.printf("%s to %s\n\n", r.From.Format(dateFormat), r.To.Format(dateFormat))
+	p.flakes(r.Flakes)
+	return p.err
+}
+
+var (
+	labeledIssuesSQL = `
+  SELECT id, number, state, title, body, created_by, labels, assignee, closed_at,
+    created_at, updated_at, milestone, repository, pull_request
+  FROM issues
+  WHERE repository = $1
+    AND EXISTS (SELECT 1 FROM jsonb_array_elements(labels) l
+      WHERE l->>'Name' IN (SELECT jsonb_array_elements_text($2::jsonb)))
+  ORDER BY number`
+
+	commentsForLabeledIssuesSQL = `
+  SELECT c.id, c.repository, c.issue_number, c.created_by, c.body, c.created_at, c.updated_at
+  FROM comments c
+  JOIN issues i ON i.repository = c.repository AND i.number = c.issue_number
+  WHERE i.repository = $1
+    AND EXISTS (SELECT 1 FROM jsonb_array_elements(i.labels) l
+      WHERE l->>'Name' IN (SELECT jsonb_array_elements_text($2::jsonb)))
+  ORDER BY c.created_at, c.id`
+)
@@ -0,0 +1,83 @@
+package kubenews
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractTests(t *testing.T) {
+	cases := []struct {
+		title string
+		body  string
+		tests []string
+	}{
+		{
+			title: "[k8s.io] Pods should be submitted and removed [Conformance] {Kubernetes e2e suite}",
+			tests: []string{"[k8s.io] Pods should be submitted and removed [Conformance]"},
+		},
+		{
+			title: "[sig-network] Services should serve a basic endpoint: flaky on gce",
+			tests: []string{"[sig-network] Services should serve a basic endpoint"},
+		},
+		{
+			title: "TestKubeletSync flakes in pkg/kubelet",
+			tests: []string{"TestKubeletSync"},
+		},
+		{
+			title: "ci-kubernetes-e2e-gci-gke: broken test run",
+			body:  "Failed: [k8s.io] Kubectl client [k8s.io] Simple pod should support exec {Kubernetes e2e suite}\n",
+			tests: []string{"[k8s.io] Kubectl client [k8s.io] Simple pod should support exec"},
+		},
+		{
+			title: "Something is broken",
+			tests: []string{},
+		},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.tests, ExtractTests(Issue{Title: c.title, Body: c.body}), c.title)
+	}
+}
+
+func TestExtractJobs(t *testing.T) {
+	issue := Issue{
+		Title: "ci-kubernetes-e2e-gci-gke: broken test run",
+		Body:  "https://k8s-gubernator.appspot.com/build/kubernetes-jenkins/logs/pull-kubernetes-unit/123\nalso kubernetes-e2e-gce-serial.",
+	}
+
+	require.Equal(t, []string{"ci-kubernetes-e2e-gci-gke", "kubernetes-e2e-gce-serial", "pull-kubernetes-unit"},
+		ExtractJobs(issue))
+}
+
+func TestTopFlakes(t *testing.T) {
+	from := time.Date(2017, 3, 6, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	before := from.AddDate(0, 0, -30)
+	during := from.AddDate(0, 0, 1)
+
+	old := Issue{Number: 1, State: "closed", Title: "TestKubeletSync flakes", CreatedAt: &before}
+	reopened := Issue{Number: 2, State: "open", Title: "TestKubeletSync is flaky again", Body: "job pull-kubernetes-unit", CreatedAt: &during}
+	commented := Issue{Number: 3, State: "open", Title: "TestSchedulerBind flakes", CreatedAt: &before}
+	quiet := Issue{Number: 4, State: "open", Title: "TestQuiet flakes", CreatedAt: &before}
+
+	comments := []Comment{
+		{IssueNumber: 1, CreatedAt: &during},
+		{IssueNumber: 3, CreatedAt: &during},
+		{IssueNumber: 4, CreatedAt: &before},
+	}
+
+	h := NewHistory([]Issue{old, reopened, commented, quiet}, comments, nil)
+	flakes := TopFlakes(h, from, to, 0)
+
+	require.Len(t, flakes, 2)
+	require.Equal(t, "TestKubeletSync", flakes[0].Name)
+	require.Equal(t, 2, flakes[0].Occurrences)
+	require.Equal(t, 1, flakes[0].Open)
+	require.Equal(t, 1, flakes[0].Closed)
+	require.Equal(t, []string{"pull-kubernetes-unit"}, flakes[0].Jobs)
+	require.Equal(t, "TestSchedulerBind", flakes[1].Name)
+
+	require.Len(t, TopFlakes(h, from, to, 1), 1)
+}
@@ -2,6 +2,9 @@ package kubenews
 
 import (
 	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,11 +28,68 @@ var (
 
 	// minThrottleDelay is the amount of time to wait when the github api throttles.
 	minThrottleDelay = time.Second * 30
+
+	// throttleJitter is the most extra time to wait when the github api
+	// throttles, so workers don't retry at once.
+	throttleJitter = time.Second * 30
+
+	// serverErrorRetries is the number of times to retry a request which
+	// failed with a server error.
+	serverErrorRetries = 3
+
+	// serverErrorDelay is the time to wait before retrying a server error. It
+	// grows with each attempt.
+	serverErrorDelay = time.Second * 5
 )
 
 // Github is a Github client.
 type Github struct {
 	client *github.Client
+	stats  *APIStats
+}
+
+// APIStats counts the requests made by a Github client.
+type APIStats struct {
+	mu    sync.Mutex
+	calls int
+	pages int
+}
+
+func (s *APIStats) record(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if err == nil {
+		s.pages++
+	}
+}
+
+// Calls returns the number of requests made, including retries.
+func (s *APIStats) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls
+}
+
+// Pages returns the number of pages fetched.
+func (s *APIStats) Pages() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pages
+}
+
+// WithStats returns a copy of the client which counts its requests in stats.
+func (gh *Github) WithStats(stats *APIStats) *Github {
+	c := *gh
+	c.stats = stats
+	return &c
 }
 
 // NewGithub creates an instance of Github.
@@ -49,6 +109,24 @@ func NewGithub(token string) *Github {
 	return gh
 }
 
+// NewGithubClient creates an instance of Github which talks to the api at
+// baseURL with httpClient, e.g. a Github Enterprise server or a fake in tests.
+func NewGithubClient(httpClient *http.Client, baseURL string) (*Github, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid github url %s", baseURL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	client := github.NewClient(httpClient)
+	client.UserAgent = "kubenews"
+	client.BaseURL = u
+
+	return &Github{client: client}, nil
+}
+
 func splitRepo(repoName string) (string, string, error) {
 	repoParts := strings.Split(repoName, "/")
 	if len(repoParts) != 2 {
@@ -70,8 +148,10 @@ func (gh *Github) ListRepoIssues(repoName string, since *time.Time) ([]github.Is
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	allIssues, resp, err := gh.GetRepoIssuePage(org, repo, 0, since)
+	// the first page tells us how many pages there are
+	allIssues, resp, err := gh.GetRepoIssuePage(org, repo, 1, since)
 	if err != nil {
 		return nil, err
 	}
@@ -79,14 +159,19 @@ func (gh *Github) ListRepoIssues(repoName string, since *time.Time) ([]github.Is
 	pageChan := make(chan int, 100)
 
 	outChan := make(chan github.Issue, 100)
+	collected := make(chan struct{})
 	go func() {
 		for issue := range outChan {
 			allIssues = append(allIssues, issue)
 		}
+		close(collected)
 	}()
 
 	wg := sync.WaitGroup{}
 
+	var errOnce sync.Once
+	var workerErr error
+
 	// start workers
 	for i := 0; i < workerCount; i++ {
 		wg.Add(1)
@@ -101,6 +186,7 @@ func (gh *Github) ListRepoIssues(repoName string, since *time.Time) ([]github.Is
 			defer wg.Done()
 			if err := w.start(ctx, since, pageChan, outChan); err != nil {
 				log.WithError(err).Error("worker failed")
+				errOnce.Do(func() { workerErr = err })
 				cancel()
 			}
 		}(ctx, i)
@@ -109,16 +195,33 @@ func (gh *Github) ListRepoIssues(repoName string, since *time.Time) ([]github.Is
 	log.WithField("totalPages", resp.LastPage).Info("page info")
 
 	// throttle the requests, so we don't anger the github api
-	throttle := time.Tick(githubRateLimit)
+	throttle := time.NewTicker(githubRateLimit)
+	defer throttle.Stop()
 
-	for i := 1; i <= resp.LastPage; i++ {
-		<-throttle
-		pageChan <- i
+pages:
+	for i := 2; i <= resp.LastPage; i++ {
+		select {
+		case <-ctx.Done():
+			break pages
+		case <-throttle.C:
+		}
+
+		select {
+		case <-ctx.Done():
+			break pages
+		case pageChan <- i:
+		}
 	}
 	close(pageChan)
 
 	log.Info("waiting for workers to finish")
 	wg.Wait()
+	close(outChan)
+	<-collected
+
+	if workerErr != nil {
+		return nil, workerErr
+	}
 
 	return allIssues, nil
 }
@@ -139,16 +242,14 @@ func (gh *Github) GetRepoIssuePage(org, repo string, page int, since *time.Time)
 		issueOptions.Since = *since
 	}
 
-	issues, resp, err := gh.client.Issues.ListByRepo(org, repo, issueOptions)
+	var issues []*github.Issue
+	resp, err := gh.retry(logger, func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		issues, resp, err = gh.client.Issues.ListByRepo(org, repo, issueOptions)
+		return resp, err
+	})
 	if err != nil {
-		if resp.StatusCode == 403 {
-			buffer := time.Duration(rand.Int31n(30)) * time.Second
-			delayTime := minThrottleDelay + buffer
-			logger.WithField("delayTime", delayTime).Warn("github api throttled, delaying")
-			time.Sleep(delayTime)
-			return gh.GetRepoIssuePage(org, repo, page, since)
-		}
-
 		logger.WithError(err).Error("listing page")
 		return nil, nil, errors.Wrap(err, "issue retrieval failed")
 	}
@@ -165,6 +266,274 @@ func (gh *Github) GetRepoIssuePage(org, repo string, page int, since *time.Time)
 	return newIssues, resp, err
 }
 
+// ListRepoComments lists issue and pull request comments for a repository
+// which were updated after since. If since is nil, all comments are listed.
+func (gh *Github) ListRepoComments(repoName string, since *time.Time) ([]github.IssueComment, error) {
+	org, repo, err := splitRepo(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	commentOptions := &github.IssueListCommentsOptions{
+		Sort:      "updated",
+		Direction: "asc",
+		ListOptions: github.ListOptions{
+			PerPage: perPageCount,
+		},
+	}
+
+	if since != nil {
+		commentOptions.Since = *since
+	}
+
+	throttle := time.Tick(githubRateLimit)
+
+	allComments := []github.IssueComment{}
+	for {
+		<-throttle
+		logger := log.WithField("currentPage", commentOptions.Page)
+		var comments []*github.IssueComment
+		resp, err := gh.retry(logger, func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			comments, resp, err = gh.client.Issues.ListComments(org, repo, 0, commentOptions)
+			return resp, err
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "comment retrieval failed")
+		}
+
+		logger.WithFields(log.Fields{
+			"lastPage": resp.LastPage,
+			"apiCalls": resp.Rate.Remaining}).Info("fetched comment page")
+
+		for _, comment := range comments {
+			allComments = append(allComments, *comment)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		commentOptions.Page = resp.NextPage
+	}
+
+	return allComments, nil
+}
+
+// ListOpenIssues lists the open issues and pull requests of a repository.
+func (gh *Github) ListOpenIssues(repoName string) ([]github.Issue, error) {
+	org, repo, err := splitRepo(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	issueOptions := &github.IssueListByRepoOptions{
+		State: "open",
+		ListOptions: github.ListOptions{
+			PerPage: perPageCount,
+		},
+	}
+
+	throttle := time.Tick(githubRateLimit)
+
+	allIssues := []github.Issue{}
+	for {
+		<-throttle
+		logger := log.WithField("currentPage", issueOptions.Page)
+		var issues []*github.Issue
+		resp, err := gh.retry(logger, func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			issues, resp, err = gh.client.Issues.ListByRepo(org, repo, issueOptions)
+			return resp, err
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "open issue retrieval failed")
+		}
+
+		logger.WithFields(log.Fields{
+			"lastPage": resp.LastPage,
+			"apiCalls": resp.Rate.Remaining}).Info("fetched open issue page")
+
+		for _, issue := range issues {
+			allIssues = append(allIssues, *issue)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		issueOptions.Page = resp.NextPage
+	}
+
+	return allIssues, nil
+}
+
+// ErrIssueGone is returned for an issue which was deleted, or which can no
+// longer be seen.
+var ErrIssueGone = errors.New("issue is gone")
+
+// GetIssue gets an issue. Github redirects requests for a transferred issue
+// to its new repository, so the repository the issue is in now is returned
+// with it.
+func (gh *Github) GetIssue(repoName string, number int) (*github.Issue, string, error) {
+	org, repo, err := splitRepo(repoName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	logger := log.WithFields(log.Fields{"repo": repoName, "number": number})
+	var issue *github.Issue
+	_, err = gh.retry(logger, func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		issue, resp, err = gh.client.Issues.Get(org, repo, number)
+		return resp, err
+	})
+	if errResp, ok := err.(*github.ErrorResponse); ok {
+		switch errResp.Response.StatusCode {
+		case http.StatusNotFound, http.StatusGone:
+			return nil, "", ErrIssueGone
+		}
+	}
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "issue %d retrieval failed", number)
+	}
+
+	current := repoName
+	if issue.HTMLURL != nil {
+		if r, ok := repoFromHTMLURL(*issue.HTMLURL); ok {
+			current = r
+		}
+	}
+
+	return issue, current, nil
+}
+
+// repoFromHTMLURL returns the repository of an issue or pull request from
+// its page, e.g. https://github.com/org/repo/issues/1.
+func repoFromHTMLURL(htmlURL string) (string, bool) {
+	u, err := url.Parse(htmlURL)
+	if err != nil {
+		return "", false
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 4 || (parts[2] != "issues" && parts[2] != "pull") {
+		return "", false
+	}
+
+	return parts[0] + "/" + parts[1], true
+}
+
+// ListRepoEvents lists issue events for a repository which are newer than the
+// event with id afterID. Github returns events newest first, so listing stops
+// at the first page containing a known event.
+func (gh *Github) ListRepoEvents(repoName string, afterID int) ([]github.IssueEvent, error) {
+	org, repo, err := splitRepo(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	listOptions := &github.ListOptions{PerPage: perPageCount}
+	throttle := time.Tick(githubRateLimit)
+
+	allEvents := []github.IssueEvent{}
+	for {
+		<-throttle
+		logger := log.WithField("currentPage", listOptions.Page)
+		var events []*github.IssueEvent
+		resp, err := gh.retry(logger, func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			events, resp, err = gh.client.Issues.ListRepositoryEvents(org, repo, listOptions)
+			return resp, err
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "event retrieval failed")
+		}
+
+		logger.WithFields(log.Fields{
+			"lastPage": resp.LastPage,
+			"apiCalls": resp.Rate.Remaining}).Info("fetched event page")
+
+		done := false
+		for _, event := range events {
+			if event.ID != nil && *event.ID <= afterID {
+				done = true
+				break
+			}
+			allEvents = append(allEvents, *event)
+		}
+
+		if done || resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+
+	return allEvents, nil
+}
+
+// retry calls fn until it succeeds, waiting when the github api throttles
+// and retrying server errors a few times. Other errors are returned.
+func (gh *Github) retry(logger *log.Entry, fn func() (*github.Response, error)) (*github.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := fn()
+		gh.stats.record(err)
+		observeGithubResponse(resp)
+		if err == nil {
+			return resp, nil
+		}
+
+		delay, ok := retryDelay(resp, err, attempt)
+		if !ok {
+			return resp, err
+		}
+
+		logger.WithError(err).WithFields(log.Fields{
+			"attempt":   attempt,
+			"delayTime": delay}).Warn("github api request failed, delaying")
+		time.Sleep(delay)
+	}
+}
+
+// retryDelay returns how long to wait before retrying a failed request, and
+// false if it shouldn't be retried. Throttled requests wait until the rate
+// limit resets, or for as long as Github asks.
+func retryDelay(resp *github.Response, err error, attempt int) (time.Duration, bool) {
+	jitter := time.Duration(0)
+	if throttleJitter > 0 {
+		jitter = time.Duration(rand.Int63n(int64(throttleJitter)))
+	}
+
+	if rateErr, ok := err.(*github.RateLimitError); ok {
+		delay := time.Until(rateErr.Rate.Reset.Time)
+		if delay < minThrottleDelay {
+			delay = minThrottleDelay
+		}
+		return delay + jitter, true
+	}
+
+	if resp == nil {
+		return 0, false
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusForbidden && resp.Header.Get("Retry-After") != "":
+		// abuse detection asks clients to wait
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		delay := time.Duration(seconds) * time.Second
+		if delay < minThrottleDelay {
+			delay = minThrottleDelay
+		}
+		return delay + jitter, true
+	case resp.StatusCode >= 500 && attempt <= serverErrorRetries:
+		return serverErrorDelay * time.Duration(attempt), true
+	}
+
+	return 0, false
+}
+
 type worker struct {
 	id   int
 	gh   *Github
@@ -0,0 +1,140 @@
+package kubenews
+
+import (
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/require"
+)
+
+func issueNumbers(issues []github.Issue) []int {
+	numbers := []int{}
+	for _, issue := range issues {
+		numbers = append(numbers, *issue.Number)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+func TestListRepoIssuesPaginates(t *testing.T) {
+	f, gh := newTestGithub(t)
+
+	issues, err := gh.ListRepoIssues("org/repo", nil)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3, 4, 5}, issueNumbers(issues))
+
+	// each of the three pages is fetched once
+	require.Len(t, f.requested(fakeIssuesPath), 3)
+}
+
+func TestListRepoIssuesSince(t *testing.T) {
+	_, gh := newTestGithub(t)
+
+	since := time.Date(2017, 3, 4, 0, 0, 0, 0, time.UTC)
+	issues, err := gh.ListRepoIssues("org/repo", &since)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 3, 4}, issueNumbers(issues))
+}
+
+func TestListRepoIssuesWorkerFailure(t *testing.T) {
+	f, gh := newTestGithub(t)
+
+	f.fail(fakeIssuesPath, fakeFailure{status: http.StatusNotFound, page: 3})
+
+	_, err := gh.ListRepoIssues("org/repo", nil)
+	require.Error(t, err)
+}
+
+func TestGithubRetriesRateLimit(t *testing.T) {
+	f, gh := newTestGithub(t)
+
+	f.fail(fakeCommentsPath, fakeFailure{status: http.StatusForbidden, rateLimited: true})
+
+	comments, err := gh.ListRepoComments("org/repo", nil)
+	require.NoError(t, err)
+	require.Len(t, comments, 3)
+
+	// the throttled first page is retried, then two pages are fetched
+	require.Len(t, f.requested(fakeCommentsPath), 3)
+}
+
+func TestGithubRetriesServerErrors(t *testing.T) {
+	f, gh := newTestGithub(t)
+
+	f.fail(fakeEventsPath,
+		fakeFailure{status: http.StatusBadGateway},
+		fakeFailure{status: http.StatusServiceUnavailable})
+
+	events, err := gh.ListRepoEvents("org/repo", 0)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+
+	failures := []fakeFailure{}
+	for i := 0; i <= serverErrorRetries; i++ {
+		failures = append(failures, fakeFailure{status: http.StatusInternalServerError})
+	}
+	f.fail(fakeEventsPath, failures...)
+
+	_, err = gh.ListRepoEvents("org/repo", 0)
+	require.Error(t, err)
+}
+
+func TestGithubForbiddenFails(t *testing.T) {
+	f, gh := newTestGithub(t)
+
+	f.fail(fakeEventsPath, fakeFailure{status: http.StatusForbidden})
+
+	_, err := gh.ListRepoEvents("org/repo", 0)
+	require.Error(t, err)
+	require.Len(t, f.requested(fakeEventsPath), 1)
+}
+
+func TestListRepoEventsStopsAtKnownEvent(t *testing.T) {
+	f, gh := newTestGithub(t)
+
+	events, err := gh.ListRepoEvents("org/repo", 2002)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, 2004, *events[0].ID)
+
+	// the second page holds the known event, so no more pages are fetched
+	require.Len(t, f.requested(fakeEventsPath), 2)
+}
+
+func TestRetryDelay(t *testing.T) {
+	defer setDuration(&minThrottleDelay, time.Second)()
+	defer setDuration(&throttleJitter, 0)()
+	defer setDuration(&serverErrorDelay, time.Second)()
+
+	response := func(status int, header http.Header) *github.Response {
+		if header == nil {
+			header = http.Header{}
+		}
+		return &github.Response{Response: &http.Response{StatusCode: status, Header: header}}
+	}
+
+	reset := time.Now().Add(time.Minute)
+	delay, ok := retryDelay(nil, &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: reset}}}, 1)
+	require.True(t, ok)
+	require.InDelta(t, float64(time.Minute), float64(delay), float64(time.Second))
+
+	delay, ok = retryDelay(response(http.StatusForbidden, http.Header{"Retry-After": {"5"}}), &github.ErrorResponse{}, 1)
+	require.True(t, ok)
+	require.Equal(t, 5*time.Second, delay)
+
+	_, ok = retryDelay(response(http.StatusForbidden, nil), &github.ErrorResponse{}, 1)
+	require.False(t, ok)
+
+	delay, ok = retryDelay(response(http.StatusBadGateway, nil), &github.ErrorResponse{}, 2)
+	require.True(t, ok)
+	require.Equal(t, 2*time.Second, delay)
+
+	_, ok = retryDelay(response(http.StatusBadGateway, nil), &github.ErrorResponse{}, serverErrorRetries+1)
+	require.False(t, ok)
+
+	_, ok = retryDelay(response(http.StatusNotFound, nil), &github.ErrorResponse{}, 1)
+	require.False(t, ok)
+}
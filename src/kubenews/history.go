@@ -0,0 +1,115 @@
+package kubenews
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// History is a set of issues along with their comments and events.
+type History struct {
+	Issues   []Issue
+	Comments map[int][]Comment
+	Events   map[int][]IssueEvent
+}
+
+// NewHistory creates an instance of History, grouping comments and events by
+// issue number.
+func NewHistory(issues []Issue, comments []Comment, events []IssueEvent) *History {
+	h := &History{
+		Issues:   issues,
+		Comments: map[int][]Comment{},
+		Events:   map[int][]IssueEvent{},
+	}
+
+	for _, c := range comments {
+		h.Comments[c.IssueNumber] = append(h.Comments[c.IssueNumber], c)
+	}
+
+	for _, e := range events {
+		h.Events[e.IssueNumber] = append(h.Events[e.IssueNumber], e)
+	}
+
+	return h
+}
+
+// LoadHistory loads the issues created in a time range for a repository with
+// their comments and events. Comments and events are ordered oldest first.
+func (s *sqlStore) LoadHistory(repository string, from, to time.Time) (*History, error) {
+	issues := []Issue{}
+	if err := s.selectx(&issues, issuesCreatedBetweenSQL, repository, from, to); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve issues")
+	}
+
+	comments := []Comment{}
+	if err := s.selectx(&comments, commentsForIssuesCreatedBetweenSQL, repository, from, to); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve comments")
+	}
+
+	events := []IssueEvent{}
+	if err := s.selectx(&events, eventsForIssuesCreatedBetweenSQL, repository, from, to); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve events")
+	}
+
+	return NewHistory(issues, comments, events), nil
+}
+
+// LoadOpenHistory loads the open issues for a repository with their comments
+// and events. Comments and events are ordered oldest first.
+func (s *sqlStore) LoadOpenHistory(repository string) (*History, error) {
+	issues, err := s.OpenIssues(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := []Comment{}
+	if err := s.selectx(&comments, commentsForOpenIssuesSQL, repository); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve comments")
+	}
+
+	events := []IssueEvent{}
+	if err := s.selectx(&events, eventsForOpenIssuesSQL, repository); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve events")
+	}
+
+	return NewHistory(issues, comments, events), nil
+}
+
+var (
+	issuesCreatedBetweenSQL = `
+  SELECT id, number, state, title, body, created_by, labels, assignee, closed_at,
+    created_at, updated_at, milestone, repository, pull_request
+  FROM issues
+  WHERE repository = $1 AND created_at >= $2 AND created_at < $3
+  ORDER BY number`
+
+	commentsForIssuesCreatedBetweenSQL = `
+  SELECT c.id, c.repository, c.issue_number, c.created_by, c.body, c.created_at, c.updated_at
+  FROM comments c
+  JOIN issues i ON i.repository = c.repository AND i.number = c.issue_number
+  WHERE i.repository = $1 AND i.created_at >= $2 AND i.created_at < $3
+  ORDER BY c.created_at, c.id`
+
+	eventsForIssuesCreatedBetweenSQL = `
+  SELECT e.id, e.repository, e.issue_number, e.event, e.actor, e.label, e.milestone,
+    e.assignee, e.commit_id, e.created_at
+  FROM issue_events e
+  JOIN issues i ON i.repository = e.repository AND i.number = e.issue_number
+  WHERE i.repository = $1 AND i.created_at >= $2 AND i.created_at < $3
+  ORDER BY e.created_at, e.id`
+
+	commentsForOpenIssuesSQL = `
+  SELECT c.id, c.repository, c.issue_number, c.created_by, c.body, c.created_at, c.updated_at
+  FROM comments c
+  JOIN issues i ON i.repository = c.repository AND i.number = c.issue_number
+  WHERE i.repository = $1 AND i.state = 'open'
+  ORDER BY c.created_at, c.id`
+
+	eventsForOpenIssuesSQL = `
+  SELECT e.id, e.repository, e.issue_number, e.event, e.actor, e.label, e.milestone,
+    e.assignee, e.commit_id, e.created_at
+  FROM issue_events e
+  JOIN issues i ON i.repository = e.repository AND i.number = e.issue_number
+  WHERE i.repository = $1 AND i.state = 'open'
+  ORDER BY e.created_at, e.id`
+)
@@ -10,25 +10,34 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/google/go-github/github"
-	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 )
 
 // Issue is a Github issue.
 type Issue struct {
-	ID         int        `db:"id"`
-	Number     int        `db:"number"`
-	State      string     `db:"state"`
-	Title      string     `db:"title"`
-	Body       string     `db:"body"`
-	User       string     `db:"created_by"`
-	Labels     Labels     `db:"labels"`
-	Assignee   string     `db:"assignee"`
-	ClosedAt   *time.Time `db:"closed_at"`
-	CreatedAt  *time.Time `db:"created_at"`
-	UpdatedAt  *time.Time `db:"updated_at"`
-	Milestone  string     `db:"milestone"`
-	Repository string     `db:"repository"`
+	ID          int        `db:"id" json:"id"`
+	Number      int        `db:"number" json:"number"`
+	State       string     `db:"state" json:"state"`
+	Title       string     `db:"title" json:"title"`
+	Body        string     `db:"body" json:"body"`
+	User        string     `db:"created_by" json:"user"`
+	Labels      Labels     `db:"labels" json:"labels"`
+	Assignee    string     `db:"assignee" json:"assignee"`
+	ClosedAt    *time.Time `db:"closed_at" json:"closed_at"`
+	CreatedAt   *time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt   *time.Time `db:"updated_at" json:"updated_at"`
+	Milestone   string     `db:"milestone" json:"milestone"`
+	Repository  string     `db:"repository" json:"repository"`
+	PullRequest bool       `db:"pull_request" json:"pull_request"`
+}
+
+// HTMLURL returns the Github URL for the issue.
+func (i Issue) HTMLURL() string {
+	if i.PullRequest {
+		return fmt.Sprintf("https://github.com/%s/pull/%d", i.Repository, i.Number)
+	}
+
+	return fmt.Sprintf("https://github.com/%s/issues/%d", i.Repository, i.Number)
 }
 
 // Label is a Github label.
@@ -48,12 +57,16 @@ func (l Labels) Value() (driver.Value, error) {
 
 // Scan converts a DB value back into Labels.
 func (l *Labels) Scan(src interface{}) error {
-	if src == nil {
+	switch v := src.(type) {
+	case nil:
 		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	default:
+		return errors.Errorf("unable to scan %T into labels", src)
 	}
-
-	b := json.RawMessage(src.([]uint8))
-	return json.Unmarshal(b, l)
 }
 
 // LastUpdate is the time of issues where last updated
@@ -63,9 +76,9 @@ type LastUpdate struct {
 }
 
 // LastIssueUpdate retrieves the last time an issue was updated for a repository.
-func LastIssueUpdate(db *sqlx.DB, repository string) (*LastUpdate, error) {
+func (s *sqlStore) LastIssueUpdate(repository string) (*LastUpdate, error) {
 	lastUpdate := LastUpdate{}
-	if err := db.Get(&lastUpdate, lastUpdateSQL, repository); err != nil {
+	if err := s.get(&lastUpdate, lastUpdateSQL, repository); err != nil {
 		if err == sql.ErrNoRows {
 			return &LastUpdate{Repository: repository}, nil
 		}
@@ -76,11 +89,52 @@ func LastIssueUpdate(db *sqlx.DB, repository string) (*LastUpdate, error) {
 	return &lastUpdate, nil
 }
 
-// ImportIssues imports issues to our datastore. If the issue exists, it is updated.
-func ImportIssues(db *sqlx.DB, repository string, inIssues []github.Issue) error {
-	tx, err := db.Begin()
+// IssuesActiveBetween retrieves the issues for a repository which were opened or
+// closed in a time range.
+func (s *sqlStore) IssuesActiveBetween(repository string, from, to time.Time) ([]Issue, error) {
+	issues := []Issue{}
+	if err := s.selectx(&issues, issuesActiveBetweenSQL, repository, from, to); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve issues")
+	}
+
+	return issues, nil
+}
+
+// OpenIssues retrieves the open issues for a repository.
+func (s *sqlStore) OpenIssues(repository string) ([]Issue, error) {
+	issues := []Issue{}
+	if err := s.selectx(&issues, openIssuesSQL, repository); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve open issues")
+	}
+
+	return issues, nil
+}
+
+// ImportIssues imports issues to a store. If the issue exists, it is updated.
+// The references in the body of each issue are saved too.
+func ImportIssues(s Store, repository string, inIssues []github.Issue) (Saved, error) {
+	issues := []Issue{}
+	sources := []ReferenceSource{}
+	for _, in := range inIssues {
+		issue := ConvertIssue(repository, in)
+		issues = append(issues, issue)
+		sources = append(sources, issueReferences(issue))
+	}
+
+	saved, err := s.SaveIssues(issues)
+	if err != nil {
+		return saved, err
+	}
+
+	return saved, s.SaveReferences(sources)
+}
+
+// SaveIssues inserts or updates issues, then records the labels of open
+// issues.
+func (s *sqlStore) SaveIssues(issues []Issue) (saved Saved, err error) {
+	tx, err := s.db.Beginx()
 	if err != nil {
-		return errors.Wrap(err, "import issue failure")
+		return saved, errors.Wrap(err, "import issue failure")
 	}
 
 	defer func() {
@@ -93,56 +147,67 @@ func ImportIssues(db *sqlx.DB, repository string, inIssues []github.Issue) error
 	}()
 
 	log.Info("updating or importing issues")
-	for _, in := range inIssues {
-		issue := ConvertIssue(repository, in)
-
-		if _, err := tx.Exec(insertIssueSQL, issue.Number, issue.State, issue.Title, issue.Body,
+	for _, issue := range issues {
+		inserted, err := s.upsert(tx, upsertIssueSQL, issue.Number, issue.State, issue.Title, issue.Body,
 			issue.User, issue.Labels, issue.Assignee, issue.ClosedAt, issue.CreatedAt,
-			issue.UpdatedAt, issue.Milestone, issue.Repository); err != nil {
-			return err
+			issue.UpdatedAt, issue.Milestone, issue.Repository, issue.PullRequest)
+		if err != nil {
+			return saved, errors.Wrap(err, "insert issue")
 		}
+		saved.count(inserted)
 	}
 
 	log.Info("analyzing labels")
 	labels := map[string]Label{}
 
-	issue := Issue{}
-	rows, err := db.Queryx(activeIssuesSQL)
-	if err != nil {
-		return errors.Wrap(err, "query open issues failure")
+	active := []Issue{}
+	if err := tx.Select(&active, s.rebind(activeIssuesSQL)); err != nil {
+		return saved, errors.Wrap(err, "query open issues failure")
 	}
-	for rows.Next() {
-		err := rows.StructScan(&issue)
-		if err != nil {
-			return errors.Wrap(err, "scan issue into struct failure")
-		}
-
+	for _, issue := range active {
 		for _, label := range issue.Labels {
 			labels[label.Name] = label
 		}
 	}
 
 	for _, label := range labels {
-		if _, err := tx.Exec(insertLabelSQL, label.Name, label.URL, label.Color); err != nil {
-			return errors.Wrap(err, "insert label")
+		if err := s.exec(tx, insertLabelSQL, label.Name, label.URL, label.Color); err != nil {
+			return saved, errors.Wrap(err, "insert label")
 		}
 	}
 
-	return nil
+	return saved, nil
+}
+
+// SetIssueState sets the state of an issue, e.g. when reconciling finds it
+// was deleted.
+func (s *sqlStore) SetIssueState(repository string, number int, state string) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "set issue state failure")
+	}
+
+	if err := s.exec(tx, setIssueStateSQL, repository, number, state); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "update issue state")
+	}
+
+	return tx.Commit()
 }
 
 // ConvertIssue converts an issue from the github api client to our format.
 func ConvertIssue(repostitory string, in github.Issue) Issue {
 
 	issue := Issue{
-		Number:     *in.Number,
-		State:      *in.State,
-		Title:      *in.Title,
-		Labels:     []Label{},
-		ClosedAt:   in.ClosedAt,
-		CreatedAt:  in.CreatedAt,
-		UpdatedAt:  in.UpdatedAt,
-		Repository: repostitory,
+		Number:      *in.Number,
+		State:       *in.State,
+		Title:       *in.Title,
+		Labels:      []Label{},
+		ClosedAt:    in.ClosedAt,
+		CreatedAt:   in.CreatedAt,
+		UpdatedAt:   in.UpdatedAt,
+		Repository:  repostitory,
+		PullRequest: in.PullRequestLinks != nil,
 	}
 
 	defer func() {
@@ -185,18 +250,28 @@ func ConvertLabel(in github.Label) Label {
 }
 
 var (
-	insertIssueSQL = `
+	upsertIssueSQL = upsert{insert: `
   INSERT INTO issues
   (number, state, title, body, created_by, labels, assignee, closed_at, created_at,
-  updated_at, milestone, repository)
+  updated_at, milestone, repository, pull_request)
 
   VALUES
-  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+
+  ON conflict (repository, number)
+  DO UPDATE SET (state, title, body, labels, assignee, closed_at, updated_at, milestone,
+    pull_request) = ($2, $3, $4, $6, $7, $8, $10, $11, $13)
+  WHERE issues.repository = $12 AND issues.number = $1`,
 
-  ON conflict (number)
-  DO UPDATE SET (state, title, body, labels, assignee, closed_at, updated_at, milestone) =
-    ($2, $3, $4, $6, $7, $8, $10, $11)
-  WHERE issues.number = $1`
+		update: `
+  UPDATE issues SET (state, title, body, labels, assignee, closed_at, updated_at, milestone,
+    pull_request) = ($2, $3, $4, $6, $7, $8, $10, $11, $13)
+  WHERE repository = $12 AND number = $1`,
+	}
+
+	setIssueStateSQL = `
+  UPDATE issues SET state = $3
+  WHERE repository = $1 AND number = $2`
 
 	lastUpdateSQL = `
   SELECT updated_at FROM issues
@@ -216,7 +291,22 @@ var (
 
 	activeIssuesSQL = `
   SELECT id, number, state, title, body, created_by, labels, assignee, closed_at,
-    created_at, updated_at, milestone, repository
+    created_at, updated_at, milestone, repository, pull_request
   FROM issues
   WHERE state = 'open'`
+
+	issuesActiveBetweenSQL = `
+  SELECT id, number, state, title, body, created_by, labels, assignee, closed_at,
+    created_at, updated_at, milestone, repository, pull_request
+  FROM issues
+  WHERE repository = $1
+    AND ((created_at >= $2 AND created_at < $3) OR (closed_at >= $2 AND closed_at < $3))
+  ORDER BY number`
+
+	openIssuesSQL = `
+  SELECT id, number, state, title, body, created_by, labels, assignee, closed_at,
+    created_at, updated_at, milestone, repository, pull_request
+  FROM issues
+  WHERE repository = $1 AND state = 'open'
+  ORDER BY number`
 )
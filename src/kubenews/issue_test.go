@@ -1,88 +1,68 @@
 package kubenews
 
 import (
-	"database/sql/driver"
-	"encoding/json"
 	"testing"
 	"time"
 
 	"github.com/google/go-github/github"
-	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/require"
-
-	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 )
 
-type anyTime struct{}
-
-// Match satisfies sqlmock.Argument interface
-func (a anyTime) Match(v driver.Value) bool {
-	_, ok := v.(time.Time)
-	return ok
-}
+func TestImportIssues(t *testing.T) {
+	s := NewMemoryStore()
 
-type validJSON struct{}
+	now := time.Now()
+	issue := func(number int, state string) github.Issue {
+		return github.Issue{
+			Number: github.Int(number),
+			State:  github.String(state),
+			Title:  github.String("title"),
+			Body:   github.String("body"),
+			User:   &github.User{Login: github.String("user")},
+			Labels: []github.Label{
+				{URL: github.String("http://example.com"), Name: github.String("label1"), Color: github.String("#fff")},
+			},
+			Assignee:  &github.User{Login: github.String("assignee")},
+			CreatedAt: &now,
+			UpdatedAt: &now,
+			Milestone: &github.Milestone{Title: github.String("milestone")},
+		}
+	}
 
-func (j validJSON) Match(v driver.Value) bool {
-	var out []interface{}
-	err := json.Unmarshal(v.([]uint8), &out)
-	return err == nil
-}
+	issues := []github.Issue{issue(1, "open"), issue(2, "closed")}
+	issues[1].ClosedAt = &now
+	issues[1].PullRequestLinks = &github.PullRequestLinks{}
 
-func TestImportIssues(t *testing.T) {
-	stdlibdb, mock, err := sqlmock.New()
+	saved, err := ImportIssues(s, "org/repo", issues)
 	require.NoError(t, err)
-	db := sqlx.NewDb(stdlibdb, "mockdriver")
+	require.Equal(t, Saved{Inserted: 2}, saved)
 
-	mock.ExpectBegin()
-	mock.ExpectExec("INSERT INTO issues").WithArgs(1, "open", "title", "body", "user", validJSON{},
-		"assignee", anyTime{}, anyTime{}, anyTime{}, "milestone", "org/repo").
-		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("INSERT INTO issues").WithArgs(2, "open", "title", "body", "user", validJSON{},
-		"assignee", anyTime{}, anyTime{}, anyTime{}, "milestone", "org/repo").
-		WillReturnResult(sqlmock.NewResult(2, 1))
-	mock.ExpectCommit()
+	h, err := s.LoadRepositoryHistory("org/repo")
+	require.NoError(t, err)
+	require.Len(t, h.Issues, 2)
 
-	now := time.Now()
+	got := h.Issues[0]
+	require.Equal(t, 1, got.Number)
+	require.Equal(t, "open", got.State)
+	require.Equal(t, "user", got.User)
+	require.Equal(t, "assignee", got.Assignee)
+	require.Equal(t, "milestone", got.Milestone)
+	require.Equal(t, "org/repo", got.Repository)
+	require.Equal(t, Labels{{URL: "http://example.com", Name: "label1", Color: "#fff"}}, got.Labels)
+	require.False(t, got.PullRequest)
+	require.True(t, h.Issues[1].PullRequest)
 
-	issue1 := github.Issue{
-		Number: github.Int(1),
-		State:  github.String("open"),
-		Title:  github.String("title"),
-		Body:   github.String("body"),
-		User:   &github.User{Name: github.String("user")},
-		Labels: []github.Label{
-			{URL: github.String("http://example.com"), Name: github.String("label1"), Color: github.String("#fff")},
-		},
-		Assignee:   &github.User{Name: github.String("assignee")},
-		ClosedAt:   &now,
-		CreatedAt:  &now,
-		UpdatedAt:  &now,
-		Milestone:  &github.Milestone{Title: github.String("milestone")},
-		Repository: &github.Repository{FullName: github.String("org/repo")},
-	}
-	issue2 := github.Issue{
-		Number: github.Int(2),
-		State:  github.String("open"),
-		Title:  github.String("title"),
-		Body:   github.String("body"),
-		User:   &github.User{Name: github.String("user")},
-		Labels: []github.Label{
-			{URL: github.String("http://example.com"), Name: github.String("label1"), Color: github.String("#fff")},
-		},
-		Assignee:   &github.User{Name: github.String("assignee")},
-		ClosedAt:   &now,
-		CreatedAt:  &now,
-		UpdatedAt:  &now,
-		Milestone:  &github.Milestone{Title: github.String("milestone")},
-		Repository: &github.Repository{FullName: github.String("org/repo")},
-	}
-
-	issues := []github.Issue{issue1, issue2}
+	labels, err := s.ActiveLabels()
+	require.NoError(t, err)
+	require.Equal(t, []StoredLabel{{Name: "label1", URL: "http://example.com", Color: "#fff", Active: true}}, labels)
 
-	err = ImportIssues(db, issues)
+	// importing again updates the issue
+	issues[0].State = github.String("closed")
+	saved, err = ImportIssues(s, "org/repo", issues[:1])
 	require.NoError(t, err)
+	require.Equal(t, Saved{Updated: 1}, saved)
 
-	err = mock.ExpectationsWereMet()
+	open, err := s.OpenIssues("org/repo")
 	require.NoError(t, err)
+	require.Len(t, open, 0)
 }
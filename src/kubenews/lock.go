@@ -0,0 +1,277 @@
+package kubenews
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// Lock policies say what to do when another job holds a repository's lock.
+const (
+	// LockWait waits for the lock, up to a timeout.
+	LockWait = "wait"
+	// LockSkip skips the job.
+	LockSkip = "skip"
+)
+
+// ErrLocked is returned when a repository's lock is held by another job.
+var ErrLocked = errors.New("repository is locked by another job")
+
+var (
+	// lockPollInterval is how often a waiting job retries a held lock.
+	lockPollInterval = time.Second
+)
+
+// LockOptions is how jobs wait for a lock.
+type LockOptions struct {
+	Policy string
+	// Timeout is the longest to wait with LockWait. Zero waits forever.
+	Timeout time.Duration
+}
+
+// Locker takes per-repository locks, so sync and publish jobs for a
+// repository never overlap. Waiting for a lock stops when ctx is done.
+type Locker interface {
+	Lock(ctx context.Context, repository, job string) (*Lease, error)
+}
+
+// Lease is a held repository lock.
+type Lease struct {
+	Repository string    `db:"repository" json:"repository"`
+	Job        string    `db:"job" json:"job"`
+	Holder     string    `db:"holder" json:"holder"`
+	AcquiredAt time.Time `db:"acquired_at" json:"acquired_at"`
+
+	release func() error
+}
+
+// Release releases the lock.
+func (l *Lease) Release() error {
+	if l.release == nil {
+		return nil
+	}
+
+	return l.release()
+}
+
+// WithLock runs fn holding a repository's lock. If the lock is held and the
+// policy is to skip, fn isn't run and ErrLocked is returned. If ctx is done
+// while waiting for the lock, fn isn't run and ctx.Err() is returned.
+func WithLock(ctx context.Context, locker Locker, repository, job string, fn func() error) (err error) {
+	lease, err := locker.Lock(ctx, repository, job)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if releaseErr := lease.Release(); releaseErr != nil && err == nil {
+			err = errors.Wrap(releaseErr, "release lock")
+		}
+	}()
+
+	return fn()
+}
+
+// lockHolder identifies this process in leases.
+func lockHolder() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// acquire calls try until it takes the lock, following the policy, or ctx is
+// done.
+func acquire(ctx context.Context, opts LockOptions, logger *log.Entry, try func() (bool, error)) error {
+	start := time.Now()
+	for waiting := false; ; waiting = true {
+		ok, err := try()
+		if err != nil || ok {
+			return err
+		}
+
+		if opts.Policy == LockSkip || (opts.Timeout > 0 && time.Since(start) >= opts.Timeout) {
+			return ErrLocked
+		}
+
+		if !waiting {
+			logger.Info("waiting for lock")
+		}
+
+		timer := time.NewTimer(lockPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// AdvisoryLocker takes Postgres advisory locks, so jobs don't overlap across
+// processes and replicas sharing a database. The lease of each held lock is
+// recorded in the leases table.
+type AdvisoryLocker struct {
+	db     *sqlx.DB
+	opts   LockOptions
+	holder string
+}
+
+// NewAdvisoryLocker creates an instance of AdvisoryLocker.
+func NewAdvisoryLocker(db *sqlx.DB, opts LockOptions) *AdvisoryLocker {
+	return &AdvisoryLocker{db: db, opts: opts, holder: lockHolder()}
+}
+
+// lockKey is the advisory lock key for a repository.
+func lockKey(repository string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("kubenews/" + repository))
+	return int64(h.Sum64())
+}
+
+// Lock takes the advisory lock for a repository. The lock belongs to a
+// database session, so it is held on a dedicated connection until released.
+// ctx only bounds taking the lock, so a lock taken is always released.
+func (l *AdvisoryLocker) Lock(ctx context.Context, repository, job string) (*Lease, error) {
+	key := lockKey(repository)
+	logger := log.WithFields(log.Fields{"repo": repository, "job": job})
+
+	conn, err := l.db.DB.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "lock connection")
+	}
+
+	err = acquire(ctx, l.opts, logger, func() (bool, error) {
+		var ok bool
+		if err := conn.QueryRowContext(ctx, tryLockSQL, key).Scan(&ok); err != nil {
+			return false, errors.Wrap(err, "take advisory lock")
+		}
+		return ok, nil
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	lease := &Lease{
+		Repository: repository,
+		Job:        job,
+		Holder:     l.holder,
+		AcquiredAt: time.Now().UTC(),
+	}
+	lease.release = func() error {
+		defer conn.Close()
+
+		if _, err := conn.ExecContext(context.Background(), deleteLeaseSQL, key); err != nil {
+			return errors.Wrap(err, "delete lease")
+		}
+
+		var ok bool
+		if err := conn.QueryRowContext(context.Background(), unlockSQL, key).Scan(&ok); err != nil {
+			return errors.Wrap(err, "release advisory lock")
+		}
+
+		logger.Debug("released lock")
+		return nil
+	}
+
+	if _, err := conn.ExecContext(context.Background(), insertLeaseSQL, key, repository, job, l.holder, lease.AcquiredAt); err != nil {
+		lease.Release()
+		return nil, errors.Wrap(err, "record lease")
+	}
+
+	logger.Debug("took lock")
+	return lease, nil
+}
+
+// LoadLeases loads the leases of locks which are held. Leases left behind
+// by a process which died without releasing its lock are not included.
+func LoadLeases(db *sqlx.DB) ([]Lease, error) {
+	leases := []Lease{}
+	if err := db.Select(&leases, heldLeasesSQL); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve leases")
+	}
+
+	return leases, nil
+}
+
+// LocalLocker takes locks within a process, for stores which can't be shared
+// by several processes.
+type LocalLocker struct {
+	opts LockOptions
+
+	mu     sync.Mutex
+	leases map[string]*Lease
+}
+
+// NewLocalLocker creates an instance of LocalLocker.
+func NewLocalLocker(opts LockOptions) *LocalLocker {
+	return &LocalLocker{opts: opts, leases: map[string]*Lease{}}
+}
+
+// Lock takes the lock for a repository.
+func (l *LocalLocker) Lock(ctx context.Context, repository, job string) (*Lease, error) {
+	lease := &Lease{Repository: repository, Job: job, Holder: lockHolder()}
+	lease.release = func() error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		delete(l.leases, repository)
+		return nil
+	}
+
+	logger := log.WithFields(log.Fields{"repo": repository, "job": job})
+	err := acquire(ctx, l.opts, logger, func() (bool, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if _, held := l.leases[repository]; held {
+			return false, nil
+		}
+
+		lease.AcquiredAt = time.Now().UTC()
+		l.leases[repository] = lease
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lease, nil
+}
+
+var (
+	tryLockSQL = `SELECT pg_try_advisory_lock($1)`
+
+	unlockSQL = `SELECT pg_advisory_unlock($1)`
+
+	insertLeaseSQL = `
+  INSERT INTO leases
+  (lock_key, repository, job, holder, acquired_at)
+
+  VALUES
+  ($1, $2, $3, $4, $5)
+
+  ON conflict (lock_key)
+  DO UPDATE SET (repository, job, holder, acquired_at) = ($2, $3, $4, $5)`
+
+	deleteLeaseSQL = `DELETE FROM leases WHERE lock_key = $1`
+
+	// advisory lock keys are split into two 32 bit halves in pg_locks
+	heldLeasesSQL = `
+  SELECT l.repository, l.job, l.holder, l.acquired_at
+  FROM leases l
+  WHERE EXISTS (SELECT 1 FROM pg_locks p
+    WHERE p.locktype = 'advisory' AND p.granted AND p.objsubid = 1
+      AND ((p.classid::bigint << 32) | p.objid::bigint) = l.lock_key)
+  ORDER BY l.repository`
+)
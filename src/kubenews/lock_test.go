@@ -0,0 +1,111 @@
+package kubenews
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestLocalLocker(t *testing.T) {
+	defer setDuration(&lockPollInterval, time.Millisecond)()
+	ctx := context.Background()
+
+	skip := NewLocalLocker(LockOptions{Policy: LockSkip})
+	lease, err := skip.Lock(ctx, "org/repo", "update")
+	require.NoError(t, err)
+	require.Equal(t, "update", lease.Job)
+
+	_, err = skip.Lock(ctx, "org/repo", "publish")
+	require.Equal(t, ErrLocked, err)
+
+	// other repositories aren't locked
+	other, err := skip.Lock(ctx, "org/other", "update")
+	require.NoError(t, err)
+	require.NoError(t, other.Release())
+
+	require.NoError(t, lease.Release())
+	lease, err = skip.Lock(ctx, "org/repo", "publish")
+	require.NoError(t, err)
+
+	wait := NewLocalLocker(LockOptions{Policy: LockWait, Timeout: 5 * time.Millisecond})
+	held, err := wait.Lock(ctx, "org/repo", "update")
+	require.NoError(t, err)
+	_, err = wait.Lock(ctx, "org/repo", "publish")
+	require.Equal(t, ErrLocked, err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		held.Release()
+	}()
+	wait.opts.Timeout = 0
+	lease, err = wait.Lock(ctx, "org/repo", "publish")
+	require.NoError(t, err)
+
+	// waiting without a timeout stops when ctx is done
+	stop, cancel := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err = wait.Lock(stop, "org/repo", "update")
+	require.Equal(t, context.Canceled, err)
+	require.NoError(t, lease.Release())
+}
+
+func TestWithLock(t *testing.T) {
+	ctx := context.Background()
+	locker := NewLocalLocker(LockOptions{Policy: LockSkip})
+
+	err := WithLock(ctx, locker, "org/repo", "update", func() error {
+		return WithLock(ctx, locker, "org/repo", "publish", func() error {
+			t.Fatal("ran while locked")
+			return nil
+		})
+	})
+	require.Equal(t, ErrLocked, err)
+
+	err = WithLock(ctx, locker, "org/repo", "update", func() error { return errors.New("sync failed") })
+	require.EqualError(t, err, "sync failed")
+
+	// the lock was released by the failed job
+	require.NoError(t, WithLock(ctx, locker, "org/repo", "update", func() error { return nil }))
+}
+
+func TestAdvisoryLocker(t *testing.T) {
+	ctx := context.Background()
+	stdlibdb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := sqlx.NewDb(stdlibdb, "mockdriver")
+
+	key := lockKey("org/repo")
+	require.NotEqual(t, key, lockKey("org/other"))
+
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO leases").WithArgs(key, "org/repo", "update", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM leases").WithArgs(key).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT pg_advisory_unlock").WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(true))
+
+	locker := NewAdvisoryLocker(db, LockOptions{Policy: LockSkip})
+	lease, err := locker.Lock(ctx, "org/repo", "update")
+	require.NoError(t, err)
+	require.Equal(t, "org/repo", lease.Repository)
+	require.NoError(t, lease.Release())
+
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"ok"}).AddRow(false))
+
+	_, err = locker.Lock(ctx, "org/repo", "publish")
+	require.Equal(t, ErrLocked, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
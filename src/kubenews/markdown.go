@@ -0,0 +1,112 @@
+package kubenews
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shurcooL/sanitized_anchor_name"
+)
+
+// dateFormat is the format dates are displayed in reports.
+const dateFormat = "2006-01-02"
+
+// Report is a report which can be written as Markdown.
+type Report interface {
+	Title() string
+	WriteMarkdown(w io.Writer) error
+}
+
+// printer writes formatted output and keeps track of the first error.
+type printer struct {
+	w   io.Writer
+	err error
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
+
+func (p *printer) issues(issues []Issue) {
+	if len(issues) == 0 {
+		p.printf("None\n\n")
+		return
+	}
+
+	for _, issue := range issues {
+		p.printf("* %s\n", issueLink(issue))
+	}
+	p.printf("\n")
+}
+
+// issueLink formats a Markdown link to an issue followed by its title.
+func issueLink(issue Issue) string {
+	return fmt.Sprintf("[#%d](%s) %s", issue.Number, issue.HTMLURL(), escapeMarkdown(issue.Title))
+}
+
+// Anchor returns the anchor name rendered for a heading.
+func Anchor(heading string) string {
+	return sanitized_anchor_name.Create(heading)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`>`, `\>`,
+	`|`, `\|`,
+	`~`, `\~`,
+	`#`, `\#`,
+)
+
+// escapeMarkdown escapes text from Github, such as issue titles, so it is
+// displayed literally instead of being interpreted as Markdown or HTML.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// escapeTableCell escapes text for use in a Markdown table cell.
+func escapeTableCell(s string) string {
+	return escapeMarkdown(s)
+}
+
+var (
+	htmlCommentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+	codeFenceRe   = regexp.MustCompile("(?s)```.*?```")
+)
+
+// Snippet returns the start of an issue body as a single line of plain text.
+// HTML comments, such as issue template instructions, and code blocks are
+// dropped. The snippet is at most max characters long, so it is empty if max
+// isn't positive.
+func Snippet(body string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+
+	body = htmlCommentRe.ReplaceAllString(body, " ")
+	body = codeFenceRe.ReplaceAllString(body, " ")
+	body = strings.Join(strings.Fields(body), " ")
+
+	if utf8.RuneCountInString(body) <= max {
+		return body
+	}
+
+	runes := []rune(body)
+	cut := string(runes[:max])
+	if i := strings.LastIndex(cut, " "); i > max/2 {
+		cut = cut[:i]
+	}
+
+	return cut + "…"
+}
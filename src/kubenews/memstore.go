@@ -0,0 +1,832 @@
+package kubenews
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store held in memory. It answers queries the way the SQL
+// stores do, so tests can run without a database.
+type MemoryStore struct {
+	mu        sync.Mutex
+	nextID    int
+	issues    map[issueKey]Issue
+	comments  map[int]Comment
+	events    map[int]IssueEvent
+	labels    map[string]StoredLabel
+	snapshots map[snapshotKey]Snapshot
+	syncRuns  []SyncRun
+	refs      map[refSourceKey][]Reference
+	digests   []DigestDelivery
+	alerts    map[alertKey]time.Time
+}
+
+type issueKey struct {
+	repository string
+	number     int
+}
+
+type refSourceKey struct {
+	repository string
+	number     int
+	commentID  int
+}
+
+type alertKey struct {
+	repository string
+	number     int
+	alert      string
+	route      string
+}
+
+type snapshotKey struct {
+	day        string
+	repository string
+	dimension  string
+	value      string
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		issues:    map[issueKey]Issue{},
+		comments:  map[int]Comment{},
+		events:    map[int]IssueEvent{},
+		labels:    map[string]StoredLabel{},
+		snapshots: map[snapshotKey]Snapshot{},
+		refs:      map[refSourceKey][]Reference{},
+		alerts:    map[alertKey]time.Time{},
+	}
+}
+
+// Migrate does nothing, a MemoryStore has no schema.
+func (s *MemoryStore) Migrate() error {
+	return nil
+}
+
+// LastIssueUpdate returns the newest issue update time for a repository.
+func (s *MemoryStore) LastIssueUpdate(repository string) (*LastUpdate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := &LastUpdate{Repository: repository}
+	for _, issue := range s.issues {
+		if issue.Repository == repository {
+			last.At = latest(last.At, issue.UpdatedAt)
+		}
+	}
+
+	return last, nil
+}
+
+// LastCommentUpdate returns the newest comment update time for a repository.
+func (s *MemoryStore) LastCommentUpdate(repository string) (*LastUpdate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := &LastUpdate{Repository: repository}
+	for _, comment := range s.comments {
+		if comment.Repository == repository {
+			last.At = latest(last.At, comment.UpdatedAt)
+		}
+	}
+
+	return last, nil
+}
+
+// LastEventID returns the id of the newest event for a repository, or 0.
+func (s *MemoryStore) LastEventID(repository string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := 0
+	for _, event := range s.events {
+		if event.Repository == repository && event.ID > id {
+			id = event.ID
+		}
+	}
+
+	return id, nil
+}
+
+// SaveIssues inserts or updates issues, then records the labels of open
+// issues.
+func (s *MemoryStore) SaveIssues(issues []Issue) (Saved, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := Saved{}
+	for _, issue := range issues {
+		key := issueKey{issue.Repository, issue.Number}
+		if existing, ok := s.issues[key]; ok {
+			issue.ID = existing.ID
+			issue.User = existing.User
+			issue.CreatedAt = existing.CreatedAt
+			saved.Updated++
+		} else {
+			s.nextID++
+			issue.ID = s.nextID
+			saved.Inserted++
+		}
+		s.issues[key] = issue
+	}
+
+	for _, issue := range s.issues {
+		if issue.State != "open" {
+			continue
+		}
+		for _, label := range issue.Labels {
+			s.labels[label.Name] = StoredLabel{Name: label.Name, URL: label.URL, Color: label.Color, Active: true}
+		}
+	}
+
+	return saved, nil
+}
+
+// SetIssueState sets the state of an issue.
+func (s *MemoryStore) SetIssueState(repository string, number int, state string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := issueKey{repository, number}
+	if issue, ok := s.issues[key]; ok {
+		issue.State = state
+		s.issues[key] = issue
+	}
+
+	return nil
+}
+
+// SaveComments inserts or updates comments.
+func (s *MemoryStore) SaveComments(comments []Comment) (Saved, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := Saved{}
+	for _, comment := range comments {
+		if existing, ok := s.comments[comment.ID]; ok {
+			existing.Body = comment.Body
+			existing.UpdatedAt = comment.UpdatedAt
+			comment = existing
+			saved.Updated++
+		} else {
+			saved.Inserted++
+		}
+		s.comments[comment.ID] = comment
+	}
+
+	return saved, nil
+}
+
+// SaveEvents inserts events, skipping existing ones.
+func (s *MemoryStore) SaveEvents(events []IssueEvent) (Saved, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := Saved{}
+	for _, event := range events {
+		if _, ok := s.events[event.ID]; !ok {
+			s.events[event.ID] = event
+			saved.Inserted++
+		}
+	}
+
+	return saved, nil
+}
+
+// ActiveLabels returns the labels used by open issues.
+func (s *MemoryStore) ActiveLabels() ([]StoredLabel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	labels := []StoredLabel{}
+	for _, label := range s.labels {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+	return labels, nil
+}
+
+// IssuesActiveBetween returns the issues for a repository which were opened or
+// closed in a time range.
+func (s *MemoryStore) IssuesActiveBetween(repository string, from, to time.Time) ([]Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.selectIssues(repository, func(i Issue) bool {
+		return within(i.CreatedAt, from, to) || within(i.ClosedAt, from, to)
+	}), nil
+}
+
+// OpenIssues returns the open issues for a repository.
+func (s *MemoryStore) OpenIssues(repository string) ([]Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.selectIssues(repository, isOpen), nil
+}
+
+// LoadHistory returns the issues created in a time range for a repository with
+// their comments and events.
+func (s *MemoryStore) LoadHistory(repository string, from, to time.Time) (*History, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.history(repository, func(i Issue) bool { return within(i.CreatedAt, from, to) }, true, true), nil
+}
+
+// LoadOpenHistory returns the open issues for a repository with their
+// comments and events.
+func (s *MemoryStore) LoadOpenHistory(repository string) (*History, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.history(repository, isOpen, true, true), nil
+}
+
+// LoadLabeledHistory returns the issues for a repository which carry any of
+// labels, with their comments.
+func (s *MemoryStore) LoadLabeledHistory(repository string, labels []string) (*History, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	match := func(i Issue) bool {
+		for _, label := range i.Labels {
+			for _, name := range labels {
+				if label.Name == name {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	return s.history(repository, match, true, false), nil
+}
+
+// LoadRepositoryHistory returns all issues for a repository with their events.
+func (s *MemoryStore) LoadRepositoryHistory(repository string) (*History, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.history(repository, func(Issue) bool { return true }, false, true), nil
+}
+
+// LoadMergedPullRequests returns the pull requests merged in a milestone, or
+// merged in a time range if milestone is empty.
+func (s *MemoryStore) LoadMergedPullRequests(repository, milestone string, from, to time.Time) ([]MergedPullRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the last merged event of each pull request
+	merges := map[int]IssueEvent{}
+	for _, event := range s.events {
+		if event.Repository != repository || event.Event != "merged" {
+			continue
+		}
+		if last, ok := merges[event.IssueNumber]; !ok || event.ID > last.ID {
+			merges[event.IssueNumber] = event
+		}
+	}
+
+	prs := []MergedPullRequest{}
+	for _, issue := range s.selectIssues(repository, func(i Issue) bool { return i.PullRequest }) {
+		merge, ok := merges[issue.Number]
+		if !ok || merge.CreatedAt == nil {
+			continue
+		}
+		if milestone != "" && issue.Milestone != milestone {
+			continue
+		}
+		if milestone == "" && !within(merge.CreatedAt, from, to) {
+			continue
+		}
+		prs = append(prs, MergedPullRequest{Issue: issue, MergedAt: *merge.CreatedAt})
+	}
+
+	return prs, nil
+}
+
+// SaveSnapshots stores snapshots, replacing the existing snapshots of the
+// same repository and day.
+func (s *MemoryStore) SaveSnapshots(snapshots []Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, day := range snapshotDays(snapshots) {
+		for key := range s.snapshots {
+			if key.repository == day.Repository && key.day == truncateDay(day.Day).Format("2006-01-02") {
+				delete(s.snapshots, key)
+			}
+		}
+	}
+
+	for _, snapshot := range snapshots {
+		snapshot.Day = truncateDay(snapshot.Day)
+		key := snapshotKey{snapshot.Day.Format("2006-01-02"), snapshot.Repository, snapshot.Dimension, snapshot.Value}
+		s.snapshots[key] = snapshot
+	}
+
+	return nil
+}
+
+// LoadTrend returns the snapshots for a dimension value in a time range.
+// Days the repository was snapshotted without the value have an open count
+// of 0.
+func (s *MemoryStore) LoadTrend(repository, dimension, value string, from, to time.Time) (*Trend, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &Trend{
+		Repository: repository,
+		Dimension:  dimension,
+		Value:      value,
+		Snapshots: fillTrend(s.trendSnapshots(repository, dimension, value, from, to),
+			s.trendSnapshots(repository, SnapshotRepository, repository, from, to), dimension, value),
+	}, nil
+}
+
+func (s *MemoryStore) trendSnapshots(repository, dimension, value string, from, to time.Time) []Snapshot {
+	snapshots := []Snapshot{}
+	for _, snapshot := range s.snapshots {
+		if snapshot.Repository == repository && snapshot.Dimension == dimension &&
+			snapshot.Value == value && within(&snapshot.Day, from, to) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Day.Before(snapshots[j].Day) })
+
+	return snapshots
+}
+
+// selectIssues returns the issues for a repository matching fn, ordered by
+// number.
+func (s *MemoryStore) selectIssues(repository string, fn func(Issue) bool) []Issue {
+	issues := []Issue{}
+	for _, issue := range s.issues {
+		if issue.Repository == repository && fn(issue) {
+			issues = append(issues, issue)
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Number < issues[j].Number })
+
+	return issues
+}
+
+// history returns the issues for a repository matching fn, optionally with
+// their comments and events ordered oldest first.
+func (s *MemoryStore) history(repository string, fn func(Issue) bool, withComments, withEvents bool) *History {
+	issues := s.selectIssues(repository, fn)
+
+	numbers := map[int]bool{}
+	for _, issue := range issues {
+		numbers[issue.Number] = true
+	}
+
+	var comments []Comment
+	if withComments {
+		for _, comment := range s.comments {
+			if comment.Repository == repository && numbers[comment.IssueNumber] {
+				comments = append(comments, comment)
+			}
+		}
+		sort.Slice(comments, func(i, j int) bool {
+			return sortsBefore(comments[i].CreatedAt, comments[i].ID, comments[j].CreatedAt, comments[j].ID)
+		})
+	}
+
+	var events []IssueEvent
+	if withEvents {
+		for _, event := range s.events {
+			if event.Repository == repository && numbers[event.IssueNumber] {
+				events = append(events, event)
+			}
+		}
+		sort.Slice(events, func(i, j int) bool {
+			return sortsBefore(events[i].CreatedAt, events[i].ID, events[j].CreatedAt, events[j].ID)
+		})
+	}
+
+	return NewHistory(issues, comments, events)
+}
+
+func isOpen(i Issue) bool {
+	return i.State == "open"
+}
+
+// within reports whether t is in [from, to). A nil time is never within a
+// range, as NULL never compares true in SQL.
+func within(t *time.Time, from, to time.Time) bool {
+	return t != nil && !t.Before(from) && t.Before(to)
+}
+
+// latest returns the later of two times, ignoring nil.
+func latest(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+
+	return a
+}
+
+// sortsBefore orders records by creation time then id, with nil times last as
+// Postgres sorts NULLs.
+func sortsBefore(at *time.Time, aid int, bt *time.Time, bid int) bool {
+	switch {
+	case at == nil && bt == nil:
+		return aid < bid
+	case at == nil:
+		return false
+	case bt == nil:
+		return true
+	case !at.Equal(*bt):
+		return at.Before(*bt)
+	}
+
+	return aid < bid
+}
+
+// SaveSyncRun records a sync run.
+func (s *MemoryStore) SaveSyncRun(run SyncRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run.ID = len(s.syncRuns) + 1
+	s.syncRuns = append(s.syncRuns, run)
+	return nil
+}
+
+// LoadSyncRuns returns the most recent sync runs, newest first. If
+// repository is empty, runs of every repository are returned.
+func (s *MemoryStore) LoadSyncRuns(repository string, limit int) ([]SyncRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := []SyncRun{}
+	for _, run := range s.newestRuns() {
+		if len(runs) == limit {
+			break
+		}
+		if repository == "" || run.Repository == repository {
+			runs = append(runs, run)
+		}
+	}
+
+	return runs, nil
+}
+
+// LoadSyncFreshness returns the freshness of each synced repository
+// resource, ordered by repository and resource.
+func (s *MemoryStore) LoadSyncFreshness() ([]SyncFreshness, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byResource := map[[2]string]*SyncFreshness{}
+	freshness := []*SyncFreshness{}
+	for _, run := range s.newestRuns() {
+		key := [2]string{run.Repository, run.Resource}
+		f, ok := byResource[key]
+		if !ok {
+			f = &SyncFreshness{
+				Repository: run.Repository,
+				Resource:   run.Resource,
+				LastRun:    run.StartedAt,
+				LastError:  run.Error,
+			}
+			byResource[key] = f
+			freshness = append(freshness, f)
+		}
+
+		switch {
+		case f.LastSuccess != nil:
+		case run.Failed():
+			f.Failures++
+		default:
+			startedAt := run.StartedAt
+			f.LastSuccess = &startedAt
+		}
+	}
+
+	sort.Slice(freshness, func(i, j int) bool {
+		if freshness[i].Repository != freshness[j].Repository {
+			return freshness[i].Repository < freshness[j].Repository
+		}
+		return freshness[i].Resource < freshness[j].Resource
+	})
+
+	out := []SyncFreshness{}
+	for _, f := range freshness {
+		out = append(out, *f)
+	}
+
+	return out, nil
+}
+
+// newestRuns returns the sync runs, newest first.
+func (s *MemoryStore) newestRuns() []SyncRun {
+	runs := append([]SyncRun{}, s.syncRuns...)
+	sort.SliceStable(runs, func(i, j int) bool {
+		if !runs[i].StartedAt.Equal(runs[j].StartedAt) {
+			return runs[i].StartedAt.After(runs[j].StartedAt)
+		}
+		return runs[i].ID > runs[j].ID
+	})
+
+	return runs
+}
+
+// SaveReferences replaces the references parsed from each source.
+func (s *MemoryStore) SaveReferences(sources []ReferenceSource) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, source := range sources {
+		key := refSourceKey{source.Repository, source.Number, source.CommentID}
+		delete(s.refs, key)
+		for _, ref := range source.References {
+			ref.Repository, ref.Number, ref.CommentID = source.Repository, source.Number, source.CommentID
+			s.refs[key] = append(s.refs[key], ref)
+		}
+	}
+
+	return nil
+}
+
+// LoadFixes returns the pull requests which fix issues in a repository,
+// ordered by issue and pull request number.
+func (s *MemoryStore) LoadFixes(repository string) ([]Fix, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byKey := map[[2]issueKey]*Fix{}
+	fixes := []*Fix{}
+	for _, refs := range s.refs {
+		for _, ref := range refs {
+			if ref.TargetRepository != repository || ref.Kind != RefFixes {
+				continue
+			}
+
+			pr, ok := s.issues[issueKey{ref.Repository, ref.Number}]
+			if !ok || !pr.PullRequest {
+				continue
+			}
+
+			key := [2]issueKey{{ref.TargetRepository, ref.TargetNumber}, {pr.Repository, pr.Number}}
+			if _, ok := byKey[key]; ok {
+				continue
+			}
+
+			fix := &Fix{Repository: ref.TargetRepository, Number: ref.TargetNumber, PullRequest: pr}
+			byKey[key] = fix
+			fixes = append(fixes, fix)
+		}
+	}
+
+	for _, event := range s.events {
+		if event.Event != "merged" {
+			continue
+		}
+		for _, fix := range fixes {
+			if fix.PullRequest.Repository == event.Repository && fix.PullRequest.Number == event.IssueNumber {
+				fix.MergedAt = latest(fix.MergedAt, event.CreatedAt)
+			}
+		}
+	}
+
+	sort.Slice(fixes, func(i, j int) bool {
+		a, b := fixes[i], fixes[j]
+		switch {
+		case a.Number != b.Number:
+			return a.Number < b.Number
+		case a.PullRequest.Repository != b.PullRequest.Repository:
+			return a.PullRequest.Repository < b.PullRequest.Repository
+		}
+		return a.PullRequest.Number < b.PullRequest.Number
+	})
+
+	loaded := []Fix{}
+	for _, fix := range fixes {
+		loaded = append(loaded, *fix)
+	}
+
+	return loaded, nil
+}
+
+// QueryIssues returns a page of issues matching a query, along with the total
+// number of matching issues.
+func (s *MemoryStore) QueryIssues(q IssueQuery) ([]Issue, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issues := []Issue{}
+	for _, issue := range s.issues {
+		issues = append(issues, issue)
+	}
+
+	return q.page(issues)
+}
+
+// Milestones summarizes the milestones in a repository.
+func (s *MemoryStore) Milestones(repository string) ([]MilestoneSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byName := map[string]*MilestoneSummary{}
+	milestones := []MilestoneSummary{}
+	for _, issue := range s.selectIssues(repository, func(i Issue) bool { return i.Milestone != "" }) {
+		m, ok := byName[issue.Milestone]
+		if !ok {
+			m = &MilestoneSummary{Milestone: issue.Milestone}
+			byName[issue.Milestone] = m
+		}
+		switch issue.State {
+		case "open":
+			m.Open++
+		case "closed":
+			m.Closed++
+		}
+	}
+	for _, m := range byName {
+		milestones = append(milestones, *m)
+	}
+	sort.Slice(milestones, func(i, j int) bool { return milestones[i].Milestone < milestones[j].Milestone })
+
+	return milestones, nil
+}
+
+// LoadIssue returns an issue by number, or nil if it doesn't exist.
+func (s *MemoryStore) LoadIssue(repository string, number int) (*Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, ok := s.issues[issueKey{repository, number}]
+	if !ok {
+		return nil, nil
+	}
+
+	return &issue, nil
+}
+
+// LoadIssueEvents returns the events for an issue, oldest first.
+func (s *MemoryStore) LoadIssueEvents(repository string, number int) ([]IssueEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := []IssueEvent{}
+	for _, event := range s.events {
+		if event.Repository == repository && event.IssueNumber == number {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return sortsBefore(events[i].CreatedAt, events[i].ID, events[j].CreatedAt, events[j].ID)
+	})
+
+	return events, nil
+}
+
+// RecentIssues returns the issues, not pull requests, created in a repository
+// since a time, newest first.
+func (s *MemoryStore) RecentIssues(repository string, since time.Time) ([]Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issues := s.selectIssues(repository, func(i Issue) bool {
+		return !i.PullRequest && i.CreatedAt != nil && !i.CreatedAt.Before(since)
+	})
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].CreatedAt.After(*issues[j].CreatedAt) })
+
+	return issues, nil
+}
+
+// FindDigestDelivery returns the delivery of a digest for a period to a list,
+// or nil if the digest wasn't sent or pending.
+func (s *MemoryStore) FindDigestDelivery(repository, list string, from, to time.Time) (*DigestDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.findDigest(repository, list, from, to); i >= 0 {
+		d := s.digests[i]
+		return &d, nil
+	}
+
+	return nil, nil
+}
+
+// LoadDigestDelivery returns a sent digest by id, or nil if it doesn't exist.
+func (s *MemoryStore) LoadDigestDelivery(id int) (*DigestDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.digests {
+		if d.ID == id && d.Status == DeliverySent {
+			return &d, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// LoadDigestDeliveries returns a page of the digests sent for a repository,
+// newest first, along with the total number sent.
+func (s *MemoryStore) LoadDigestDeliveries(repository, list string, limit, offset int) ([]DigestDelivery, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries := []DigestDelivery{}
+	for _, d := range s.digests {
+		if d.Repository == repository && (list == "" || d.List == list) && d.Status == DeliverySent {
+			deliveries = append(deliveries, d)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		a, b := deliveries[i], deliveries[j]
+		if !a.From.Equal(b.From) {
+			return a.From.After(b.From)
+		}
+		return a.List < b.List
+	})
+
+	total := len(deliveries)
+	if offset > total {
+		offset = total
+	}
+	deliveries = deliveries[offset:]
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+
+	return deliveries, total, nil
+}
+
+// RecordDigestDelivery saves a digest delivery, replacing an earlier delivery
+// for the same period and list.
+func (s *MemoryStore) RecordDigestDelivery(d *DigestDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := *d
+	saved.From, saved.To = truncateDay(d.From), truncateDay(d.To)
+	saved.Status = deliveryStatus(d)
+
+	if i := s.findDigest(d.Repository, d.List, d.From, d.To); i >= 0 {
+		saved.ID = s.digests[i].ID
+		s.digests[i] = saved
+		return nil
+	}
+
+	s.nextID++
+	saved.ID = s.nextID
+	s.digests = append(s.digests, saved)
+	return nil
+}
+
+// DeleteDigestDelivery deletes the delivery of a digest for a period to a
+// list.
+func (s *MemoryStore) DeleteDigestDelivery(repository, list string, from, to time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.findDigest(repository, list, from, to); i >= 0 {
+		s.digests = append(s.digests[:i], s.digests[i+1:]...)
+	}
+
+	return nil
+}
+
+// findDigest returns the index of the delivery of a digest, comparing periods
+// by day, or -1.
+func (s *MemoryStore) findDigest(repository, list string, from, to time.Time) int {
+	for i, d := range s.digests {
+		if d.Repository == repository && d.List == list && d.From.Equal(truncateDay(from)) && d.To.Equal(truncateDay(to)) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// AlertSent returns true if an alert for an issue was already sent to a route.
+func (s *MemoryStore) AlertSent(repository string, number int, alert, route string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.alerts[alertKey{repository, number, alert, route}]
+	return ok, nil
+}
+
+// RecordAlert records that an alert for an issue was sent to a route. An
+// alert already recorded keeps its first send time.
+func (s *MemoryStore) RecordAlert(repository string, number int, alert, route string, sentAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := alertKey{repository, number, alert, route}
+	if _, ok := s.alerts[key]; !ok {
+		s.alerts[key] = sentAt
+	}
+
+	return nil
+}
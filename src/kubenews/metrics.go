@@ -0,0 +1,333 @@
+package kubenews
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Groupings for backlog metrics.
+const (
+	GroupByRepository = "repository"
+	GroupBySIG        = "sig"
+	GroupByKind       = "kind"
+)
+
+// noKind is the kind used for issues without a kind label.
+const noKind = "none"
+
+// MetricsOptions are options for computing backlog metrics.
+type MetricsOptions struct {
+	From     time.Time
+	To       time.Time
+	GroupBy  string
+	SIGs     *SIGMap
+	Taxonomy *Taxonomy
+	// Bots are the accounts whose comments aren't a response. If nil,
+	// DefaultBots are used.
+	Bots Bots
+}
+
+// MetricsReport is a set of backlog metrics for issues created in a time range.
+type MetricsReport struct {
+	From    time.Time    `json:"from"`
+	To      time.Time    `json:"to"`
+	GroupBy string       `json:"group_by"`
+	Rows    []MetricsRow `json:"rows"`
+}
+
+// MetricsRow are the metrics for a group of issues.
+type MetricsRow struct {
+	Group         string        `json:"group"`
+	Issues        int           `json:"issues"`
+	FirstResponse DurationStats `json:"first_response"`
+	Triage        DurationStats `json:"triage"`
+	Close         DurationStats `json:"close"`
+}
+
+// DurationStats summarizes a set of durations.
+type DurationStats struct {
+	Count  int           `json:"count"`
+	Median time.Duration `json:"median"`
+	P90    time.Duration `json:"p90"`
+}
+
+// NewDurationStats computes the median and 90th percentile of durations.
+func NewDurationStats(durations []time.Duration) DurationStats {
+	stats := DurationStats{Count: len(durations)}
+	if len(durations) == 0 {
+		return stats
+	}
+
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	stats.Median = percentile(sorted, 50)
+	stats.P90 = percentile(sorted, 90)
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// issueTimings are the durations measured for an issue.
+type issueTimings struct {
+	firstResponse *time.Duration
+	triage        *time.Duration
+	close         *time.Duration
+}
+
+// FirstResponse returns the time from an issue being created to its first
+// comment by someone other than the author or a bot.
+func FirstResponse(issue Issue, comments []Comment, bots Bots) (time.Duration, bool) {
+	if issue.CreatedAt == nil {
+		return 0, false
+	}
+
+	for _, c := range comments {
+		if c.User == issue.User || bots.IsBot(c.User) || c.CreatedAt == nil {
+			continue
+		}
+
+		return c.CreatedAt.Sub(*issue.CreatedAt), true
+	}
+
+	return 0, false
+}
+
+// TimeToTriage returns the time from an issue being created to a SIG label
+// being applied.
+func TimeToTriage(issue Issue, events []IssueEvent, sigs *SIGMap) (time.Duration, bool) {
+	if issue.CreatedAt == nil {
+		return 0, false
+	}
+
+	for _, e := range events {
+		if e.Event != "labeled" || e.CreatedAt == nil {
+			continue
+		}
+
+		if _, ok := sigs.Lookup(e.Label); ok {
+			return e.CreatedAt.Sub(*issue.CreatedAt), true
+		}
+	}
+
+	return 0, false
+}
+
+// TimeToClose returns the time from an issue being created to being closed.
+func TimeToClose(issue Issue) (time.Duration, bool) {
+	if issue.CreatedAt == nil || issue.ClosedAt == nil {
+		return 0, false
+	}
+
+	return issue.ClosedAt.Sub(*issue.CreatedAt), true
+}
+
+// ComputeMetrics computes backlog metrics for issues in histories.
+func ComputeMetrics(histories []*History, opts MetricsOptions) (*MetricsReport, error) {
+	sigs := opts.SIGs.orDefault()
+
+	taxonomy := opts.Taxonomy
+	if taxonomy == nil {
+		taxonomy = NewTaxonomy(nil)
+	}
+
+	bots := opts.Bots
+	if bots == nil {
+		bots = NewBots(nil)
+	}
+
+	var groupsFor func(Issue) []string
+	switch opts.GroupBy {
+	case GroupByRepository, "":
+		groupsFor = func(issue Issue) []string { return []string{issue.Repository} }
+	case GroupBySIG:
+		groupsFor = sigs.SIGs
+	case GroupByKind:
+		groupsFor = func(issue Issue) []string {
+			kinds := taxonomy.Classify(issue).Kinds()
+			if len(kinds) == 0 {
+				return []string{noKind}
+			}
+			return kinds
+		}
+	default:
+		return nil, errors.Errorf("unknown metrics grouping %q", opts.GroupBy)
+	}
+
+	groups := map[string][]issueTimings{}
+	for _, h := range histories {
+		for _, issue := range h.Issues {
+			t := issueTimings{}
+			if d, ok := FirstResponse(issue, h.Comments[issue.Number], bots); ok {
+				t.firstResponse = &d
+			}
+			if d, ok := TimeToTriage(issue, h.Events[issue.Number], sigs); ok {
+				t.triage = &d
+			}
+			if d, ok := TimeToClose(issue); ok {
+				t.close = &d
+			}
+
+			for _, group := range groupsFor(issue) {
+				groups[group] = append(groups[group], t)
+			}
+		}
+	}
+
+	report := &MetricsReport{
+		From:    opts.From,
+		To:      opts.To,
+		GroupBy: opts.GroupBy,
+		Rows:    []MetricsRow{},
+	}
+	if report.GroupBy == "" {
+		report.GroupBy = GroupByRepository
+	}
+
+	names := []string{}
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var firstResponse, triage, closing []time.Duration
+		for _, t := range groups[name] {
+			if t.firstResponse != nil {
+				firstResponse = append(firstResponse, *t.firstResponse)
+			}
+			if t.triage != nil {
+				triage = append(triage, *t.triage)
+			}
+			if t.close != nil {
+				closing = append(closing, *t.close)
+			}
+		}
+
+		report.Rows = append(report.Rows, MetricsRow{
+			Group:         name,
+			Issues:        len(groups[name]),
+			FirstResponse: NewDurationStats(firstResponse),
+			Triage:        NewDurationStats(triage),
+			Close:         NewDurationStats(closing),
+		})
+	}
+
+	return report, nil
+}
+
+// Write writes the report in a format: table, json or csv.
+func (r *MetricsReport) Write(w io.Writer, format string) error {
+	switch format {
+	case "table", "":
+		return r.WriteTable(w)
+	case "json":
+		return r.WriteJSON(w)
+	case "csv":
+		return r.WriteCSV(w)
+	default:
+		return errors.Errorf("unknown format %q", format)
+	}
+}
+
+var metricsColumns = []string{
+	"issues",
+	"first_response_median", "first_response_p90",
+	"triage_median", "triage_p90",
+	"close_median", "close_p90",
+}
+
+// WriteTable writes the report as an aligned text table.
+func (r *MetricsReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tISSUES\tRESPONSE MEDIAN\tRESPONSE P90\tTRIAGE MEDIAN\tTRIAGE P90\tCLOSE MEDIAN\tCLOSE P90\n",
+		strings.ToUpper(r.GroupBy))
+	for _, row := range r.Rows {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", row.Group, row.Issues,
+			formatStat(row.FirstResponse, row.FirstResponse.Median), formatStat(row.FirstResponse, row.FirstResponse.P90),
+			formatStat(row.Triage, row.Triage.Median), formatStat(row.Triage, row.Triage.P90),
+			formatStat(row.Close, row.Close.Median), formatStat(row.Close, row.Close.P90))
+	}
+
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as JSON. Durations are in nanoseconds.
+func (r *MetricsReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes the report as CSV. Durations are in hours.
+func (r *MetricsReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{r.GroupBy}, metricsColumns...)); err != nil {
+		return err
+	}
+
+	for _, row := range r.Rows {
+		record := []string{
+			row.Group,
+			strconv.Itoa(row.Issues),
+			csvHours(row.FirstResponse, row.FirstResponse.Median), csvHours(row.FirstResponse, row.FirstResponse.P90),
+			csvHours(row.Triage, row.Triage.Median), csvHours(row.Triage, row.Triage.P90),
+			csvHours(row.Close, row.Close.Median), csvHours(row.Close, row.Close.P90),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatStat(stats DurationStats, d time.Duration) string {
+	if stats.Count == 0 {
+		return "-"
+	}
+
+	return FormatDuration(d)
+}
+
+func csvHours(stats DurationStats, d time.Duration) string {
+	if stats.Count == 0 {
+		return ""
+	}
+
+	return strconv.FormatFloat(d.Hours(), 'f', 1, 64)
+}
+
+// FormatDuration formats a duration in days and hours, e.g. 3d4h.
+func FormatDuration(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
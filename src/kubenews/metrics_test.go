@@ -0,0 +1,77 @@
+package kubenews
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDurationStats(t *testing.T) {
+	durations := []time.Duration{}
+	for i := 10; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Hour)
+	}
+
+	stats := NewDurationStats(durations)
+	require.Equal(t, 10, stats.Count)
+	require.Equal(t, 5*time.Hour, stats.Median)
+	require.Equal(t, 9*time.Hour, stats.P90)
+
+	require.Equal(t, DurationStats{}, NewDurationStats(nil))
+}
+
+func TestComputeMetrics(t *testing.T) {
+	created := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := created.Add(d)
+		return &t
+	}
+
+	bug := labeledIssue(1, "kind/bug", "sig/node")
+	bug.User = "author"
+	bug.CreatedAt = at(0)
+	bug.ClosedAt = at(48 * time.Hour)
+
+	feature := labeledIssue(2, "kind/feature")
+	feature.User = "author"
+	feature.CreatedAt = at(0)
+
+	comments := []Comment{
+		{IssueNumber: 1, User: "author", CreatedAt: at(time.Hour)},
+		{IssueNumber: 1, User: "k8s-ci-robot", CreatedAt: at(2 * time.Hour)},
+		{IssueNumber: 1, User: "reviewer", CreatedAt: at(3 * time.Hour)},
+		{IssueNumber: 2, User: "author", CreatedAt: at(time.Hour)},
+	}
+
+	events := []IssueEvent{
+		{IssueNumber: 1, Event: "labeled", Label: "kind/bug", CreatedAt: at(time.Hour)},
+		{IssueNumber: 1, Event: "labeled", Label: "sig/node", CreatedAt: at(5 * time.Hour)},
+	}
+
+	h := NewHistory([]Issue{bug, feature}, comments, events)
+
+	report, err := ComputeMetrics([]*History{h}, MetricsOptions{GroupBy: GroupByKind})
+	require.NoError(t, err)
+	require.Len(t, report.Rows, 2)
+
+	row := report.Rows[0]
+	require.Equal(t, "bug", row.Group)
+	require.Equal(t, 1, row.Issues)
+	require.Equal(t, 3*time.Hour, row.FirstResponse.Median)
+	require.Equal(t, 5*time.Hour, row.Triage.Median)
+	require.Equal(t, 48*time.Hour, row.Close.P90)
+
+	row = report.Rows[1]
+	require.Equal(t, "feature", row.Group)
+	require.Equal(t, 0, row.FirstResponse.Count)
+	require.Equal(t, 0, row.Close.Count)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf, "csv"))
+	require.Contains(t, buf.String(), "bug,1,3.0,3.0,5.0,5.0,48.0,48.0")
+
+	_, err = ComputeMetrics([]*History{h}, MetricsOptions{GroupBy: "color"})
+	require.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package kubenews
+
+import (
+	"net/http"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// MetricsHandler serves the metrics in DefaultRegistry. If Store is set, the
+// last successful sync of each repository resource is loaded from it on each
+// scrape, so syncs by other processes are included.
+type MetricsHandler struct {
+	Store Store
+}
+
+func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Store != nil {
+		freshness, err := h.Store.LoadSyncFreshness()
+		if err != nil {
+			log.WithError(err).Error("unable to load sync freshness")
+			http.Error(w, "unable to load sync freshness", http.StatusInternalServerError)
+			return
+		}
+		ObserveSyncFreshness(freshness)
+	}
+
+	promhttp.HandlerFor(DefaultRegistry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// PushMetrics pushes the metrics in DefaultRegistry to a Prometheus
+// pushgateway under a job. Metrics are POSTed, so those not in this push,
+// e.g. the time of the last successful sync after a failed one, keep their
+// previous value.
+func PushMetrics(client *http.Client, gatewayURL, job string) error {
+	err := push.New(gatewayURL, job).Gatherer(DefaultRegistry).Client(client).Add()
+	return errors.Wrap(err, "push metrics")
+}
+
+// DefaultRegistry holds the metrics kubenews exports.
+var DefaultRegistry = prometheus.NewRegistry()
+
+var (
+	githubRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubenews_github_requests_total",
+		Help: "Github API requests by response status, or error if there was no response.",
+	}, []string{"status"})
+	githubRateRemaining = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kubenews_github_rate_limit_remaining",
+		Help: "Github API requests remaining in the current rate limit window.",
+	})
+
+	syncRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubenews_sync_runs_total",
+		Help: "Syncs of a repository resource by outcome: success or failure.",
+	}, []string{"repository", "resource", "outcome"})
+	syncPages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubenews_sync_pages_total",
+		Help: "Pages fetched from the Github API by syncs.",
+	}, []string{"repository", "resource"})
+	syncRecords = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubenews_sync_records_total",
+		Help: "Records saved by syncs by operation: inserted or updated.",
+	}, []string{"repository", "resource", "operation"})
+	syncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kubenews_sync_duration_seconds",
+		Help:    "Duration of syncs of a repository resource.",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800},
+	}, []string{"repository", "resource"})
+	syncLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubenews_sync_last_success_timestamp_seconds",
+		Help: "Unix time the last successful sync of a repository resource started.",
+	}, []string{"repository", "resource"})
+
+	digestPublishes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubenews_digest_publish_total",
+		Help: "Digests published by channel, email or webhook, and outcome: sent, skipped or failed.",
+	}, []string{"repository", "channel", "outcome"})
+)
+
+func init() {
+	DefaultRegistry.MustRegister(githubRequests, githubRateRemaining, syncRuns, syncPages, syncRecords,
+		syncDuration, syncLastSuccess, digestPublishes)
+}
+
+// observeGithubResponse records the status of a Github API request and the
+// rate limit remaining.
+func observeGithubResponse(resp *github.Response) {
+	if resp == nil || resp.Response == nil {
+		githubRequests.WithLabelValues("error").Inc()
+		return
+	}
+
+	githubRequests.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
+	if resp.Header.Get("X-RateLimit-Remaining") != "" {
+		githubRateRemaining.Set(float64(resp.Rate.Remaining))
+	}
+}
+
+// observeSyncRun records a sync run.
+func observeSyncRun(run SyncRun) {
+	outcome := "success"
+	if run.Failed() {
+		outcome = "failure"
+	}
+
+	syncRuns.WithLabelValues(run.Repository, run.Resource, outcome).Inc()
+	syncPages.WithLabelValues(run.Repository, run.Resource).Add(float64(run.Pages))
+	syncRecords.WithLabelValues(run.Repository, run.Resource, "inserted").Add(float64(run.Inserted))
+	syncRecords.WithLabelValues(run.Repository, run.Resource, "updated").Add(float64(run.Updated))
+	syncDuration.WithLabelValues(run.Repository, run.Resource).Observe(run.FinishedAt.Sub(run.StartedAt).Seconds())
+	if !run.Failed() {
+		syncLastSuccess.WithLabelValues(run.Repository, run.Resource).Set(float64(run.StartedAt.UnixNano()) / 1e9)
+	}
+}
+
+// ObserveSyncFreshness records the last successful sync of repository
+// resources.
+func ObserveSyncFreshness(freshness []SyncFreshness) {
+	for _, f := range freshness {
+		if f.LastSuccess != nil {
+			syncLastSuccess.WithLabelValues(f.Repository, f.Resource).Set(float64(f.LastSuccess.UnixNano()) / 1e9)
+		}
+	}
+}
+
+// Digest publish outcomes.
+const (
+	DigestSent    = "sent"
+	DigestSkipped = "skipped"
+	DigestFailed  = "failed"
+)
+
+// ObserveDigestPublish records the outcome of publishing a digest of a
+// repository to a channel, email or webhook.
+func ObserveDigestPublish(repository, channel, outcome string) {
+	digestPublishes.WithLabelValues(repository, channel, outcome).Inc()
+}
@@ -0,0 +1,81 @@
+package kubenews
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushMetrics(t *testing.T) {
+	var method, path, body string
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(b)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	githubRateRemaining.Set(10)
+
+	require.NoError(t, PushMetrics(server.Client(), server.URL+"/", "kubenews_update"))
+	require.Equal(t, http.MethodPost, method)
+	require.Equal(t, "/metrics/job/kubenews_update", path)
+	require.Contains(t, body, "kubenews_github_rate_limit_remaining")
+
+	status = http.StatusBadRequest
+	require.Error(t, PushMetrics(server.Client(), server.URL, "kubenews_update"))
+}
+
+func TestSyncMetrics(t *testing.T) {
+	f, gh := newTestGithub(t)
+	s := NewMemoryStore()
+
+	before := scrape(t, &MetricsHandler{})
+
+	f.fail(fakeIssuesPath, fakeFailure{status: http.StatusBadGateway, page: 2})
+	require.NoError(t, Sync(gh, s, "org/repo"))
+
+	started := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, s.SaveSyncRun(SyncRun{Repository: "other/repo", Resource: "issues",
+		StartedAt: started, FinishedAt: started}))
+
+	after := scrape(t, &MetricsHandler{Store: s})
+
+	// metrics are global, so other tests' syncs are counted too
+	delta := func(series string) float64 { return after[series] - before[series] }
+	require.Equal(t, 1.0, delta(`kubenews_sync_runs_total{outcome="success",repository="org/repo",resource="issues"}`))
+	require.Equal(t, 3.0, delta(`kubenews_sync_pages_total{repository="org/repo",resource="issues"}`))
+	require.Equal(t, 5.0, delta(`kubenews_sync_records_total{operation="inserted",repository="org/repo",resource="issues"}`))
+	require.Equal(t, 1.0, delta(`kubenews_sync_duration_seconds_count{repository="org/repo",resource="events"}`))
+	require.Equal(t, 1.0, delta(`kubenews_github_requests_total{status="502"}`))
+	require.Equal(t, 1.4883264e+09, after[`kubenews_sync_last_success_timestamp_seconds{repository="other/repo",resource="issues"}`])
+	require.Contains(t, after, "kubenews_github_rate_limit_remaining")
+}
+
+// scrape returns the value of each series a metrics handler serves.
+func scrape(t *testing.T, h http.Handler) map[string]float64 {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	values := map[string]float64{}
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		i := strings.LastIndex(line, " ")
+		if line == "" || strings.HasPrefix(line, "#") || i < 0 {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		require.NoError(t, err)
+		values[line[:i]] = v
+	}
+
+	return values
+}
@@ -0,0 +1,444 @@
+package kubenews
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Query is an issue filter written in a syntax modelled on Github search, e.g.
+// "repo:kubernetes/kubernetes is:pr is:open label:sig/node
+// -label:lifecycle/frozen updated:<2017-01-01 assignee:foo".
+//
+// Terms are separated by spaces and all terms must match. A term prefixed with
+// - must not match. Values containing spaces can be quoted. Words without a
+// qualifier match the title or body.
+//
+// Qualifiers are repo, is (open, closed, pr, issue), state, label, sig,
+// author, assignee, milestone, no (label, assignee, milestone) and the dates
+// created, updated and closed. label and sig take a comma separated list of
+// which any may match. Dates compare with >, >=, <, <= or a range a..b, and a
+// plain date matches that day.
+type Query struct {
+	terms []queryTerm
+}
+
+type queryTerm struct {
+	qualifier string
+	negated   bool
+	// values are the alternatives for label and sig, otherwise a single value.
+	values []string
+	// op, from and to are set for date qualifiers. A range uses from and to.
+	op   string
+	from time.Time
+	to   time.Time
+}
+
+// queryDateColumns maps date qualifiers to columns.
+var queryDateColumns = map[string]string{
+	"created": "created_at",
+	"updated": "updated_at",
+	"closed":  "closed_at",
+}
+
+// queryTextColumns maps text qualifiers to columns.
+var queryTextColumns = map[string]string{
+	"repo":      "repository",
+	"state":     "state",
+	"author":    "created_by",
+	"assignee":  "assignee",
+	"milestone": "milestone",
+}
+
+// ParseQuery parses a query. sig qualifiers are resolved with sigs.
+func ParseQuery(expr string, sigs *SIGMap) (*Query, error) {
+	sigs = sigs.orDefault()
+
+	fields, err := splitQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Query{}
+	for _, field := range fields {
+		term, err := parseQueryTerm(field, sigs)
+		if err != nil {
+			return nil, err
+		}
+
+		q.terms = append(q.terms, term)
+	}
+
+	return q, nil
+}
+
+// splitQuery splits a query on spaces outside of double quotes. Quotes are
+// removed.
+func splitQuery(expr string) ([]string, error) {
+	fields := []string{}
+	var field strings.Builder
+	quoted, inField := false, false
+
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inField = true
+		case unicode.IsSpace(r) && !quoted:
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+
+	if quoted {
+		return nil, errors.Errorf("unterminated quote in query %q", expr)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+
+	return fields, nil
+}
+
+func parseQueryTerm(field string, sigs *SIGMap) (queryTerm, error) {
+	term := queryTerm{}
+	if strings.HasPrefix(field, "-") && len(field) > 1 {
+		term.negated = true
+		field = field[1:]
+	}
+
+	i := strings.Index(field, ":")
+	if i < 0 {
+		term.values = []string{field}
+		return term, nil
+	}
+
+	term.qualifier, term.values = strings.ToLower(field[:i]), []string{field[i+1:]}
+	value := term.values[0]
+	if value == "" {
+		return term, errors.Errorf("missing value in %q", field)
+	}
+
+	switch term.qualifier {
+	case "is":
+		switch value {
+		case "open", "closed", "pr", "issue":
+		default:
+			return term, errors.Errorf("unknown value in %q, expected open, closed, pr or issue", field)
+		}
+	case "no":
+		switch value {
+		case "label", "assignee", "milestone":
+		default:
+			return term, errors.Errorf("unknown value in %q, expected label, assignee or milestone", field)
+		}
+	case "state":
+		if value != "open" && value != "closed" {
+			return term, errors.Errorf("unknown value in %q, expected open or closed", field)
+		}
+	case "label", "sig":
+		names := splitList(value)
+		if len(names) == 0 {
+			return term, errors.Errorf("missing value in %q", field)
+		}
+		if term.qualifier == "label" {
+			term.values = names
+			break
+		}
+
+		term.values = []string{}
+		for _, name := range names {
+			term.values = append(term.values, sigs.Labels(name)...)
+		}
+		// Labels are matched rather than SIGs, so issues match the same way
+		// in SQL and in memory.
+		term.qualifier = "label"
+	case "created", "updated", "closed":
+		if err := term.parseDate(value); err != nil {
+			return term, errors.Wrapf(err, "invalid date in %q", field)
+		}
+	default:
+		if _, ok := queryTextColumns[term.qualifier]; !ok {
+			return term, errors.Errorf("unknown qualifier %q", term.qualifier)
+		}
+	}
+
+	return term, nil
+}
+
+func splitList(s string) []string {
+	values := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// parseDate parses a date comparison, range or day.
+func (t *queryTerm) parseDate(s string) error {
+	if i := strings.Index(s, ".."); i >= 0 {
+		from, err := parseQueryDate(s[:i])
+		if err != nil {
+			return err
+		}
+		to, err := parseQueryDate(s[i+2:])
+		if err != nil {
+			return err
+		}
+
+		t.op, t.from, t.to = "..", from, to.AddDate(0, 0, 1)
+		return nil
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(s, op) {
+			d, err := parseQueryDate(s[len(op):])
+			if err != nil {
+				return err
+			}
+
+			// Comparisons after a day start at the end of the day.
+			if op == ">" || op == "<=" {
+				d = d.AddDate(0, 0, 1)
+				op = map[string]string{">": ">=", "<=": "<"}[op]
+			}
+
+			t.op, t.from = op, d
+			return nil
+		}
+	}
+
+	d, err := parseQueryDate(s)
+	if err != nil {
+		return err
+	}
+
+	t.op, t.from, t.to = "..", d, d.AddDate(0, 0, 1)
+	return nil
+}
+
+func parseQueryDate(s string) (time.Time, error) {
+	return time.Parse("2006-01-02", s)
+}
+
+// Words returns the unqualified words in the query.
+func (q *Query) Words() []string {
+	words := []string{}
+	for _, term := range q.terms {
+		if term.qualifier == "" && !term.negated {
+			words = append(words, term.values[0])
+		}
+	}
+
+	return words
+}
+
+// WithoutWords returns the query without its unqualified words, for when the
+// words are matched separately, e.g. by a full-text search.
+func (q *Query) WithoutWords() *Query {
+	out := &Query{}
+	for _, term := range q.terms {
+		if term.qualifier != "" || term.negated {
+			out.terms = append(out.terms, term)
+		}
+	}
+
+	return out
+}
+
+// Match returns true if the issue matches all terms in the query. A nil query
+// matches every issue.
+func (q *Query) Match(issue Issue) bool {
+	if q == nil {
+		return true
+	}
+
+	for _, term := range q.terms {
+		if term.match(issue) == term.negated {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t queryTerm) match(issue Issue) bool {
+	value := t.values[0]
+
+	switch t.qualifier {
+	case "":
+		word := strings.ToLower(value)
+		return strings.Contains(strings.ToLower(issue.Title), word) ||
+			strings.Contains(strings.ToLower(issue.Body), word)
+	case "is":
+		switch value {
+		case "pr":
+			return issue.PullRequest
+		case "issue":
+			return !issue.PullRequest
+		default:
+			return issue.State == value
+		}
+	case "no":
+		switch value {
+		case "label":
+			return len(issue.Labels) == 0
+		case "assignee":
+			return issue.Assignee == ""
+		default:
+			return issue.Milestone == ""
+		}
+	case "label":
+		for _, label := range issue.Labels {
+			for _, name := range t.values {
+				if label.Name == name {
+					return true
+				}
+			}
+		}
+		return false
+	case "repo":
+		return issue.Repository == value
+	case "state":
+		return issue.State == value
+	case "author":
+		return issue.User == value
+	case "assignee":
+		return issue.Assignee == value
+	case "milestone":
+		return issue.Milestone == value
+	}
+
+	var at *time.Time
+	switch t.qualifier {
+	case "created":
+		at = issue.CreatedAt
+	case "updated":
+		at = issue.UpdatedAt
+	default:
+		at = issue.ClosedAt
+	}
+
+	if at == nil {
+		return false
+	}
+
+	switch t.op {
+	case ">=":
+		return !at.Before(t.from)
+	case "<":
+		return at.Before(t.from)
+	default:
+		return !at.Before(t.from) && at.Before(t.to)
+	}
+}
+
+// where adds the query's conditions on the issues table to b.
+func (q *Query) where(b *sqlBuilder) {
+	for _, term := range q.terms {
+		cond, args := term.sql()
+		if term.negated {
+			cond = "NOT COALESCE(" + cond + ", false)"
+		}
+
+		b.add(cond, args...)
+	}
+}
+
+func (t queryTerm) sql() (string, []interface{}) {
+	value := t.values[0]
+
+	switch t.qualifier {
+	case "":
+		pattern := "%" + likeEscaper.Replace(value) + "%"
+		return "(title ILIKE ? OR body ILIKE ?)", []interface{}{pattern, pattern}
+	case "is":
+		switch value {
+		case "pr":
+			return "pull_request", nil
+		case "issue":
+			return "NOT pull_request", nil
+		default:
+			return "state = ?", []interface{}{value}
+		}
+	case "no":
+		switch value {
+		case "label":
+			return "labels = '[]'::jsonb", nil
+		default:
+			return value + " = ''", nil
+		}
+	case "label":
+		conds := []string{}
+		args := []interface{}{}
+		for _, name := range t.values {
+			b, _ := json.Marshal([]map[string]string{{"Name": name}})
+			conds = append(conds, "labels @> ?::jsonb")
+			args = append(args, string(b))
+		}
+		return "(" + strings.Join(conds, " OR ") + ")", args
+	}
+
+	if column, ok := queryTextColumns[t.qualifier]; ok {
+		return column + " = ?", []interface{}{value}
+	}
+
+	column := queryDateColumns[t.qualifier]
+	switch t.op {
+	case ">=":
+		return column + " >= ?", []interface{}{t.from}
+	case "<":
+		return column + " < ?", []interface{}{t.from}
+	default:
+		return "(" + column + " >= ? AND " + column + " < ?)", []interface{}{t.from, t.to}
+	}
+}
+
+// AllOf returns a filter matching issues which match every filter. Nil filters
+// are ignored.
+func AllOf(filters ...IssueFilter) IssueFilter {
+	return allFilter(filters)
+}
+
+type allFilter []IssueFilter
+
+func (f allFilter) Match(issue Issue) bool {
+	for _, filter := range f {
+		if filter != nil && !filter.Match(issue) {
+			return false
+		}
+	}
+
+	return true
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// sqlBuilder collects conditions and their arguments. Conditions are written
+// with ? placeholders, which are numbered as they are added.
+type sqlBuilder struct {
+	conds []string
+	args  []interface{}
+}
+
+func (b *sqlBuilder) add(cond string, args ...interface{}) {
+	for _, arg := range args {
+		b.args = append(b.args, arg)
+		cond = strings.Replace(cond, "?", "$"+strconv.Itoa(len(b.args)), 1)
+	}
+
+	b.conds = append(b.conds, cond)
+}
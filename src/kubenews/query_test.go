@@ -0,0 +1,124 @@
+package kubenews
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// queryWhere returns the condition and arguments a query adds to an issue
+// query's WHERE clause.
+func queryWhere(t *testing.T, q *Query) (string, []interface{}) {
+	where, args, err := IssueQuery{Filter: q}.where()
+	require.NoError(t, err)
+	return strings.TrimPrefix(where, " WHERE "), args
+}
+
+func TestParseQuery(t *testing.T) {
+	sigs := NewSIGMap("sig/", []SIG{{Name: "node", Aliases: []string{"sig/kubelet"}}})
+
+	q, err := ParseQuery(`repo:org/repo is:pr is:open sig:node -label:lifecycle/frozen updated:<2017-01-01 assignee:foo etcd "quorum lost"`, sigs)
+	require.NoError(t, err)
+	require.Equal(t, []string{"etcd", "quorum lost"}, q.Words())
+
+	where, args := queryWhere(t, q)
+	require.Equal(t, "repository = $1 AND pull_request AND state = $2 AND (labels @> $3::jsonb OR labels @> $4::jsonb) AND "+
+		"NOT COALESCE((labels @> $5::jsonb), false) AND updated_at < $6 AND assignee = $7 AND "+
+		"(title ILIKE $8 OR body ILIKE $9) AND (title ILIKE $10 OR body ILIKE $11)", where)
+	require.Equal(t, []interface{}{
+		"org/repo", "open",
+		`[{"Name":"sig/kubelet"}]`, `[{"Name":"sig/node"}]`,
+		`[{"Name":"lifecycle/frozen"}]`,
+		time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC),
+		"foo", "%etcd%", "%etcd%", "%quorum lost%", "%quorum lost%",
+	}, args)
+
+	where, args = queryWhere(t, q.WithoutWords())
+	require.NotContains(t, where, "ILIKE")
+	require.Len(t, args, 7)
+}
+
+func TestParseQueryDates(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2017, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	cases := []struct {
+		expr  string
+		where string
+		args  []interface{}
+	}{
+		{"created:>2017-01-02", "created_at >= $1", []interface{}{day(3)}},
+		{"created:>=2017-01-02", "created_at >= $1", []interface{}{day(2)}},
+		{"closed:<=2017-01-02", "closed_at < $1", []interface{}{day(3)}},
+		{"updated:2017-01-02", "(updated_at >= $1 AND updated_at < $2)", []interface{}{day(2), day(3)}},
+		{"created:2017-01-02..2017-01-04", "(created_at >= $1 AND created_at < $2)", []interface{}{day(2), day(5)}},
+	}
+
+	for _, c := range cases {
+		q, err := ParseQuery(c.expr, nil)
+		require.NoError(t, err, c.expr)
+
+		where, args := queryWhere(t, q)
+		require.Equal(t, c.where, where, c.expr)
+		require.Equal(t, c.args, args, c.expr)
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, expr := range []string{
+		"is:locked",
+		"no:reviewer",
+		"state:merged",
+		"label:",
+		"label:,",
+		"created:yesterday",
+		"reviewer:foo",
+		`"unterminated`,
+	} {
+		_, err := ParseQuery(expr, nil)
+		require.Error(t, err, expr)
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	created := time.Date(2017, 1, 5, 0, 0, 0, 0, time.UTC)
+	issue := Issue{
+		Number:     1,
+		State:      "open",
+		Title:      "etcd lost quorum",
+		User:       "alice",
+		Labels:     Labels{{Name: "sig/node"}, {Name: "kind/bug"}},
+		CreatedAt:  &created,
+		Repository: "org/repo",
+	}
+
+	cases := map[string]bool{
+		"":                               true,
+		"repo:org/repo is:issue is:open": true,
+		"is:pr":                          false,
+		"sig:node,network author:alice":  true,
+		"-label:kind/bug":                false,
+		"label:kind/flake,kind/bug":      true,
+		"no:assignee no:milestone":       true,
+		"no:label":                       false,
+		"created:2017-01-05":             true,
+		"created:<2017-01-05":            false,
+		"-closed:<2017-02-01":            true,
+		"closed:<2017-02-01":             false,
+		"ETCD quorum":                    true,
+		"-quorum":                        false,
+		`milestone:"v1.7 beta"`:          false,
+		"state:closed":                   false,
+		"sig:network created:2017-01-01..2017-01-31": false,
+	}
+
+	for expr, want := range cases {
+		q, err := ParseQuery(expr, nil)
+		require.NoError(t, err, expr)
+		require.Equal(t, want, q.Match(issue), expr)
+	}
+
+	var nilQuery *Query
+	require.True(t, AllOf(nil, nilQuery).Match(issue))
+}
@@ -0,0 +1,149 @@
+package kubenews
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+)
+
+// Issue states set by reconciling, for issues stored as open which Github
+// no longer has in the repository.
+const (
+	IssueDeleted     = "deleted"
+	IssueTransferred = "transferred"
+)
+
+// ReconcileResource is the resource reconciling is recorded as in sync runs.
+const ReconcileResource = "reconcile"
+
+// Reconciliation is what reconciling a repository found.
+type Reconciliation struct {
+	Repository string
+	// Missing are open issues which weren't stored as open, e.g. because a
+	// sync failed part way.
+	Missing []int
+	// Updated are issues stored as open which Github doesn't list as open,
+	// e.g. because a sync missed them being closed.
+	Updated []int
+	// Deleted are issues which Github no longer has.
+	Deleted []int
+	// Transferred are issues which were moved to another repository.
+	Transferred []Transfer
+}
+
+// Transfer is an issue moved to another repository, where it has a new
+// number.
+type Transfer struct {
+	Number     int
+	Repository string
+	NewNumber  int
+}
+
+// Reconcile compares the open issues of a repository on Github with the
+// store, which incremental syncs can't keep right: an issue which is deleted
+// or transferred is never updated again, so it stays open. Missing issues are
+// saved, transferred issues are followed to their new repository, and
+// deleted ones are marked as deleted. It is recorded as a sync run.
+func Reconcile(gh *Github, s Store, repository string) (*Reconciliation, error) {
+	r := &Reconciliation{Repository: repository}
+	_, err := recordSyncRun(gh, s, repository, ReconcileResource, func(gh *Github) (int, Saved, error) {
+		return reconcile(gh, s, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"repo":        repository,
+		"missing":     len(r.Missing),
+		"updated":     len(r.Updated),
+		"deleted":     len(r.Deleted),
+		"transferred": len(r.Transferred),
+	}).Info("reconciled open issues")
+
+	return r, nil
+}
+
+func reconcile(gh *Github, s Store, r *Reconciliation) (int, Saved, error) {
+	saved := Saved{}
+
+	open, err := gh.ListOpenIssues(r.Repository)
+	if err != nil {
+		return 0, saved, errors.Wrap(err, "list open issues")
+	}
+	fetched := len(open)
+
+	stored, err := s.OpenIssues(r.Repository)
+	if err != nil {
+		return fetched, saved, err
+	}
+
+	storedOpen := map[int]bool{}
+	for _, issue := range stored {
+		storedOpen[issue.Number] = true
+	}
+
+	onGithub := map[int]bool{}
+	missing := []Issue{}
+	for _, issue := range open {
+		onGithub[*issue.Number] = true
+		if !storedOpen[*issue.Number] {
+			missing = append(missing, ConvertIssue(r.Repository, issue))
+			r.Missing = append(r.Missing, *issue.Number)
+		}
+	}
+
+	if len(missing) > 0 {
+		n, err := s.SaveIssues(missing)
+		if err != nil {
+			return fetched, saved, errors.Wrap(err, "save missing issues")
+		}
+		saved.add(n)
+	}
+
+	for _, issue := range stored {
+		if onGithub[issue.Number] {
+			continue
+		}
+
+		logger := log.WithFields(log.Fields{"repo": r.Repository, "number": issue.Number})
+
+		current, repository, err := gh.GetIssue(r.Repository, issue.Number)
+		if err == ErrIssueGone {
+			logger.Info("issue was deleted")
+			if err := s.SetIssueState(r.Repository, issue.Number, IssueDeleted); err != nil {
+				return fetched, saved, err
+			}
+			saved.Updated++
+			r.Deleted = append(r.Deleted, issue.Number)
+			continue
+		}
+		if err != nil {
+			return fetched, saved, err
+		}
+		fetched++
+
+		n, err := s.SaveIssues([]Issue{ConvertIssue(repository, *current)})
+		if err != nil {
+			return fetched, saved, errors.Wrap(err, "save issue")
+		}
+		saved.add(n)
+
+		if repository == r.Repository {
+			r.Updated = append(r.Updated, issue.Number)
+			continue
+		}
+
+		logger.WithFields(log.Fields{"newRepo": repository, "newNumber": *current.Number}).Info("issue was transferred")
+		if err := s.SetIssueState(r.Repository, issue.Number, IssueTransferred); err != nil {
+			return fetched, saved, err
+		}
+		saved.Updated++
+		r.Transferred = append(r.Transferred, Transfer{
+			Number:     issue.Number,
+			Repository: repository,
+			NewNumber:  *current.Number,
+		})
+	}
+
+	return fetched, saved, nil
+}
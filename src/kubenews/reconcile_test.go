@@ -0,0 +1,101 @@
+package kubenews
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		f, gh := newTestGithub(t)
+		require.NoError(t, Sync(gh, s, "org/repo"))
+
+		open, err := s.OpenIssues("org/repo")
+		require.NoError(t, err)
+		require.Len(t, open, 3)
+
+		// none of these change what an incremental sync fetches
+		closed := f.remove(1)
+		closed["state"] = "closed"
+		f.add(fakeIssuesPath, closed)
+		f.transfer(4, "org/other", 12)
+		f.remove(5)
+		f.add(fakeIssuesPath, map[string]interface{}{
+			"number":     6,
+			"state":      "open",
+			"title":      "scheduler ignores taints",
+			"user":       map[string]interface{}{"login": "dave"},
+			"labels":     []interface{}{},
+			"created_at": "2017-01-02T10:00:00Z",
+			"updated_at": "2017-01-02T10:00:00Z",
+		})
+
+		r, err := Reconcile(gh, s, "org/repo")
+		require.NoError(t, err)
+		require.Equal(t, &Reconciliation{
+			Repository:  "org/repo",
+			Missing:     []int{6},
+			Updated:     []int{1},
+			Deleted:     []int{5},
+			Transferred: []Transfer{{Number: 4, Repository: "org/other", NewNumber: 12}},
+		}, r)
+
+		open, err = s.OpenIssues("org/repo")
+		require.NoError(t, err)
+		require.Len(t, open, 1)
+		require.Equal(t, 6, open[0].Number)
+
+		moved, err := s.OpenIssues("org/other")
+		require.NoError(t, err)
+		require.Len(t, moved, 1)
+		require.Equal(t, 12, moved[0].Number)
+		require.Equal(t, "e2e flake: [k8s.io] Networking should function for intra-pod communication", moved[0].Title)
+
+		h, err := s.LoadRepositoryHistory("org/repo")
+		require.NoError(t, err)
+		states := map[int]string{}
+		for _, issue := range h.Issues {
+			states[issue.Number] = issue.State
+		}
+		require.Equal(t, map[int]string{1: "closed", 2: "closed", 3: "closed", 4: IssueTransferred,
+			5: IssueDeleted, 6: "open"}, states)
+
+		runs, err := s.LoadSyncRuns("org/repo", 1)
+		require.NoError(t, err)
+		require.Equal(t, ReconcileResource, runs[0].Resource)
+		require.False(t, runs[0].Failed())
+		require.Equal(t, Saved{Inserted: 2, Updated: 3}, Saved{Inserted: runs[0].Inserted, Updated: runs[0].Updated})
+
+		// reconciling again finds nothing
+		r, err = Reconcile(gh, s, "org/repo")
+		require.NoError(t, err)
+		require.Equal(t, &Reconciliation{Repository: "org/repo"}, r)
+	})
+}
+
+func TestReconcileGone(t *testing.T) {
+	f, gh := newTestGithub(t)
+	s := NewMemoryStore()
+	require.NoError(t, Sync(gh, s, "org/repo"))
+
+	// Github responds 410 Gone for deleted issues it can see
+	f.remove(5)
+	f.fail(fakeIssuesPath+"/5", fakeFailure{status: http.StatusGone})
+	f.fail(fakeIssuesPath+"/1", fakeFailure{status: http.StatusInternalServerError},
+		fakeFailure{status: http.StatusInternalServerError}, fakeFailure{status: http.StatusInternalServerError},
+		fakeFailure{status: http.StatusInternalServerError})
+	f.remove(1)
+
+	_, err := Reconcile(gh, s, "org/repo")
+	require.Error(t, err)
+
+	runs, err := s.LoadSyncRuns("org/repo", 1)
+	require.NoError(t, err)
+	require.True(t, runs[0].Failed())
+
+	r, err := Reconcile(gh, s, "org/repo")
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 5}, r.Deleted)
+}
@@ -0,0 +1,273 @@
+package kubenews
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Reference kinds, strongest first.
+const (
+	// RefFixes is a pull request which closes an issue when merged.
+	RefFixes = "fixes"
+	// RefDuplicates is an issue which duplicates another.
+	RefDuplicates = "duplicates"
+	// RefReferences is any other mention.
+	RefReferences = "references"
+)
+
+var refKindRank = map[string]int{RefFixes: 0, RefDuplicates: 1, RefReferences: 2}
+
+// Reference is an edge from an issue or pull request, or one of its comments,
+// to another issue or pull request it mentions.
+//
+// References are parsed from text. The cross-referenced events of the issue
+// timeline aren't included, as the repository events API doesn't list them.
+type Reference struct {
+	Repository string `db:"repository" json:"repository"`
+	Number     int    `db:"number" json:"number"`
+	// CommentID is the comment the reference is in, or 0 if it is in the
+	// body.
+	CommentID        int    `db:"comment_id" json:"comment_id,omitempty"`
+	TargetRepository string `db:"target_repository" json:"target_repository"`
+	TargetNumber     int    `db:"target_number" json:"target_number"`
+	Kind             string `db:"kind" json:"kind"`
+}
+
+// ReferenceSource is the references parsed from the body of an issue or pull
+// request, or from one of its comments. Saving it replaces the references
+// previously parsed from the same text, which may have been edited.
+type ReferenceSource struct {
+	Repository string
+	Number     int
+	CommentID  int
+	References []Reference
+}
+
+var (
+	// refRe matches org/repo#1, #1 and issue or pull request URLs.
+	refRe = regexp.MustCompile(`(?:^|[^\w./#-])(?:https?://github\.com/([\w.-]+/[\w.-]+)/(?:issues|pull)/(\d+)|([\w.-]+/[\w.-]+)?#(\d+))\b`)
+
+	fixesKeywordRe      = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s*$`)
+	duplicatesKeywordRe = regexp.MustCompile(`(?i)\b(?:duplicate(?:s|\s+of)?|dup(?:e)?\s+of):?\s*$`)
+)
+
+// ParseReferences parses the references in the body of an issue or pull
+// request, e.g. "Fixes #1234", "ref kubernetes/website#55", "Duplicate of
+// #99" or a full issue URL. A reference is a fix when it follows a closing
+// keyword, as Github uses to close issues. References to the issue itself
+// are ignored, and a target referenced several times is kept once with its
+// strongest kind.
+func ParseReferences(repository string, number int, text string) []Reference {
+	byTarget := map[string]Reference{}
+	for _, m := range refRe.FindAllStringSubmatchIndex(text, -1) {
+		target, n := repository, 0
+		switch {
+		case m[2] >= 0:
+			target = text[m[2]:m[3]]
+			n, _ = strconv.Atoi(text[m[4]:m[5]])
+		default:
+			if m[6] >= 0 {
+				target = text[m[6]:m[7]]
+			}
+			n, _ = strconv.Atoi(text[m[8]:m[9]])
+		}
+
+		if n == 0 || (target == repository && n == number) {
+			continue
+		}
+
+		kind := RefReferences
+		before := text[:m[0]+1]
+		switch {
+		case fixesKeywordRe.MatchString(before):
+			kind = RefFixes
+		case duplicatesKeywordRe.MatchString(before):
+			kind = RefDuplicates
+		}
+
+		key := target + "#" + strconv.Itoa(n)
+		if existing, ok := byTarget[key]; ok && refKindRank[existing.Kind] <= refKindRank[kind] {
+			continue
+		}
+
+		byTarget[key] = Reference{
+			Repository:       repository,
+			Number:           number,
+			TargetRepository: target,
+			TargetNumber:     n,
+			Kind:             kind,
+		}
+	}
+
+	refs := []Reference{}
+	for _, ref := range byTarget {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].TargetRepository != refs[j].TargetRepository {
+			return refs[i].TargetRepository < refs[j].TargetRepository
+		}
+		return refs[i].TargetNumber < refs[j].TargetNumber
+	})
+
+	return refs
+}
+
+// issueReferences returns the references in the body of an issue. Only pull
+// requests fix issues, so closing keywords in an issue are references.
+func issueReferences(issue Issue) ReferenceSource {
+	refs := ParseReferences(issue.Repository, issue.Number, issue.Body)
+	if !issue.PullRequest {
+		refs = withoutFixes(refs)
+	}
+
+	return ReferenceSource{Repository: issue.Repository, Number: issue.Number, References: refs}
+}
+
+// commentReferences returns the references in a comment. Github doesn't
+// close issues from comments, so closing keywords in a comment are
+// references.
+func commentReferences(comment Comment) ReferenceSource {
+	refs := withoutFixes(ParseReferences(comment.Repository, comment.IssueNumber, comment.Body))
+	for i := range refs {
+		refs[i].CommentID = comment.ID
+	}
+
+	return ReferenceSource{
+		Repository: comment.Repository,
+		Number:     comment.IssueNumber,
+		CommentID:  comment.ID,
+		References: refs,
+	}
+}
+
+func withoutFixes(refs []Reference) []Reference {
+	for i := range refs {
+		if refs[i].Kind == RefFixes {
+			refs[i].Kind = RefReferences
+		}
+	}
+
+	return refs
+}
+
+// Fix is a pull request which fixes an issue.
+type Fix struct {
+	Repository  string `json:"repository"`
+	Number      int    `json:"number"`
+	PullRequest Issue  `json:"pull_request"`
+	// MergedAt is when the pull request was merged, or nil if it wasn't.
+	MergedAt *time.Time `json:"merged_at,omitempty"`
+}
+
+// Merged returns true if the pull request was merged.
+func (f Fix) Merged() bool {
+	return f.MergedAt != nil
+}
+
+// Open returns true if the pull request is still open.
+func (f Fix) Open() bool {
+	return f.PullRequest.State == "open"
+}
+
+// FixesByIssue groups fixes by the number of the issue they fix.
+func FixesByIssue(fixes []Fix) map[int][]Fix {
+	byIssue := map[int][]Fix{}
+	for _, fix := range fixes {
+		byIssue[fix.Number] = append(byIssue[fix.Number], fix)
+	}
+
+	return byIssue
+}
+
+// SaveReferences replaces the references parsed from each source.
+func (s *sqlStore) SaveReferences(sources []ReferenceSource) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "save references failure")
+	}
+
+	for _, source := range sources {
+		if err := s.exec(tx, deleteReferencesSQL, source.Repository, source.Number, source.CommentID); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "delete references")
+		}
+
+		for _, ref := range source.References {
+			if err := s.exec(tx, insertReferenceSQL, source.Repository, source.Number, source.CommentID,
+				ref.TargetRepository, ref.TargetNumber, ref.Kind); err != nil {
+				tx.Rollback()
+				return errors.Wrap(err, "insert reference")
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// fixRow is a fix as loaded from the database.
+type fixRow struct {
+	Issue
+	TargetRepository string     `db:"target_repository"`
+	TargetNumber     int        `db:"target_number"`
+	MergedAt         *time.Time `db:"merged_at"`
+}
+
+// LoadFixes loads the pull requests which fix issues in a repository,
+// ordered by issue and pull request number.
+func (s *sqlStore) LoadFixes(repository string) ([]Fix, error) {
+	rows := []fixRow{}
+	if err := s.selectx(&rows, fixesSQL, repository); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve fixes")
+	}
+
+	// a pull request merged more than once has a row for each merge
+	fixes := []Fix{}
+	for _, row := range rows {
+		fix := Fix{
+			Repository:  row.TargetRepository,
+			Number:      row.TargetNumber,
+			PullRequest: row.Issue,
+			MergedAt:    row.MergedAt,
+		}
+
+		if last := len(fixes) - 1; last >= 0 && fixes[last].Number == fix.Number &&
+			fixes[last].PullRequest.Repository == fix.PullRequest.Repository &&
+			fixes[last].PullRequest.Number == fix.PullRequest.Number {
+			fixes[last].MergedAt = latest(fixes[last].MergedAt, fix.MergedAt)
+			continue
+		}
+
+		fixes = append(fixes, fix)
+	}
+
+	return fixes, nil
+}
+
+var (
+	deleteReferencesSQL = `
+  DELETE FROM issue_references
+  WHERE repository = $1 AND number = $2 AND comment_id = $3`
+
+	insertReferenceSQL = `
+  INSERT INTO issue_references
+  (repository, number, comment_id, target_repository, target_number, kind)
+
+  VALUES
+  ($1, $2, $3, $4, $5, $6)`
+
+	fixesSQL = `
+  SELECT r.target_repository, r.target_number, e.created_at AS merged_at,
+    p.id, p.number, p.state, p.title, p.body, p.created_by, p.labels, p.assignee, p.closed_at,
+    p.created_at, p.updated_at, p.milestone, p.repository, p.pull_request
+  FROM issue_references r
+  JOIN issues p ON p.repository = r.repository AND p.number = r.number AND p.pull_request
+  LEFT JOIN issue_events e ON e.repository = p.repository AND e.issue_number = p.number
+    AND e.event = 'merged'
+  WHERE r.target_repository = $1 AND r.kind = 'fixes'
+  ORDER BY r.target_number, p.repository, p.number`
+)
@@ -0,0 +1,98 @@
+package kubenews
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReferences(t *testing.T) {
+	ref := func(repo string, n int, kind string) Reference {
+		return Reference{Repository: "org/repo", Number: 10, TargetRepository: repo, TargetNumber: n, Kind: kind}
+	}
+
+	cases := []struct {
+		text string
+		want []Reference
+	}{
+		{"Fixes #1234", []Reference{ref("org/repo", 1234, RefFixes)}},
+		{"this closes: #1 and resolves org/other#2", []Reference{
+			ref("org/other", 2, RefFixes), ref("org/repo", 1, RefFixes),
+		}},
+		{"ref kubernetes/website#55", []Reference{ref("kubernetes/website", 55, RefReferences)}},
+		{"Duplicate of #99", []Reference{ref("org/repo", 99, RefDuplicates)}},
+		{"see https://github.com/org/other/pull/7 and https://github.com/org/repo/issues/8",
+			[]Reference{ref("org/other", 7, RefReferences), ref("org/repo", 8, RefReferences)}},
+		{"fix https://github.com/org/repo/issues/3", []Reference{ref("org/repo", 3, RefFixes)}},
+		// the strongest kind is kept
+		{"like #5, which this fixes #5", []Reference{ref("org/repo", 5, RefFixes)}},
+		// self references, anchors and prefixes aren't references
+		{"#10 http://example.com/page#3 abc#4 prefix fix#6", []Reference{}},
+		{"prefixes #5", []Reference{ref("org/repo", 5, RefReferences)}},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.want, ParseReferences("org/repo", 10, c.text), c.text)
+	}
+}
+
+func TestImportReferences(t *testing.T) {
+	s := NewMemoryStore()
+
+	now := time.Now()
+	issue := func(number int, body string, pr bool) github.Issue {
+		i := github.Issue{
+			Number:    github.Int(number),
+			State:     github.String("open"),
+			Title:     github.String("title"),
+			Body:      github.String(body),
+			User:      &github.User{Login: github.String("user")},
+			CreatedAt: &now,
+			UpdatedAt: &now,
+		}
+		if pr {
+			i.PullRequestLinks = &github.PullRequestLinks{}
+		}
+		return i
+	}
+
+	_, err := ImportIssues(s, "org/repo", []github.Issue{
+		issue(1, "broken", false),
+		issue(2, "Fixes #1", true),
+		// only pull requests fix issues
+		issue(3, "fixes #1", false),
+	})
+	require.NoError(t, err)
+
+	comment := github.IssueComment{
+		ID:        github.Int(100),
+		Body:      github.String("fixes #1"),
+		User:      &github.User{Login: github.String("user")},
+		IssueURL:  github.String("https://api.github.com/repos/org/repo/issues/4"),
+		CreatedAt: &now,
+		UpdatedAt: &now,
+	}
+	_, err = ImportComments(s, "org/repo", []github.IssueComment{comment})
+	require.NoError(t, err)
+
+	fixes, err := s.LoadFixes("org/repo")
+	require.NoError(t, err)
+	require.Len(t, fixes, 1)
+	require.Equal(t, 1, fixes[0].Number)
+	require.Equal(t, 2, fixes[0].PullRequest.Number)
+	require.False(t, fixes[0].Merged())
+
+	require.Equal(t, []Reference{
+		{Repository: "org/repo", Number: 4, CommentID: 100, TargetRepository: "org/repo", TargetNumber: 1, Kind: RefReferences},
+	}, s.refs[refSourceKey{"org/repo", 4, 100}])
+
+	// an edited body replaces its references
+	_, err = ImportIssues(s, "org/repo", []github.Issue{issue(2, "ref #1", true)})
+	require.NoError(t, err)
+
+	fixes, err = s.LoadFixes("org/repo")
+	require.NoError(t, err)
+	require.Empty(t, fixes)
+}
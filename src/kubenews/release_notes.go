@@ -0,0 +1,281 @@
+package kubenews
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// releaseNoteNoneLabel marks pull requests without a release note.
+	releaseNoteNoneLabel = "release-note-none"
+
+	// actionRequiredLabel marks release notes which need action from users.
+	actionRequiredLabel = "release-note-action-required"
+
+	releaseNoteRe = regexp.MustCompile("(?s)```release-note\\s*\\n(.*?)```")
+
+	actionRequiredRe = regexp.MustCompile(`(?i)\baction required\b`)
+)
+
+// MergedPullRequest is a pull request with the time it was merged.
+type MergedPullRequest struct {
+	Issue
+	MergedAt time.Time `db:"merged_at"`
+}
+
+// ReleaseNote is a release note extracted from a merged pull request.
+type ReleaseNote struct {
+	PullRequest    MergedPullRequest
+	Text           string
+	ActionRequired bool
+	Kinds          []string
+	SIGs           []string
+}
+
+// ExtractReleaseNote extracts the release note from a pull request body. It
+// returns false if the pull request has no note.
+func ExtractReleaseNote(pr Issue) (string, bool) {
+	for _, label := range pr.Labels {
+		if label.Name == releaseNoteNoneLabel {
+			return "", false
+		}
+	}
+
+	m := releaseNoteRe.FindStringSubmatch(strings.Replace(pr.Body, "\r\n", "\n", -1))
+	if m == nil {
+		return "", false
+	}
+
+	text := strings.TrimSpace(m[1])
+	if text == "" || strings.EqualFold(text, "none") {
+		return "", false
+	}
+
+	return text, true
+}
+
+// ReleaseNotesOptions are options for collecting release notes.
+type ReleaseNotesOptions struct {
+	Repository string
+	// Milestone selects pull requests in a milestone. If empty, pull requests
+	// merged between From and To are selected.
+	Milestone string
+	From      time.Time
+	To        time.Time
+	SIGs      *SIGMap
+	Taxonomy  *Taxonomy
+}
+
+// ReleaseNotes are the release notes for a milestone or time range.
+type ReleaseNotes struct {
+	Repository string
+	Milestone  string
+	From       time.Time
+	To         time.Time
+	Notes      []ReleaseNote
+}
+
+// LoadMergedPullRequests loads the pull requests merged in a milestone, or
+// merged in a time range if milestone is empty.
+func (s *sqlStore) LoadMergedPullRequests(repository, milestone string, from, to time.Time) ([]MergedPullRequest, error) {
+	prs := []MergedPullRequest{}
+
+	var err error
+	if milestone != "" {
+		err = s.selectx(&prs, mergedInMilestoneSQL, repository, milestone)
+	} else {
+		err = s.selectx(&prs, mergedBetweenSQL, repository, from, to)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve merged pull requests")
+	}
+
+	return prs, nil
+}
+
+// NewReleaseNotes extracts release notes from merged pull requests.
+func NewReleaseNotes(prs []MergedPullRequest, opts ReleaseNotesOptions) *ReleaseNotes {
+	sigs := opts.SIGs.orDefault()
+	taxonomy := opts.Taxonomy
+	if taxonomy == nil {
+		taxonomy = NewTaxonomy(nil)
+	}
+
+	rn := &ReleaseNotes{
+		Repository: opts.Repository,
+		Milestone:  opts.Milestone,
+		From:       opts.From,
+		To:         opts.To,
+	}
+
+	for _, pr := range prs {
+		text, ok := ExtractReleaseNote(pr.Issue)
+		if !ok {
+			continue
+		}
+
+		note := ReleaseNote{
+			PullRequest:    pr,
+			Text:           text,
+			ActionRequired: actionRequiredRe.MatchString(text),
+			Kinds:          taxonomy.Classify(pr.Issue).Kinds(),
+			SIGs:           sigs.SIGs(pr.Issue),
+		}
+
+		for _, label := range pr.Labels {
+			if label.Name == actionRequiredLabel {
+				note.ActionRequired = true
+			}
+		}
+
+		if len(note.Kinds) == 0 {
+			note.Kinds = []string{noKind}
+		}
+
+		rn.Notes = append(rn.Notes, note)
+	}
+
+	sort.Slice(rn.Notes, func(i, j int) bool {
+		return rn.Notes[i].PullRequest.Number < rn.Notes[j].PullRequest.Number
+	})
+
+	return rn
+}
+
+// ActionRequired returns the notes which need action from users.
+func (rn *ReleaseNotes) ActionRequired() []ReleaseNote {
+	notes := []ReleaseNote{}
+	for _, note := range rn.Notes {
+		if note.ActionRequired {
+			notes = append(notes, note)
+		}
+	}
+
+	return notes
+}
+
+// BySIGAndKind groups notes by SIG, then kind. A note with several SIGs or
+// kinds appears in each group.
+func (rn *ReleaseNotes) BySIGAndKind() map[string]map[string][]ReleaseNote {
+	groups := map[string]map[string][]ReleaseNote{}
+	for _, note := range rn.Notes {
+		for _, sig := range note.SIGs {
+			if groups[sig] == nil {
+				groups[sig] = map[string][]ReleaseNote{}
+			}
+			for _, kind := range note.Kinds {
+				groups[sig][kind] = append(groups[sig][kind], note)
+			}
+		}
+	}
+
+	return groups
+}
+
+// Title returns the title of the release notes.
+func (rn *ReleaseNotes) Title() string {
+	if rn.Milestone != "" {
+		return fmt.Sprintf("Release notes for %s", rn.Milestone)
+	}
+
+	return fmt.Sprintf("Release notes for %s to %s", rn.From.Format(dateFormat), rn.To.Format(dateFormat))
+}
+
+// WriteMarkdown writes the release notes to w as Markdown.
+func (rn *ReleaseNotes) WriteMarkdown(w io.Writer) error {
+	p := &printer{w: w}
+	p.printf("# %s\n\n", escapeMarkdown(rn.Title()))
+
+	if len(rn.Notes) == 0 {
+		p.printf("None\n")
+		return p.err
+	}
+
+	if action := rn.ActionRequired(); len(action) > 0 {
+		p.printf("## Action required\n\n")
+		for _, note := range action {
+			p.releaseNote(note)
+		}
+		p.printf("\n")
+	}
+
+	groups := rn.BySIGAndKind()
+	sigCounts := SIGCounts{}
+	for sig, kinds := range groups {
+		sigCounts[sig] = len(kinds)
+	}
+
+	for _, sig := range sigCounts.Names() {
+		if sig == NoSIG {
+			p.printf("## Other\n\n")
+		} else {
+			p.printf("## sig/%s\n\n", sig)
+		}
+
+		kinds := []string{}
+		for kind := range groups[sig] {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		for _, kind := range kinds {
+			p.printf("### %s\n\n", kindHeading(kind))
+			for _, note := range groups[sig][kind] {
+				p.releaseNote(note)
+			}
+			p.printf("\n")
+		}
+	}
+
+	return p.err
+}
+
+func kindHeading(kind string) string {
+	if kind == noKind {
+		return "Other changes"
+	}
+
+	return "kind/" + kind
+}
+
+func (p *printer) releaseNote(note ReleaseNote) {
+	pr := note.PullRequest
+	lines := strings.Split(note.Text, "\n")
+	p.printf("* %s ([#%d](%s), [@%s](https://github.com/%s))\n", strings.TrimSpace(lines[0]), pr.Number,
+		pr.HTMLURL(), pr.User, pr.User)
+	for _, line := range lines[1:] {
+		if line = strings.TrimSpace(line); line != "" {
+			p.printf("  %s\n", line)
+		}
+	}
+}
+
+var (
+	// lastMergedSQL joins the last merged event of each pull request. The
+	// event's created_at is selected directly, rather than with MAX, so SQLite
+	// keeps its type.
+	lastMergedSQL = `
+  SELECT i.id, i.number, i.state, i.title, i.body, i.created_by, i.labels, i.assignee,
+    i.closed_at, i.created_at, i.updated_at, i.milestone, i.repository, i.pull_request,
+    e.created_at AS merged_at
+  FROM issues i
+  JOIN issue_events e ON e.repository = i.repository AND e.issue_number = i.number
+  WHERE i.repository = $1 AND i.pull_request AND e.event = 'merged'
+    AND NOT EXISTS (SELECT 1 FROM issue_events l
+      WHERE l.repository = e.repository AND l.issue_number = e.issue_number
+        AND l.event = 'merged' AND l.id > e.id)`
+
+	mergedInMilestoneSQL = lastMergedSQL + `
+    AND i.milestone = $2
+  ORDER BY i.number`
+
+	mergedBetweenSQL = lastMergedSQL + `
+    AND e.created_at >= $2 AND e.created_at < $3
+  ORDER BY i.number`
+)
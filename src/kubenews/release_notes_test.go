@@ -0,0 +1,60 @@
+package kubenews
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractReleaseNote(t *testing.T) {
+	cases := []struct {
+		body   string
+		labels []string
+		note   string
+		ok     bool
+	}{
+		{body: "Fixes #1\r\n\r\n```release-note\r\nKubelet now supports foo.\r\n```\r\n", note: "Kubelet now supports foo.", ok: true},
+		{body: "```release-note\nNONE\n```", ok: false},
+		{body: "```release-note\nSomething\n```", labels: []string{"release-note-none"}, ok: false},
+		{body: "no note here", ok: false},
+	}
+
+	for _, c := range cases {
+		pr := labeledIssue(1, c.labels...)
+		pr.Body = c.body
+
+		note, ok := ExtractReleaseNote(pr)
+		require.Equal(t, c.ok, ok, c.body)
+		require.Equal(t, c.note, note, c.body)
+	}
+}
+
+func TestReleaseNotes(t *testing.T) {
+	feature := labeledIssue(10, "kind/feature", "sig/node", "release-note")
+	feature.User = "alice"
+	feature.PullRequest = true
+	feature.Body = "```release-note\nAdd pod priority.\n```"
+
+	breaking := labeledIssue(11, "kind/bug", "sig/api-machinery", "release-note-action-required")
+	breaking.User = "bob"
+	breaking.PullRequest = true
+	breaking.Body = "```release-note\nRemove the v1beta1 API.\n```"
+
+	none := labeledIssue(12, "kind/bug", "release-note-none")
+	none.PullRequest = true
+
+	prs := []MergedPullRequest{{Issue: feature}, {Issue: breaking}, {Issue: none}}
+	rn := NewReleaseNotes(prs, ReleaseNotesOptions{Repository: "org/repo", Milestone: "v1.7"})
+
+	require.Len(t, rn.Notes, 2)
+	require.Len(t, rn.ActionRequired(), 1)
+	require.Equal(t, 11, rn.ActionRequired()[0].PullRequest.Number)
+
+	var buf bytes.Buffer
+	require.NoError(t, rn.WriteMarkdown(&buf))
+	out := buf.String()
+	require.Contains(t, out, "# Release notes for v1.7")
+	require.Contains(t, out, "## Action required")
+	require.Contains(t, out, "## sig/node\n\n### kind/feature\n\n* Add pod priority. ([#10](https://github.com/org/repo/pull/10)")
+}
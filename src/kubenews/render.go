@@ -0,0 +1,153 @@
+package kubenews
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/russross/blackfriday"
+)
+
+// Formats reports can be written in.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// htmlFlags render untrusted Markdown safely: raw HTML from issue bodies is
+// dropped. Links are checked by safeLinkRenderer.
+const htmlFlags = blackfriday.HTML_USE_XHTML |
+	blackfriday.HTML_SKIP_HTML |
+	blackfriday.HTML_SKIP_STYLE |
+	blackfriday.HTML_HREF_TARGET_BLANK |
+	blackfriday.HTML_NOFOLLOW_LINKS
+
+// markdownExtensions match Github flavored Markdown. Heading IDs are generated
+// with sanitized_anchor_name so they match the links built by Anchor.
+const markdownExtensions = blackfriday.EXTENSION_NO_INTRA_EMPHASIS |
+	blackfriday.EXTENSION_TABLES |
+	blackfriday.EXTENSION_FENCED_CODE |
+	blackfriday.EXTENSION_AUTOLINK |
+	blackfriday.EXTENSION_STRIKETHROUGH |
+	blackfriday.EXTENSION_SPACE_HEADERS |
+	blackfriday.EXTENSION_AUTO_HEADER_IDS
+
+// DefaultHTMLLayout is the layout reports are rendered into when no layout is
+// configured.
+var DefaultHTMLLayout = template.Must(template.New("layout").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 960px; margin: 0 auto; padding: 1em; color: #24292e; }
+a { color: #0366d6; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #dfe2e5; padding: 4px 10px; }
+code { background: #f6f8fa; }
+footer { color: #6a737d; font-size: small; margin-top: 2em; }
+</style>
+</head>
+<body>
+{{.Body}}
+<footer>Generated {{.Generated.Format "2006-01-02 15:04 MST"}}</footer>
+</body>
+</html>
+`))
+
+// LayoutData is the data available to an HTML layout.
+type LayoutData struct {
+	Title     string
+	Body      template.HTML
+	Generated time.Time
+}
+
+// ParseHTMLLayout parses an HTML layout template from a file. The template is
+// executed with LayoutData.
+func ParseHTMLLayout(path string) (*template.Template, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read layout %s", path)
+	}
+
+	t, err := template.New("layout").Parse(string(b))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse layout %s", path)
+	}
+
+	return t, nil
+}
+
+// safeLinkRenderer renders links with an unsafe protocol, such as
+// javascript:, as plain text. Unlike blackfriday's HTML_SAFELINK it allows
+// links to anchors in the same page.
+type safeLinkRenderer struct {
+	blackfriday.Renderer
+}
+
+var safeLinkRe = regexp.MustCompile(`^(?i:https?://|mailto:|ftp://|/|\./|\.\./|#)`)
+
+func (r safeLinkRenderer) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	if !safeLinkRe.Match(link) {
+		out.Write(content)
+		return
+	}
+
+	r.Renderer.Link(out, link, title, content)
+}
+
+func (r safeLinkRenderer) AutoLink(out *bytes.Buffer, link []byte, kind int) {
+	if kind != blackfriday.LINK_TYPE_EMAIL && !safeLinkRe.Match(link) {
+		r.Renderer.NormalText(out, link)
+		return
+	}
+
+	r.Renderer.AutoLink(out, link, kind)
+}
+
+// MarkdownToHTML renders Markdown to an HTML fragment.
+func MarkdownToHTML(markdown []byte) []byte {
+	renderer := safeLinkRenderer{blackfriday.HtmlRenderer(htmlFlags, "", "")}
+	return blackfriday.Markdown(markdown, renderer, markdownExtensions)
+}
+
+// WriteHTML renders a report into layout and writes it to w. A nil layout
+// uses DefaultHTMLLayout.
+func WriteHTML(w io.Writer, r Report, layout *template.Template) error {
+	if layout == nil {
+		layout = DefaultHTMLLayout
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteMarkdown(&buf); err != nil {
+		return err
+	}
+
+	data := LayoutData{
+		Title:     r.Title(),
+		Body:      template.HTML(MarkdownToHTML(buf.Bytes())),
+		Generated: time.Now(),
+	}
+
+	if err := layout.Execute(w, data); err != nil {
+		return errors.Wrap(err, "unable to render layout")
+	}
+
+	return nil
+}
+
+// WriteReport writes a report to w in format.
+func WriteReport(w io.Writer, r Report, format string, layout *template.Template) error {
+	switch format {
+	case FormatMarkdown, "":
+		return r.WriteMarkdown(w)
+	case FormatHTML:
+		return WriteHTML(w, r, layout)
+	default:
+		return errors.Errorf("unknown format %q", format)
+	}
+}
@@ -0,0 +1,62 @@
+package kubenews
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteHTML(t *testing.T) {
+	from := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	during := from.AddDate(0, 0, 1)
+
+	issue := labeledIssue(1, "sig/node")
+	issue.Repository = "org/repo"
+	issue.Title = "Kubelet <script>alert(1)</script> crashes on *startup*"
+	issue.Body = "<!-- template -->\nSteps: <img src=x onerror=alert(1)> [click](javascript:alert(1))"
+	issue.CreatedAt = &during
+	issue.State = "open"
+
+	d := NewDigest(DigestOptions{Repository: "org/repo", From: from, To: from.AddDate(0, 0, 7)},
+		[]Issue{issue}, []Issue{issue})
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteReport(&buf, d, FormatHTML, nil))
+	out := buf.String()
+
+	require.Contains(t, out, "<title>org/repo digest</title>")
+	require.Contains(t, out, `<h2 id="opened">Opened</h2>`)
+	require.Contains(t, out, `<a href="#opened">Opened</a>`)
+	require.Contains(t, out, "*startup*")
+	require.NotContains(t, out, "<script>")
+	require.NotContains(t, out, "<img")
+	require.NotContains(t, out, "template")
+	require.NotContains(t, out, `href="javascript:`)
+
+	layout := template.Must(template.New("layout").Parse("<h1>{{.Title}}</h1>{{.Body}}"))
+	buf.Reset()
+	require.NoError(t, WriteReport(&buf, d, FormatHTML, layout))
+	require.True(t, strings.HasPrefix(buf.String(), "<h1>org/repo digest</h1>"))
+
+	require.Error(t, WriteReport(&buf, d, "pdf", nil))
+}
+
+func TestMarkdownToHTML(t *testing.T) {
+	out := string(MarkdownToHTML([]byte("[a](javascript:alert(1)) [b](#section) [c](https://k8s.io) <b>raw</b>")))
+	require.NotContains(t, out, "javascript:")
+	require.Contains(t, out, `<a href="#section">b</a>`)
+	require.Contains(t, out, `href="https://k8s.io"`)
+	require.NotContains(t, out, "<b>")
+}
+
+func TestSnippet(t *testing.T) {
+	body := "<!-- Please fill in -->\n**What happened**:\n\n```\nlogs\n```\nthe pod   was evicted"
+	require.Equal(t, "**What happened**: the pod was evicted", Snippet(body, 100))
+	require.Equal(t, "**What happened**: the…", Snippet(body, 24))
+	require.Equal(t, "", Snippet(body, 0))
+	require.Equal(t, "", Snippet(body, -1))
+}
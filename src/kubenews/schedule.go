@@ -0,0 +1,215 @@
+package kubenews
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Schedule returns when a job next runs after a time.
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// Every is a Schedule which runs at a fixed interval.
+type Every time.Duration
+
+// Next returns after plus the interval.
+func (e Every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+// CronSchedule is a Schedule from a cron expression, evaluated in UTC.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar are set when the day fields are *, as a day
+	// matches either restricted field when both are set.
+	domStar, dowStar bool
+}
+
+var cronDescriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseSchedule parses an interval, e.g. "15m" or "@every 15m", or a five
+// field cron expression, e.g. "0 9 * * 1" for Mondays at 09:00 UTC.
+func ParseSchedule(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+
+	interval := strings.TrimSpace(strings.TrimPrefix(expr, "@every"))
+	if d, err := time.ParseDuration(interval); err == nil {
+		if d <= 0 {
+			return nil, errors.Errorf("invalid schedule %q: interval must be positive", expr)
+		}
+		return Every(d), nil
+	}
+
+	if cron, ok := cronDescriptors[expr]; ok {
+		expr = cron
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("invalid schedule %q: expected an interval or 5 cron fields", expr)
+	}
+
+	s := &CronSchedule{domStar: fields[2] == "*", dowStar: fields[4] == "*"}
+	for i, f := range []struct {
+		bits     *uint64
+		min, max int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		{&s.dow, 0, 7},
+	} {
+		bits, err := parseCronField(fields[i], f.min, f.max)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid schedule %q", expr)
+		}
+		*f.bits = bits
+	}
+
+	// Sunday is 0 or 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	return s, nil
+}
+
+// parseCronField parses a comma separated list of *, values and ranges, each
+// with an optional /step, into a bit set.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, errors.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, errors.Errorf("invalid range %q", part)
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, errors.Errorf("invalid range %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, errors.Errorf("invalid value %q", part)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, errors.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for n := lo; n <= hi; n += step {
+			bits |= 1 << uint(n)
+		}
+	}
+
+	return bits, nil
+}
+
+// Next returns the first minute after a time matching the schedule.
+func (s *CronSchedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+
+	// every schedule matches within a few years, e.g. February 29th
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// SyncSchedule is how often the resources of a repository are synced by
+// `kubenews run`, and how often its open issues are reconciled. Each is a
+// schedule for ParseSchedule, resources without one aren't synced.
+type SyncSchedule struct {
+	Repository string `mapstructure:"repo"`
+	Issues     string `mapstructure:"issues"`
+	Comments   string `mapstructure:"comments"`
+	Events     string `mapstructure:"events"`
+	Reconcile  string `mapstructure:"reconcile"`
+}
+
+// Resources returns the schedule of each synced resource.
+func (s SyncSchedule) Resources() map[string]string {
+	resources := map[string]string{}
+	for resource, schedule := range map[string]string{
+		"issues":          s.Issues,
+		"comments":        s.Comments,
+		"events":          s.Events,
+		ReconcileResource: s.Reconcile,
+	} {
+		if schedule != "" {
+			resources[resource] = schedule
+		}
+	}
+
+	return resources
+}
+
+// DefaultSyncSchedules sync kubernetes/kubernetes every 15 minutes.
+var DefaultSyncSchedules = []SyncSchedule{
+	{Repository: "kubernetes/kubernetes", Issues: "15m", Comments: "15m", Events: "15m"},
+}
+
+// DigestSchedule is when `kubenews run` generates a digest of the last Days
+// for a repository, and where it publishes it: to the email lists, the
+// webhook routes, or both.
+type DigestSchedule struct {
+	Name       string `mapstructure:"name"`
+	Repository string `mapstructure:"repo"`
+	Schedule   string `mapstructure:"schedule"`
+	Days       int    `mapstructure:"days"`
+	Email      bool   `mapstructure:"email"`
+	Webhooks   bool   `mapstructure:"webhooks"`
+}
@@ -0,0 +1,40 @@
+package kubenews
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	at := time.Date(2017, 3, 1, 10, 30, 15, 0, time.UTC) // a Wednesday
+
+	cases := []struct {
+		expr string
+		next time.Time
+	}{
+		{"15m", at.Add(15 * time.Minute)},
+		{"@every 1h", at.Add(time.Hour)},
+		{"0 9 * * 1", time.Date(2017, 3, 6, 9, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2017, 3, 1, 10, 40, 0, 0, time.UTC)},
+		{"5,35 10-12 * * *", time.Date(2017, 3, 1, 10, 35, 0, 0, time.UTC)},
+		{"@daily", time.Date(2017, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2017, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// either day field matches when both are set
+		{"0 0 15 * 5", time.Date(2017, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{"30 10 1 3 *", time.Date(2018, 3, 1, 10, 30, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		s, err := ParseSchedule(c.expr)
+		require.NoError(t, err, c.expr)
+		require.Equal(t, c.next, s.Next(at), c.expr)
+	}
+
+	for _, expr := range []string{"", "0s", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		_, err := ParseSchedule(expr)
+		require.Error(t, err, expr)
+	}
+}
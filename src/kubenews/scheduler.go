@@ -0,0 +1,175 @@
+package kubenews
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Job is work the Scheduler runs on a schedule.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Immediate runs the job when the scheduler starts, rather than waiting
+	// for the first scheduled time.
+	Immediate bool
+	// Run runs the job. ctx is done when the scheduler is stopping, so a job
+	// waiting to start, e.g. for a lock, can give up.
+	Run func(ctx context.Context) error
+}
+
+// JobStatus is the state of a scheduled job.
+type JobStatus struct {
+	Name      string     `json:"name"`
+	Running   bool       `json:"running"`
+	Runs      int        `json:"runs"`
+	Failures  int        `json:"failures"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	NextRun   *time.Time `json:"next_run,omitempty"`
+}
+
+// Scheduler runs jobs on their schedules until it is stopped. A job never
+// overlaps itself, and a running job is allowed to finish when the scheduler
+// stops, so no sync is cut off mid transaction.
+type Scheduler struct {
+	jobs []Job
+
+	mu       sync.Mutex
+	status   map[string]*JobStatus
+	stopping bool
+}
+
+// NewScheduler creates an instance of Scheduler.
+func NewScheduler(jobs []Job) *Scheduler {
+	s := &Scheduler{jobs: jobs, status: map[string]*JobStatus{}}
+	for _, job := range jobs {
+		s.status[job.Name] = &JobStatus{Name: job.Name}
+	}
+
+	return s
+}
+
+// Run runs the jobs until ctx is done, then waits for running jobs to finish.
+func (s *Scheduler) Run(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+
+	<-ctx.Done()
+
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+
+	log.Info("waiting for running jobs to finish")
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	logger := log.WithField("job", job.Name)
+
+	next := time.Now()
+	if !job.Immediate {
+		next = job.Schedule.Next(next)
+	}
+
+	for {
+		if next.IsZero() {
+			logger.Warn("job is never scheduled")
+			return
+		}
+
+		s.update(job.Name, func(st *JobStatus) { st.NextRun = &next })
+		logger.WithField("nextRun", next).Debug("job scheduled")
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.run(ctx, logger, job)
+		next = job.Schedule.Next(time.Now())
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, logger *log.Entry, job Job) {
+	start := time.Now()
+	s.update(job.Name, func(st *JobStatus) { st.Running = true })
+
+	logger.Info("running job")
+	err := job.Run(ctx)
+
+	s.update(job.Name, func(st *JobStatus) {
+		st.Running = false
+		st.Runs++
+		st.LastRun = &start
+		st.LastError = ""
+		if err != nil {
+			st.Failures++
+			st.LastError = err.Error()
+		}
+	})
+
+	logger = logger.WithField("duration", time.Since(start))
+	if err != nil {
+		logger.WithError(err).Error("job failed")
+		return
+	}
+	logger.Info("job finished")
+}
+
+func (s *Scheduler) update(name string, fn func(*JobStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(s.status[name])
+}
+
+// Status returns the status of each job, ordered by name.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := []JobStatus{}
+	for _, st := range s.status {
+		status = append(status, *st)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+
+	return status
+}
+
+// ServeHTTP is a health check. It responds 200 while the scheduler is running
+// and 503 once it is stopping, with the status of each job.
+func (s *Scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	stopping := s.stopping
+	s.mu.Unlock()
+
+	health := struct {
+		Status string      `json:"status"`
+		Jobs   []JobStatus `json:"jobs"`
+	}{Status: "ok", Jobs: s.Status()}
+
+	w.Header().Set("Content-Type", "application/json")
+	if stopping {
+		health.Status = "stopping"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(health)
+}
@@ -0,0 +1,73 @@
+package kubenews
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestScheduler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	syncs := make(chan struct{}, 10)
+	stopped := make(chan struct{})
+	s := NewScheduler([]Job{
+		{
+			Name:      "sync",
+			Schedule:  Every(time.Millisecond),
+			Immediate: true,
+			Run: func(ctx context.Context) error {
+				syncs <- struct{}{}
+				if len(syncs) == 3 {
+					// stopping doesn't interrupt a running job
+					cancel()
+					time.Sleep(10 * time.Millisecond)
+				}
+				return nil
+			},
+		},
+		{
+			Name:     "digest",
+			Schedule: Every(time.Millisecond),
+			Run:      func(ctx context.Context) error { return errors.New("no routes") },
+		},
+	})
+
+	go func() {
+		s.Run(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduler didn't stop")
+	}
+
+	status := s.Status()
+	require.Len(t, status, 2)
+	require.Equal(t, "digest", status[0].Name)
+	require.Equal(t, "no routes", status[0].LastError)
+	require.Equal(t, status[0].Runs, status[0].Failures)
+	require.Equal(t, "sync", status[1].Name)
+	require.Equal(t, 3, status[1].Runs)
+	require.False(t, status[1].Running)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var health struct {
+		Status string
+		Jobs   []JobStatus
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &health))
+	require.Equal(t, "stopping", health.Status)
+	require.Len(t, health.Jobs, 2)
+}
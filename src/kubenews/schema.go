@@ -0,0 +1,169 @@
+package kubenews
+
+import (
+	"github.com/jmoiron/sqlx"
+)
+
+// Migrate creates the Postgres tables kubenews needs if they don't exist.
+func Migrate(db *sqlx.DB) error {
+	return NewPostgresStore(db).Migrate()
+}
+
+// alterToBigint returns a statement changing a column created as an integer
+// to a bigint. Changing the type rewrites the table under an exclusive lock, so
+// it only runs if the column isn't a bigint already.
+func alterToBigint(table, column string) string {
+	return `DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM information_schema.columns
+    WHERE table_schema = current_schema() AND table_name = '` + table + `'
+      AND column_name = '` + column + `' AND data_type <> 'bigint') THEN
+    ALTER TABLE ` + table + ` ALTER COLUMN ` + column + ` TYPE bigint;
+  END IF;
+END
+$$`
+}
+
+var schemaSQL = []string{
+	`CREATE TABLE IF NOT EXISTS issues (
+    id serial PRIMARY KEY,
+    number integer NOT NULL,
+    state text NOT NULL,
+    title text NOT NULL,
+    body text NOT NULL DEFAULT '',
+    created_by text NOT NULL DEFAULT '',
+    labels jsonb NOT NULL DEFAULT '[]',
+    assignee text NOT NULL DEFAULT '',
+    closed_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    milestone text NOT NULL DEFAULT '',
+    repository text NOT NULL
+  )`,
+
+	`ALTER TABLE issues ADD COLUMN IF NOT EXISTS pull_request boolean NOT NULL DEFAULT false`,
+
+	// issue numbers are unique within a repository, so issues transferred
+	// from another repository can be stored. Databases created before then
+	// have a unique constraint on number alone.
+	`ALTER TABLE issues DROP CONSTRAINT IF EXISTS issues_number_key`,
+
+	`CREATE UNIQUE INDEX IF NOT EXISTS issues_repository_number_idx ON issues (repository, number)`,
+
+	`CREATE TABLE IF NOT EXISTS labels (
+    name text PRIMARY KEY,
+    url text NOT NULL,
+    color text NOT NULL,
+    active boolean NOT NULL DEFAULT true
+  )`,
+
+	`CREATE TABLE IF NOT EXISTS comments (
+    id bigint PRIMARY KEY,
+    repository text NOT NULL,
+    issue_number integer NOT NULL,
+    created_by text NOT NULL DEFAULT '',
+    body text NOT NULL DEFAULT '',
+    created_at timestamptz,
+    updated_at timestamptz
+  )`,
+
+	// Github comment and event ids don't fit in an integer
+	alterToBigint("comments", "id"),
+
+	`CREATE INDEX IF NOT EXISTS comments_issue_idx ON comments (repository, issue_number)`,
+
+	`CREATE INDEX IF NOT EXISTS issues_search_idx ON issues USING GIN
+    ((setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', body), 'B')))`,
+
+	`CREATE INDEX IF NOT EXISTS comments_search_idx ON comments USING GIN (to_tsvector('english', body))`,
+
+	`CREATE TABLE IF NOT EXISTS issue_events (
+    id bigint PRIMARY KEY,
+    repository text NOT NULL,
+    issue_number integer NOT NULL,
+    event text NOT NULL,
+    actor text NOT NULL DEFAULT '',
+    label text NOT NULL DEFAULT '',
+    milestone text NOT NULL DEFAULT '',
+    assignee text NOT NULL DEFAULT '',
+    commit_id text NOT NULL DEFAULT '',
+    created_at timestamptz
+  )`,
+
+	alterToBigint("issue_events", "id"),
+
+	`CREATE INDEX IF NOT EXISTS issue_events_issue_idx ON issue_events (repository, issue_number)`,
+
+	`CREATE TABLE IF NOT EXISTS backlog_snapshots (
+    day date NOT NULL,
+    repository text NOT NULL,
+    dimension text NOT NULL,
+    value text NOT NULL,
+    open_count integer NOT NULL,
+    PRIMARY KEY (day, repository, dimension, value)
+  )`,
+
+	`CREATE TABLE IF NOT EXISTS digests (
+    id serial PRIMARY KEY,
+    repository text NOT NULL,
+    list text NOT NULL,
+    sig text NOT NULL DEFAULT '',
+    period_from date NOT NULL,
+    period_to date NOT NULL,
+    title text NOT NULL,
+    markdown text NOT NULL,
+    recipients text NOT NULL,
+    message_id text NOT NULL,
+    sent_at timestamptz NOT NULL,
+    UNIQUE (repository, list, period_from, period_to)
+  )`,
+
+	`ALTER TABLE digests ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'sent'`,
+
+	`CREATE TABLE IF NOT EXISTS webhook_alerts (
+    repository text NOT NULL,
+    issue_number integer NOT NULL,
+    alert text NOT NULL,
+    route text NOT NULL,
+    sent_at timestamptz NOT NULL,
+    PRIMARY KEY (repository, issue_number, alert, route)
+  )`,
+
+	`CREATE TABLE IF NOT EXISTS leases (
+    lock_key bigint PRIMARY KEY,
+    repository text NOT NULL,
+    job text NOT NULL,
+    holder text NOT NULL,
+    acquired_at timestamptz NOT NULL
+  )`,
+
+	`CREATE TABLE IF NOT EXISTS sync_runs (
+    id serial PRIMARY KEY,
+    repository text NOT NULL,
+    resource text NOT NULL,
+    started_at timestamptz NOT NULL,
+    finished_at timestamptz NOT NULL,
+    pages integer NOT NULL,
+    api_calls integer NOT NULL,
+    fetched integer NOT NULL,
+    inserted integer NOT NULL,
+    updated integer NOT NULL,
+    error text NOT NULL DEFAULT ''
+  )`,
+
+	`CREATE INDEX IF NOT EXISTS sync_runs_started_idx ON sync_runs (repository, resource, started_at)`,
+
+	`CREATE TABLE IF NOT EXISTS issue_references (
+    repository text NOT NULL,
+    number integer NOT NULL,
+    comment_id bigint NOT NULL DEFAULT 0,
+    target_repository text NOT NULL,
+    target_number integer NOT NULL,
+    kind text NOT NULL,
+    PRIMARY KEY (repository, number, comment_id, target_repository, target_number)
+  )`,
+
+	`CREATE INDEX IF NOT EXISTS issue_references_target_idx ON issue_references (target_repository, target_number)`,
+
+	alterToBigint("issue_references", "comment_id"),
+}
@@ -0,0 +1,186 @@
+package kubenews
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Search highlights mark the matched words in a snippet.
+const (
+	HighlightStart = "**"
+	HighlightStop  = "**"
+)
+
+// defaultSearchLimit is the number of results when a search doesn't set a
+// limit.
+var defaultSearchLimit = 20
+
+// SearchQuery is a full-text search over issue titles, bodies and comments.
+// Text is parsed as plain words which must all match.
+type SearchQuery struct {
+	Repository string
+	Text       string
+	State      string
+	Labels     []string
+	// Filter limits the search to issues matching a query. Its words are
+	// ignored, they should be part of Text.
+	Filter *Query
+	Limit  int
+}
+
+// SearchResult is an issue matching a search. Snippet is an excerpt of the
+// issue, or of its best matching comment, with the matched words wrapped in
+// HighlightStart and HighlightStop.
+type SearchResult struct {
+	Issue
+	Rank    float64 `db:"rank"`
+	Snippet string  `db:"snippet"`
+}
+
+// SearchIssues retrieves the issues matching a search, most relevant first.
+// Title matches rank above body matches, which rank above comment matches.
+func SearchIssues(db *sqlx.DB, q SearchQuery) ([]SearchResult, error) {
+	if strings.TrimSpace(q.Text) == "" {
+		return nil, errors.New("search text is empty")
+	}
+
+	iq := IssueQuery{Repository: q.Repository, State: q.State, Labels: q.Labels}
+	if q.Filter != nil {
+		iq.Filter = q.Filter.WithoutWords()
+	}
+
+	where, args, err := iq.where()
+	if err != nil {
+		return nil, err
+	}
+
+	limit := q.Limit
+	if limit < 1 {
+		limit = defaultSearchLimit
+	}
+
+	conds := strings.TrimPrefix(where, " WHERE ")
+	if conds == "" {
+		conds = "true"
+	}
+
+	args = append(args, q.Text, q.Repository)
+	text, repo := "$"+strconv.Itoa(len(args)-1), "$"+strconv.Itoa(len(args))
+
+	query := fmt.Sprintf(searchSQL, text, repo, conds, limit)
+
+	results := []SearchResult{}
+	if err := db.Select(&results, query, args...); err != nil {
+		return nil, errors.Wrap(err, "unable to search issues")
+	}
+
+	for i := range results {
+		results[i].Snippet = strings.Join(strings.Fields(results[i].Snippet), " ")
+	}
+
+	return results, nil
+}
+
+// SearchResults are the results of a search.
+type SearchResults []SearchResult
+
+// Write writes the results in a format: text or json.
+func (r SearchResults) Write(w io.Writer, format string) error {
+	switch format {
+	case "text", "":
+		return r.WriteText(w)
+	case "json":
+		return r.WriteJSON(w)
+	default:
+		return errors.Errorf("unknown format %q", format)
+	}
+}
+
+// WriteText writes one entry per result with its link and snippet.
+func (r SearchResults) WriteText(w io.Writer) error {
+	p := &printer{w: w}
+	for _, result := range r {
+		p.printf("#%d [%s] %s (%.2f)\n", result.Number, result.State, result.Title, result.Rank)
+		p.printf("    %s\n", result.HTMLURL())
+		if result.Snippet != "" {
+			p.printf("    %s\n", result.Snippet)
+		}
+		p.printf("\n")
+	}
+
+	return p.err
+}
+
+// searchResultJSON is the JSON representation of a search result.
+type searchResultJSON struct {
+	apiIssue
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// WriteJSON writes the results as a JSON array.
+func (r SearchResults) WriteJSON(w io.Writer) error {
+	out := []searchResultJSON{}
+	for _, result := range r {
+		out = append(out, searchResultJSON{
+			apiIssue: newAPIIssues([]Issue{result.Issue})[0],
+			Rank:     result.Rank,
+			Snippet:  result.Snippet,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+var (
+	// issueDocumentSQL and commentDocumentSQL must match the expressions of the
+	// search indexes in the schema for the indexes to be used.
+	issueDocumentSQL = `(setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', body), 'B'))`
+
+	commentDocumentSQL = `to_tsvector('english', body)`
+
+	headlineOptions = `'StartSel=` + HighlightStart + `, StopSel=` + HighlightStop +
+		`, MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" … "'`
+
+	// searchSQL is formatted with the text and repository placeholders, the
+	// issue conditions and the limit. Issues matching by title or body are
+	// found through the issues search index, and those matching by comment
+	// through the comments one. Only the matches are ranked.
+	searchSQL = `
+  WITH q AS (SELECT plainto_tsquery('english', %[1]s) AS query),
+  matched_comments AS (
+    SELECT repository, issue_number,
+      MAX(ts_rank(` + commentDocumentSQL + `, q.query)) AS rank,
+      (array_agg(body ORDER BY ts_rank(` + commentDocumentSQL + `, q.query) DESC))[1] AS body
+    FROM comments, q
+    WHERE (%[2]s = '' OR repository = %[2]s) AND ` + commentDocumentSQL + ` @@ q.query
+    GROUP BY repository, issue_number
+  ),
+  matched AS (
+    SELECT issues.id FROM issues, q
+    WHERE ` + issueDocumentSQL + ` @@ q.query AND %[3]s
+    UNION
+    SELECT issues.id FROM issues
+    WHERE (repository, number) IN (SELECT repository, issue_number FROM matched_comments) AND %[3]s
+  )
+  SELECT i.id, i.number, i.state, i.title, i.body, i.created_by, i.labels, i.assignee,
+    i.closed_at, i.created_at, i.updated_at, i.milestone, i.repository, i.pull_request,
+    ts_rank(i.document, q.query) + COALESCE(mc.rank, 0) * 0.1 AS rank,
+    CASE WHEN i.document @@ q.query
+      THEN ts_headline('english', i.title || ' ' || i.body, q.query, ` + headlineOptions + `)
+      ELSE ts_headline('english', mc.body, q.query, ` + headlineOptions + `)
+    END AS snippet
+  FROM (SELECT *, ` + issueDocumentSQL + ` AS document FROM issues WHERE id IN (SELECT id FROM matched)) i
+  CROSS JOIN q
+  LEFT JOIN matched_comments mc ON mc.repository = i.repository AND mc.issue_number = i.number
+  ORDER BY rank DESC, i.number DESC
+  LIMIT %[4]d`
+)
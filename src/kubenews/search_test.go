@@ -0,0 +1,65 @@
+package kubenews
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestSearchIssues(t *testing.T) {
+	stdlibdb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db := sqlx.NewDb(stdlibdb, "mockdriver")
+
+	created := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("plainto_tsquery\\('english', \\$4\\).*"+
+		"setweight\\(to_tsvector\\('english', body\\), 'B'\\)\\) @@ q.query AND repository = \\$1 AND state = \\$2 AND labels @> \\$3::jsonb.*"+
+		"UNION.*FROM matched_comments\\) AND repository = \\$1.*LIMIT 5").
+		WithArgs("org/repo", "open", `[{"Name":"sig/api-machinery"}]`, "etcd quorum", "org/repo").
+		WillReturnRows(sqlmock.NewRows(append(issueColumns, "rank", "snippet")).
+			AddRow(1, 10, "open", "etcd lost quorum", "body", "alice",
+				[]byte(`[{"URL":"u","Name":"sig/api-machinery","Color":"fff"}]`), "", nil, created, created, "",
+				"org/repo", false, 0.75, "**etcd**  lost\n**quorum**"))
+
+	results, err := SearchIssues(db, SearchQuery{
+		Repository: "org/repo",
+		Text:       "etcd quorum",
+		State:      "open",
+		Labels:     []string{"sig/api-machinery"},
+		Limit:      5,
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, results, 1)
+	require.Equal(t, 10, results[0].Number)
+	require.Equal(t, 0.75, results[0].Rank)
+	require.Equal(t, "**etcd** lost **quorum**", results[0].Snippet)
+
+	var text bytes.Buffer
+	require.NoError(t, SearchResults(results).Write(&text, "text"))
+	require.Equal(t, "#10 [open] etcd lost quorum (0.75)\n"+
+		"    https://github.com/org/repo/issues/10\n"+
+		"    **etcd** lost **quorum**\n\n", text.String())
+
+	var out bytes.Buffer
+	require.NoError(t, SearchResults(results).Write(&out, "json"))
+
+	var decoded []map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Len(t, decoded, 1)
+	require.Equal(t, 0.75, decoded[0]["rank"])
+	require.Equal(t, "https://github.com/org/repo/issues/10", decoded[0]["html_url"])
+	require.Equal(t, "sig/api-machinery", decoded[0]["labels"].([]interface{})[0].(map[string]interface{})["name"])
+}
+
+func TestSearchIssuesEmpty(t *testing.T) {
+	_, err := SearchIssues(nil, SearchQuery{Text: "  "})
+	require.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package kubenews
+
+import (
+	"sort"
+	"strings"
+)
+
+var (
+	// DefaultSIGPrefix is the label prefix Kubernetes uses for SIG labels.
+	DefaultSIGPrefix = "sig/"
+
+	// NoSIG is the SIG name used for issues which don't carry a SIG label.
+	NoSIG = "none"
+)
+
+// SIG is a Kubernetes special interest group.
+type SIG struct {
+	// Name is the canonical name of the SIG, e.g. node.
+	Name string `mapstructure:"name"`
+	// Labels are the labels which identify the SIG. If empty, the label is
+	// derived from the prefix and the name.
+	Labels []string `mapstructure:"labels"`
+	// Aliases are labels or names the SIG was known by before it was renamed.
+	Aliases []string `mapstructure:"aliases"`
+}
+
+// SIGMap maps issue labels to SIGs.
+type SIGMap struct {
+	prefix  string
+	byLabel map[string]string
+	byName  map[string]string
+}
+
+// NewSIGMap creates an instance of SIGMap. Labels starting with prefix which
+// are not configured explicitly are mapped to a SIG named after the remainder
+// of the label.
+func NewSIGMap(prefix string, sigs []SIG) *SIGMap {
+	m := &SIGMap{
+		prefix:  prefix,
+		byLabel: map[string]string{},
+		byName:  map[string]string{},
+	}
+
+	for _, sig := range sigs {
+		m.byName[sig.Name] = sig.Name
+
+		labels := sig.Labels
+		if len(labels) == 0 {
+			labels = []string{prefix + sig.Name}
+		}
+
+		for _, label := range labels {
+			m.byLabel[label] = sig.Name
+		}
+
+		for _, alias := range sig.Aliases {
+			m.byLabel[alias] = sig.Name
+			m.byName[strings.TrimPrefix(alias, prefix)] = sig.Name
+		}
+	}
+
+	return m
+}
+
+// Names returns the names of the configured SIGs, sorted.
+func (m *SIGMap) Names() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, name := range m.byName {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Labels returns the labels which identify a SIG, including the labels of
+// its aliases.
+func (m *SIGMap) Labels(name string) []string {
+	name = m.Canonical(name)
+
+	seen := map[string]bool{m.prefix + name: true}
+	for label, sig := range m.byLabel {
+		if sig == name {
+			seen[label] = true
+		}
+	}
+	for alias, sig := range m.byName {
+		if sig == name {
+			seen[m.prefix+alias] = true
+		}
+	}
+
+	labels := []string{}
+	for label := range seen {
+		labels = append(labels, label)
+	}
+
+	sort.Strings(labels)
+	return labels
+}
+
+// orDefault returns m, or a SIGMap using the default prefix if m is nil.
+func (m *SIGMap) orDefault() *SIGMap {
+	if m == nil {
+		return NewSIGMap(DefaultSIGPrefix, nil)
+	}
+
+	return m
+}
+
+// Lookup returns the SIG a label belongs to.
+func (m *SIGMap) Lookup(label string) (string, bool) {
+	if name, ok := m.byLabel[label]; ok {
+		return name, true
+	}
+
+	if m.prefix != "" && strings.HasPrefix(label, m.prefix) {
+		name := strings.TrimPrefix(label, m.prefix)
+		return m.Canonical(name), name != ""
+	}
+
+	return "", false
+}
+
+// Canonical resolves a SIG name or alias to the canonical SIG name.
+func (m *SIGMap) Canonical(name string) string {
+	name = strings.TrimPrefix(name, m.prefix)
+	if canonical, ok := m.byName[name]; ok {
+		return canonical
+	}
+
+	return name
+}
+
+// SIGs returns the SIGs an issue belongs to. An issue without a SIG label
+// belongs to NoSIG.
+func (m *SIGMap) SIGs(issue Issue) []string {
+	seen := map[string]bool{}
+	sigs := []string{}
+	for _, label := range issue.Labels {
+		name, ok := m.Lookup(label.Name)
+		if !ok || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		sigs = append(sigs, name)
+	}
+
+	if len(sigs) == 0 {
+		return []string{NoSIG}
+	}
+
+	sort.Strings(sigs)
+	return sigs
+}
+
+// HasSIG returns true if an issue belongs to a SIG.
+func (m *SIGMap) HasSIG(issue Issue, sig string) bool {
+	sig = m.Canonical(sig)
+	for _, name := range m.SIGs(issue) {
+		if name == sig {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilterSIG returns the issues which belong to a SIG.
+func (m *SIGMap) FilterSIG(issues []Issue, sig string) []Issue {
+	filtered := []Issue{}
+	for _, issue := range issues {
+		if m.HasSIG(issue, sig) {
+			filtered = append(filtered, issue)
+		}
+	}
+
+	return filtered
+}
+
+// CountBySIG counts issues per SIG. An issue with multiple SIG labels is
+// counted once for each SIG.
+func (m *SIGMap) CountBySIG(issues []Issue) SIGCounts {
+	counts := SIGCounts{}
+	for _, issue := range issues {
+		for _, name := range m.SIGs(issue) {
+			counts[name]++
+		}
+	}
+
+	return counts
+}
+
+// SIGCounts is the count of issues per SIG.
+type SIGCounts map[string]int
+
+// Names returns the SIG names in the counts sorted alphabetically with
+// NoSIG last.
+func (c SIGCounts) Names() []string {
+	out := []string{}
+	for name := range c {
+		out = append(out, name)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i] == NoSIG || out[j] == NoSIG {
+			return out[j] == NoSIG && out[i] != NoSIG
+		}
+		return out[i] < out[j]
+	})
+
+	return out
+}
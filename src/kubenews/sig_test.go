@@ -0,0 +1,60 @@
+package kubenews
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func labeledIssue(number int, labels ...string) Issue {
+	issue := Issue{Number: number, Repository: "org/repo"}
+	for _, label := range labels {
+		issue.Labels = append(issue.Labels, Label{Name: label})
+	}
+
+	return issue
+}
+
+func TestSIGMap(t *testing.T) {
+	m := NewSIGMap("sig/", []SIG{
+		{Name: "node", Aliases: []string{"sig/rktnetes"}},
+		{Name: "cluster-lifecycle", Labels: []string{"sig/cluster-lifecycle", "area/kubeadm"}},
+	})
+
+	cases := []struct {
+		label string
+		sig   string
+		ok    bool
+	}{
+		{label: "sig/node", sig: "node", ok: true},
+		{label: "sig/rktnetes", sig: "node", ok: true},
+		{label: "area/kubeadm", sig: "cluster-lifecycle", ok: true},
+		{label: "sig/storage", sig: "storage", ok: true},
+		{label: "kind/bug", ok: false},
+	}
+
+	for _, c := range cases {
+		sig, ok := m.Lookup(c.label)
+		require.Equal(t, c.ok, ok, c.label)
+		require.Equal(t, c.sig, sig, c.label)
+	}
+
+	require.Equal(t, "node", m.Canonical("rktnetes"))
+	require.Equal(t, "node", m.Canonical("sig/node"))
+}
+
+func TestSIGMapCountBySIG(t *testing.T) {
+	m := NewSIGMap("sig/", []SIG{{Name: "node", Aliases: []string{"sig/rktnetes"}}})
+
+	issues := []Issue{
+		labeledIssue(1, "sig/node", "sig/rktnetes"),
+		labeledIssue(2, "sig/node", "sig/storage"),
+		labeledIssue(3, "kind/bug"),
+	}
+
+	counts := m.CountBySIG(issues)
+	require.Equal(t, SIGCounts{"node": 2, "storage": 1, NoSIG: 1}, counts)
+	require.Equal(t, []string{"node", "storage", NoSIG}, counts.Names())
+
+	require.Len(t, m.FilterSIG(issues, "rktnetes"), 2)
+}
@@ -0,0 +1,273 @@
+package kubenews
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	// DefaultDuplicateThreshold is the cosine similarity above which issues
+	// are reported as likely duplicates.
+	DefaultDuplicateThreshold = 0.6
+
+	// titleWeight is how many times more title terms count than body terms.
+	titleWeight = 3.0
+
+	// maxDocumentFrequency ignores terms which appear in more than this
+	// fraction of issues. They add little signal and a lot of comparisons.
+	maxDocumentFrequency = 0.2
+)
+
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`a an and are as at be but by can do does for from
+		has have how i if in is it its not of on or should so that the this to was we
+		what when where which while why will with you your`) {
+		stopWords[w] = true
+	}
+}
+
+// tokenize splits text into lower case terms, dropping stop words and
+// single characters.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	terms := []string{}
+	for _, f := range fields {
+		if len(f) < 2 || stopWords[f] {
+			continue
+		}
+		terms = append(terms, f)
+	}
+
+	return terms
+}
+
+// termFrequencies counts the weighted terms in an issue's title and body.
+func termFrequencies(issue Issue) map[string]float64 {
+	tf := map[string]float64{}
+	for _, term := range tokenize(issue.Title) {
+		tf[term] += titleWeight
+	}
+	for _, term := range tokenize(issue.Body) {
+		tf[term]++
+	}
+
+	return tf
+}
+
+type posting struct {
+	doc    int
+	weight float64
+}
+
+// SimilarityIndex finds similar issues using TF-IDF weighted cosine similarity
+// over titles and bodies.
+type SimilarityIndex struct {
+	issues   []Issue
+	vectors  []map[string]float64
+	idf      map[string]float64
+	postings map[string][]posting
+}
+
+// NewSimilarityIndex creates an instance of SimilarityIndex over issues.
+func NewSimilarityIndex(issues []Issue) *SimilarityIndex {
+	s := &SimilarityIndex{
+		issues:   issues,
+		idf:      map[string]float64{},
+		postings: map[string][]posting{},
+	}
+
+	tfs := make([]map[string]float64, len(issues))
+	df := map[string]int{}
+	for i, issue := range issues {
+		tfs[i] = termFrequencies(issue)
+		for term := range tfs[i] {
+			df[term]++
+		}
+	}
+
+	n := float64(len(issues))
+	for term, count := range df {
+		if len(issues) > 10 && float64(count)/n > maxDocumentFrequency {
+			continue
+		}
+		s.idf[term] = math.Log(1 + n/float64(count))
+	}
+
+	for i, tf := range tfs {
+		v := s.vector(tf)
+		s.vectors = append(s.vectors, v)
+		for term, weight := range v {
+			s.postings[term] = append(s.postings[term], posting{doc: i, weight: weight})
+		}
+	}
+
+	return s
+}
+
+// vector converts term frequencies to a unit length TF-IDF vector.
+func (s *SimilarityIndex) vector(tf map[string]float64) map[string]float64 {
+	v := map[string]float64{}
+	norm := 0.0
+	for term, freq := range tf {
+		idf, ok := s.idf[term]
+		if !ok {
+			continue
+		}
+
+		w := (1 + math.Log(freq)) * idf
+		v[term] = w
+		norm += w * w
+	}
+
+	norm = math.Sqrt(norm)
+	for term := range v {
+		v[term] /= norm
+	}
+
+	return v
+}
+
+// Match is an issue similar to another issue.
+type Match struct {
+	Issue Issue
+	Score float64
+}
+
+// scores computes the similarity of a vector to every indexed issue sharing
+// a term with it.
+func (s *SimilarityIndex) scores(v map[string]float64) map[int]float64 {
+	scores := map[int]float64{}
+	for term, weight := range v {
+		for _, p := range s.postings[term] {
+			scores[p.doc] += weight * p.weight
+		}
+	}
+
+	return scores
+}
+
+// Similar returns the indexed issues with a similarity to issue of at least
+// threshold, most similar first. The issue itself is never included.
+func (s *SimilarityIndex) Similar(issue Issue, threshold float64) []Match {
+	matches := []Match{}
+	for doc, score := range s.scores(s.vector(termFrequencies(issue))) {
+		other := s.issues[doc]
+		if score < threshold || (other.Number == issue.Number && other.Repository == issue.Repository) {
+			continue
+		}
+
+		matches = append(matches, Match{Issue: other, Score: score})
+	}
+
+	sortMatches(matches)
+	return matches
+}
+
+func sortMatches(matches []Match) {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Issue.Number < matches[j].Issue.Number
+	})
+}
+
+// DuplicatePair is a pair of similar issues in a cluster.
+type DuplicatePair struct {
+	A     int
+	B     int
+	Score float64
+}
+
+// DuplicateCluster is a group of issues which are likely duplicates of each
+// other. Issues are connected by pairs with a similarity of at least the
+// threshold.
+type DuplicateCluster struct {
+	Issues []Issue
+	Pairs  []DuplicatePair
+}
+
+// Score returns the highest similarity in the cluster.
+func (c DuplicateCluster) Score() float64 {
+	max := 0.0
+	for _, p := range c.Pairs {
+		max = math.Max(max, p.Score)
+	}
+
+	return max
+}
+
+// Clusters groups the indexed issues into clusters of likely duplicates.
+func (s *SimilarityIndex) Clusters(threshold float64) []DuplicateCluster {
+	parent := make([]int, len(s.issues))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	type indexedPair struct {
+		i, j  int
+		score float64
+	}
+
+	pairs := []indexedPair{}
+	for i, v := range s.vectors {
+		for j, score := range s.scores(v) {
+			if j <= i || score < threshold {
+				continue
+			}
+
+			pairs = append(pairs, indexedPair{i: i, j: j, score: score})
+			parent[find(j)] = find(i)
+		}
+	}
+
+	byRoot := map[int]*DuplicateCluster{}
+	roots := []int{}
+	for i, issue := range s.issues {
+		root := find(i)
+		c, ok := byRoot[root]
+		if !ok {
+			c = &DuplicateCluster{}
+			byRoot[root] = c
+			roots = append(roots, root)
+		}
+		c.Issues = append(c.Issues, issue)
+	}
+
+	for _, p := range pairs {
+		c := byRoot[find(p.i)]
+		c.Pairs = append(c.Pairs, DuplicatePair{A: s.issues[p.i].Number, B: s.issues[p.j].Number, Score: p.score})
+	}
+
+	clusters := []DuplicateCluster{}
+	for _, root := range roots {
+		c := byRoot[root]
+		if len(c.Issues) < 2 {
+			continue
+		}
+
+		sort.Slice(c.Pairs, func(i, j int) bool { return c.Pairs[i].Score > c.Pairs[j].Score })
+		clusters = append(clusters, *c)
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].Score() > clusters[j].Score()
+	})
+
+	return clusters
+}
@@ -0,0 +1,53 @@
+package kubenews
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func textIssue(number int, title, body string) Issue {
+	created := time.Date(2017, 3, number, 0, 0, 0, 0, time.UTC)
+	return Issue{Number: number, Repository: "org/repo", State: "open", Title: title, Body: body, CreatedAt: &created}
+}
+
+func TestTokenize(t *testing.T) {
+	require.Equal(t, []string{"kubelet", "fails", "start", "node", "v1"},
+		tokenize("The kubelet fails to start on a node (v1.7)"))
+}
+
+func TestSimilarityClusters(t *testing.T) {
+	issues := []Issue{
+		textIssue(1, "kubelet fails to start on CoreOS", "kubelet crashes with cgroup driver error on coreos"),
+		textIssue(2, "Kubelet fails to start on coreos nodes", "cgroup driver mismatch makes kubelet crash"),
+		textIssue(3, "kubectl apply ignores namespace flag", "running kubectl apply with --namespace creates objects in default"),
+		textIssue(4, "Add support for IPv6 services", "services should allocate ipv6 cluster ips"),
+		textIssue(5, "kubelet won't start on CoreOS", "cgroup driver error"),
+	}
+
+	index := NewSimilarityIndex(issues)
+
+	clusters := index.Clusters(0.3)
+	require.Len(t, clusters, 1)
+
+	numbers := []int{}
+	for _, issue := range clusters[0].Issues {
+		numbers = append(numbers, issue.Number)
+	}
+	require.Equal(t, []int{1, 2, 5}, numbers)
+	require.True(t, clusters[0].Score() >= 0.3)
+
+	matches := index.Similar(issues[0], 0.3)
+	require.NotEmpty(t, matches)
+	for _, m := range matches {
+		require.NotEqual(t, 1, m.Issue.Number)
+	}
+
+	dups := findPossibleDuplicates([]Issue{issues[4]}, issues, 0.3)
+	require.Len(t, dups, 1)
+	require.Equal(t, 5, dups[0].Issue.Number)
+	for _, m := range dups[0].Matches {
+		require.True(t, m.Issue.Number < 5)
+	}
+}
@@ -0,0 +1,345 @@
+package kubenews
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+)
+
+// Dimensions open backlog counts are recorded for.
+const (
+	SnapshotRepository = "repository"
+	SnapshotLabel      = "label"
+	SnapshotSIG        = "sig"
+	SnapshotMilestone  = "milestone"
+)
+
+// Snapshot is the number of open issues in a repository on a day, for one
+// value of a dimension, e.g. label sig/storage.
+type Snapshot struct {
+	Day        time.Time `db:"day"`
+	Repository string    `db:"repository"`
+	Dimension  string    `db:"dimension"`
+	Value      string    `db:"value"`
+	Open       int       `db:"open_count"`
+}
+
+// issueState is the state of an issue at a point in time.
+type issueState struct {
+	open      bool
+	labels    map[string]bool
+	milestone string
+}
+
+// stateAt reconstructs the state of an issue at a point in time by rewinding
+// events which happened after it from the issue's current state. Events must
+// be ordered oldest first. It returns false if the issue didn't exist yet.
+func stateAt(issue Issue, events []IssueEvent, at time.Time) (issueState, bool) {
+	if issue.CreatedAt == nil || !issue.CreatedAt.Before(at) {
+		return issueState{}, false
+	}
+
+	state := issueState{
+		open:      issue.State == "open",
+		labels:    map[string]bool{},
+		milestone: issue.Milestone,
+	}
+
+	for _, label := range issue.Labels {
+		state.labels[label.Name] = true
+	}
+
+	rewoundClose := false
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		if e.CreatedAt == nil || e.CreatedAt.Before(at) {
+			break
+		}
+
+		switch e.Event {
+		case "closed":
+			state.open = true
+			rewoundClose = true
+		case "reopened":
+			state.open = false
+		case "labeled":
+			delete(state.labels, e.Label)
+		case "unlabeled":
+			state.labels[e.Label] = true
+		case "milestoned":
+			state.milestone = ""
+		case "demilestoned":
+			state.milestone = e.Milestone
+		}
+	}
+
+	// the event log may not reach back far enough to contain the close
+	if !state.open && !rewoundClose && issue.ClosedAt != nil && !issue.ClosedAt.Before(at) {
+		state.open = true
+	}
+
+	return state, true
+}
+
+// BacklogSnapshots counts the issues in a history which were open at the end
+// of a day, per repository, label, SIG and milestone.
+func BacklogSnapshots(day time.Time, repository string, h *History, sigs *SIGMap) []Snapshot {
+	day = truncateDay(day)
+	at := day.AddDate(0, 0, 1)
+	if now := time.Now(); at.After(now) {
+		at = now
+	}
+
+	counts := map[string]map[string]int{
+		SnapshotRepository: {repository: 0},
+		SnapshotLabel:      {},
+		SnapshotSIG:        {},
+		SnapshotMilestone:  {},
+	}
+
+	for _, issue := range h.Issues {
+		state, ok := stateAt(issue, h.Events[issue.Number], at)
+		if !ok || !state.open {
+			continue
+		}
+
+		counts[SnapshotRepository][repository]++
+
+		past := Issue{}
+		for name := range state.labels {
+			counts[SnapshotLabel][name]++
+			past.Labels = append(past.Labels, Label{Name: name})
+		}
+
+		for _, sig := range sigs.SIGs(past) {
+			counts[SnapshotSIG][sig]++
+		}
+
+		if state.milestone != "" {
+			counts[SnapshotMilestone][state.milestone]++
+		}
+	}
+
+	snapshots := []Snapshot{}
+	for _, dimension := range []string{SnapshotRepository, SnapshotLabel, SnapshotSIG, SnapshotMilestone} {
+		values := []string{}
+		for value := range counts[dimension] {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+
+		for _, value := range values {
+			snapshots = append(snapshots, Snapshot{
+				Day:        day,
+				Repository: repository,
+				Dimension:  dimension,
+				Value:      value,
+				Open:       counts[dimension][value],
+			})
+		}
+	}
+
+	return snapshots
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// LoadRepositoryHistory loads all issues for a repository with their events.
+// Comments are not loaded.
+func (s *sqlStore) LoadRepositoryHistory(repository string) (*History, error) {
+	issues := []Issue{}
+	if err := s.selectx(&issues, repositoryIssuesSQL, repository); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve issues")
+	}
+
+	events := []IssueEvent{}
+	if err := s.selectx(&events, repositoryEventsSQL, repository); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve events")
+	}
+
+	return NewHistory(issues, nil, events), nil
+}
+
+// SaveSnapshots stores snapshots, replacing the existing snapshots of the
+// same repository and day.
+func (s *sqlStore) SaveSnapshots(snapshots []Snapshot) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "save snapshot failure")
+	}
+
+	for _, snapshot := range snapshotDays(snapshots) {
+		if err := s.exec(tx, deleteSnapshotsSQL, snapshot.Day, snapshot.Repository); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "delete snapshots")
+		}
+	}
+
+	for _, snapshot := range snapshots {
+		if err := s.exec(tx, insertSnapshotSQL, snapshot.Day, snapshot.Repository, snapshot.Dimension,
+			snapshot.Value, snapshot.Open); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "insert snapshot")
+		}
+	}
+
+	log.WithField("snapshotCount", len(snapshots)).Info("saved backlog snapshots")
+	return tx.Commit()
+}
+
+// Trend is a series of snapshots for a dimension value, oldest first.
+type Trend struct {
+	Repository string
+	Dimension  string
+	Value      string
+	Snapshots  []Snapshot
+}
+
+// LoadTrend loads the snapshots for a dimension value in a time range. Days
+// the repository was snapshotted without the value have an open count of 0.
+func (s *sqlStore) LoadTrend(repository, dimension, value string, from, to time.Time) (*Trend, error) {
+	snapshots := []Snapshot{}
+	if err := s.selectx(&snapshots, trendSQL, repository, dimension, value, from, to); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve snapshots")
+	}
+
+	days := []Snapshot{}
+	if err := s.selectx(&days, trendSQL, repository, SnapshotRepository, repository, from, to); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve snapshot days")
+	}
+
+	return &Trend{
+		Repository: repository,
+		Dimension:  dimension,
+		Value:      value,
+		Snapshots:  fillTrend(snapshots, days, dimension, value),
+	}, nil
+}
+
+// snapshotDays returns a snapshot for each repository and day in snapshots.
+func snapshotDays(snapshots []Snapshot) []Snapshot {
+	seen := map[string]bool{}
+	days := []Snapshot{}
+	for _, snapshot := range snapshots {
+		key := snapshot.Repository + " " + snapshot.Day.Format(dateFormat)
+		if !seen[key] {
+			seen[key] = true
+			days = append(days, snapshot)
+		}
+	}
+
+	return days
+}
+
+// fillTrend adds an open count of 0 to the snapshots of a dimension value for
+// each day the repository was snapshotted without it. Only values with open
+// issues are stored, so a missing value was closed out, while a missing day
+// wasn't snapshotted. Both are ordered by day.
+func fillTrend(snapshots, days []Snapshot, dimension, value string) []Snapshot {
+	byDay := map[string]Snapshot{}
+	for _, snapshot := range snapshots {
+		byDay[snapshot.Day.Format(dateFormat)] = snapshot
+	}
+
+	filled := []Snapshot{}
+	for _, day := range days {
+		key := day.Day.Format(dateFormat)
+		snapshot, ok := byDay[key]
+		if !ok {
+			snapshot = Snapshot{Day: day.Day, Repository: day.Repository, Dimension: dimension, Value: value}
+		}
+		delete(byDay, key)
+		filled = append(filled, snapshot)
+	}
+
+	// snapshots saved without the repository count
+	for _, snapshot := range byDay {
+		filled = append(filled, snapshot)
+	}
+	sort.SliceStable(filled, func(i, j int) bool { return filled[i].Day.Before(filled[j].Day) })
+
+	return filled
+}
+
+// Max returns the largest open count in the trend.
+func (t *Trend) Max() int {
+	max := 0
+	for _, s := range t.Snapshots {
+		if s.Open > max {
+			max = s.Open
+		}
+	}
+
+	return max
+}
+
+// WriteChart writes the trend as a text bar chart with one row per day.
+func (t *Trend) WriteChart(w io.Writer, width int) error {
+	p := &printer{w: w}
+	p.printf("%s %s=%s open backlog\n\n", t.Repository, t.Dimension, t.Value)
+
+	max := t.Max()
+	for _, s := range t.Snapshots {
+		bar := 0
+		if max > 0 {
+			bar = s.Open * width / max
+		}
+		p.printf("%s %s %d\n", s.Day.Format(dateFormat), strings.Repeat("#", bar), s.Open)
+	}
+
+	return p.err
+}
+
+// WriteCSV writes the trend as CSV with a day and open count per row.
+func (t *Trend) WriteCSV(w io.Writer) error {
+	p := &printer{w: w}
+	p.printf("day,open\n")
+	for _, s := range t.Snapshots {
+		p.printf("%s,%d\n", s.Day.Format(dateFormat), s.Open)
+	}
+
+	return p.err
+}
+
+var (
+	insertSnapshotSQL = `
+  INSERT INTO backlog_snapshots
+  (day, repository, dimension, value, open_count)
+
+  VALUES
+  ($1, $2, $3, $4, $5)
+
+  ON conflict (day, repository, dimension, value)
+  DO UPDATE SET open_count = $5`
+
+	deleteSnapshotsSQL = `
+  DELETE FROM backlog_snapshots
+  WHERE day = $1 AND repository = $2`
+
+	trendSQL = `
+  SELECT day, repository, dimension, value, open_count
+  FROM backlog_snapshots
+  WHERE repository = $1 AND dimension = $2 AND value = $3 AND day >= $4 AND day < $5
+  ORDER BY day`
+
+	repositoryIssuesSQL = `
+  SELECT id, number, state, title, body, created_by, labels, assignee, closed_at,
+    created_at, updated_at, milestone, repository, pull_request
+  FROM issues
+  WHERE repository = $1
+  ORDER BY number`
+
+	repositoryEventsSQL = `
+  SELECT id, repository, issue_number, event, actor, label, milestone, assignee,
+    commit_id, created_at
+  FROM issue_events
+  WHERE repository = $1
+  ORDER BY created_at, id`
+)
@@ -0,0 +1,74 @@
+package kubenews
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBacklogSnapshots(t *testing.T) {
+	day := func(d int) *time.Time {
+		t := time.Date(2017, 3, d, 12, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	// opened on the 1st with sig/storage, moved to sig/node on the 3rd
+	moved := labeledIssue(1, "sig/node")
+	moved.State = "open"
+	moved.CreatedAt = day(1)
+
+	// opened on the 1st, closed on the 4th, no events synced
+	closed := labeledIssue(2, "sig/storage")
+	closed.State = "closed"
+	closed.CreatedAt = day(1)
+	closed.ClosedAt = day(4)
+
+	// opened on the 2nd, closed on the 2nd, reopened on the 5th
+	reopened := labeledIssue(3)
+	reopened.State = "open"
+	reopened.Milestone = "v1.7"
+	reopened.CreatedAt = day(2)
+
+	events := []IssueEvent{
+		{IssueNumber: 1, Event: "labeled", Label: "sig/storage", CreatedAt: day(1)},
+		{IssueNumber: 3, Event: "closed", CreatedAt: day(2)},
+		{IssueNumber: 1, Event: "unlabeled", Label: "sig/storage", CreatedAt: day(3)},
+		{IssueNumber: 1, Event: "labeled", Label: "sig/node", CreatedAt: day(3)},
+		{IssueNumber: 3, Event: "reopened", CreatedAt: day(5)},
+		{IssueNumber: 3, Event: "milestoned", Milestone: "v1.7", CreatedAt: day(5)},
+	}
+
+	h := NewHistory([]Issue{moved, closed, reopened}, nil, events)
+	sigs := NewSIGMap(DefaultSIGPrefix, nil)
+
+	counts := func(d int) map[string]int {
+		out := map[string]int{}
+		for _, s := range BacklogSnapshots(*day(d), "org/repo", h, sigs) {
+			out[s.Dimension+":"+s.Value] = s.Open
+		}
+		return out
+	}
+
+	require.Equal(t, map[string]int{
+		"repository:org/repo": 2,
+		"label:sig/storage":   2,
+		"sig:storage":         2,
+	}, counts(2))
+
+	require.Equal(t, map[string]int{
+		"repository:org/repo": 2,
+		"label:sig/node":      1,
+		"label:sig/storage":   1,
+		"sig:node":            1,
+		"sig:storage":         1,
+	}, counts(3))
+
+	require.Equal(t, map[string]int{
+		"repository:org/repo": 2,
+		"label:sig/node":      1,
+		"sig:node":            1,
+		"sig:none":            1,
+		"milestone:v1.7":      1,
+	}, counts(5))
+}
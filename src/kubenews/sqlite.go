@@ -0,0 +1,289 @@
+package kubenews
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	// SQLite is the store for running without a database server
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore is a Store in a single SQLite file, for running kubenews on a
+// laptop without a database server.
+type SQLiteStore struct {
+	*sqlStore
+	DB *sqlx.DB
+}
+
+// NewSQLiteStore opens a SQLite store, creating the file if it doesn't exist.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sqlx.Connect("sqlite3", path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open %s", path)
+	}
+
+	// SQLite allows one writer at a time, and each connection to :memory: is
+	// a separate database.
+	db.SetMaxOpenConns(1)
+
+	return &SQLiteStore{
+		sqlStore: &sqlStore{
+			db:     db,
+			schema: sqliteSchemaSQL,
+			rebind: sqliteRebind,
+			arg:    sqliteArg,
+		},
+		DB: db,
+	}, nil
+}
+
+// Migrate creates the tables the store needs if they don't exist, then
+// rebuilds tables created with constraints which have since changed, as
+// SQLite can't drop a constraint.
+func (s *SQLiteStore) Migrate() error {
+	if err := s.sqlStore.Migrate(); err != nil {
+		return err
+	}
+
+	// issues numbered once across repositories have an automatic index for
+	// UNIQUE (number)
+	var old int
+	if err := s.DB.Get(&old, sqliteUniqueNumberSQL); err != nil {
+		return errors.Wrap(err, "migrate schema")
+	}
+	if old == 0 {
+		return nil
+	}
+
+	tx, err := s.DB.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "migrate schema")
+	}
+
+	for _, stmt := range sqliteRebuildIssuesSQL {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "rebuild issues")
+		}
+	}
+
+	return errors.Wrap(tx.Commit(), "rebuild issues")
+}
+
+var placeholderRe = regexp.MustCompile(`\$(\d+)`)
+
+// sqliteRebind replaces queries SQLite can't run, then numbers placeholders
+// the SQLite way.
+func sqliteRebind(query string) string {
+	if q, ok := sqliteQueries[query]; ok {
+		query = q
+	}
+
+	return placeholderRe.ReplaceAllString(query, "?$1")
+}
+
+// sqliteArg stores times in UTC, so they compare as text, and labels as JSON
+// text rather than a blob.
+func sqliteArg(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC()
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return v.UTC()
+	case Labels:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		return string(b)
+	}
+
+	return v
+}
+
+var (
+	// sqliteQueries are the SQLite versions of Postgres queries.
+	sqliteQueries = map[string]string{
+		labeledIssuesSQL: `
+  SELECT id, number, state, title, body, created_by, labels, assignee, closed_at,
+    created_at, updated_at, milestone, repository, pull_request
+  FROM issues
+  WHERE repository = $1
+    AND EXISTS (SELECT 1 FROM json_each(labels) l
+      WHERE json_extract(l.value, '$.Name') IN (SELECT value FROM json_each($2)))
+  ORDER BY number`,
+
+		commentsForLabeledIssuesSQL: `
+  SELECT c.id, c.repository, c.issue_number, c.created_by, c.body, c.created_at, c.updated_at
+  FROM comments c
+  JOIN issues i ON i.repository = c.repository AND i.number = c.issue_number
+  WHERE i.repository = $1
+    AND EXISTS (SELECT 1 FROM json_each(i.labels) l
+      WHERE json_extract(l.value, '$.Name') IN (SELECT value FROM json_each($2)))
+  ORDER BY c.created_at, c.id`,
+	}
+
+	sqliteUniqueNumberSQL = `
+  SELECT COUNT(*) FROM sqlite_master
+  WHERE type = 'index' AND tbl_name = 'issues' AND name LIKE 'sqlite_autoindex_issues_%'`
+
+	// sqliteRebuildIssuesSQL copies the issues into a table without
+	// UNIQUE (number), so issue numbers are only unique within a repository.
+	sqliteRebuildIssuesSQL = []string{
+		`CREATE TABLE issues_new (
+    id integer PRIMARY KEY,
+    number integer NOT NULL,
+    state text NOT NULL,
+    title text NOT NULL,
+    body text NOT NULL DEFAULT '',
+    created_by text NOT NULL DEFAULT '',
+    labels text NOT NULL DEFAULT '[]',
+    assignee text NOT NULL DEFAULT '',
+    closed_at timestamp,
+    created_at timestamp,
+    updated_at timestamp,
+    milestone text NOT NULL DEFAULT '',
+    repository text NOT NULL,
+    pull_request boolean NOT NULL DEFAULT false
+  )`,
+
+		`INSERT INTO issues_new
+  (id, number, state, title, body, created_by, labels, assignee, closed_at, created_at,
+  updated_at, milestone, repository, pull_request)
+  SELECT id, number, state, title, body, created_by, labels, assignee, closed_at, created_at,
+    updated_at, milestone, repository, pull_request
+  FROM issues`,
+
+		`DROP TABLE issues`,
+
+		`ALTER TABLE issues_new RENAME TO issues`,
+
+		`CREATE UNIQUE INDEX issues_repository_number_idx ON issues (repository, number)`,
+	}
+
+	// sqliteSchemaSQL is the schema the Store needs. Times are declared as
+	// timestamp so the driver converts them.
+	sqliteSchemaSQL = []string{
+		`CREATE TABLE IF NOT EXISTS issues (
+    id integer PRIMARY KEY,
+    number integer NOT NULL,
+    state text NOT NULL,
+    title text NOT NULL,
+    body text NOT NULL DEFAULT '',
+    created_by text NOT NULL DEFAULT '',
+    labels text NOT NULL DEFAULT '[]',
+    assignee text NOT NULL DEFAULT '',
+    closed_at timestamp,
+    created_at timestamp,
+    updated_at timestamp,
+    milestone text NOT NULL DEFAULT '',
+    repository text NOT NULL,
+    pull_request boolean NOT NULL DEFAULT false
+  )`,
+
+		`CREATE UNIQUE INDEX IF NOT EXISTS issues_repository_number_idx ON issues (repository, number)`,
+
+		`CREATE TABLE IF NOT EXISTS labels (
+    name text PRIMARY KEY,
+    url text NOT NULL,
+    color text NOT NULL,
+    active boolean NOT NULL DEFAULT true
+  )`,
+
+		`CREATE TABLE IF NOT EXISTS comments (
+    id integer PRIMARY KEY,
+    repository text NOT NULL,
+    issue_number integer NOT NULL,
+    created_by text NOT NULL DEFAULT '',
+    body text NOT NULL DEFAULT '',
+    created_at timestamp,
+    updated_at timestamp
+  )`,
+
+		`CREATE INDEX IF NOT EXISTS comments_issue_idx ON comments (repository, issue_number)`,
+
+		`CREATE TABLE IF NOT EXISTS issue_events (
+    id integer PRIMARY KEY,
+    repository text NOT NULL,
+    issue_number integer NOT NULL,
+    event text NOT NULL,
+    actor text NOT NULL DEFAULT '',
+    label text NOT NULL DEFAULT '',
+    milestone text NOT NULL DEFAULT '',
+    assignee text NOT NULL DEFAULT '',
+    commit_id text NOT NULL DEFAULT '',
+    created_at timestamp
+  )`,
+
+		`CREATE INDEX IF NOT EXISTS issue_events_issue_idx ON issue_events (repository, issue_number)`,
+
+		`CREATE TABLE IF NOT EXISTS backlog_snapshots (
+    day date NOT NULL,
+    repository text NOT NULL,
+    dimension text NOT NULL,
+    value text NOT NULL,
+    open_count integer NOT NULL,
+    PRIMARY KEY (day, repository, dimension, value)
+  )`,
+
+		`CREATE TABLE IF NOT EXISTS sync_runs (
+    id integer PRIMARY KEY,
+    repository text NOT NULL,
+    resource text NOT NULL,
+    started_at timestamp NOT NULL,
+    finished_at timestamp NOT NULL,
+    pages integer NOT NULL,
+    api_calls integer NOT NULL,
+    fetched integer NOT NULL,
+    inserted integer NOT NULL,
+    updated integer NOT NULL,
+    error text NOT NULL DEFAULT ''
+  )`,
+
+		`CREATE INDEX IF NOT EXISTS sync_runs_started_idx ON sync_runs (repository, resource, started_at)`,
+
+		`CREATE TABLE IF NOT EXISTS issue_references (
+    repository text NOT NULL,
+    number integer NOT NULL,
+    comment_id integer NOT NULL DEFAULT 0,
+    target_repository text NOT NULL,
+    target_number integer NOT NULL,
+    kind text NOT NULL,
+    PRIMARY KEY (repository, number, comment_id, target_repository, target_number)
+  )`,
+
+		`CREATE INDEX IF NOT EXISTS issue_references_target_idx ON issue_references (target_repository, target_number)`,
+
+		`CREATE TABLE IF NOT EXISTS digests (
+    id integer PRIMARY KEY,
+    repository text NOT NULL,
+    list text NOT NULL,
+    sig text NOT NULL DEFAULT '',
+    period_from date NOT NULL,
+    period_to date NOT NULL,
+    title text NOT NULL,
+    markdown text NOT NULL,
+    recipients text NOT NULL,
+    message_id text NOT NULL,
+    sent_at timestamp NOT NULL,
+    status text NOT NULL DEFAULT 'sent',
+    UNIQUE (repository, list, period_from, period_to)
+  )`,
+
+		`CREATE TABLE IF NOT EXISTS webhook_alerts (
+    repository text NOT NULL,
+    issue_number integer NOT NULL,
+    alert text NOT NULL,
+    route text NOT NULL,
+    sent_at timestamp NOT NULL,
+    PRIMARY KEY (repository, issue_number, alert, route)
+  )`,
+	}
+)
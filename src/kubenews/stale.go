@@ -0,0 +1,279 @@
+package kubenews
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultBots are the Kubernetes automation accounts. Their activity doesn't
+// count as human activity.
+var DefaultBots = []string{
+	"k8s-bot",
+	"k8s-ci-robot",
+	"k8s-github-robot",
+	"k8s-merge-robot",
+	"googlebot",
+	"fejta-bot",
+}
+
+// Bots identifies automation accounts.
+type Bots map[string]bool
+
+// NewBots creates an instance of Bots. If no logins are given, DefaultBots are
+// used.
+func NewBots(logins []string) Bots {
+	if len(logins) == 0 {
+		logins = DefaultBots
+	}
+
+	bots := Bots{}
+	for _, login := range logins {
+		bots[login] = true
+	}
+
+	return bots
+}
+
+// IsBot returns true if a login belongs to an automation account.
+func (b Bots) IsBot(login string) bool {
+	return b[login] || strings.HasSuffix(login, "[bot]")
+}
+
+// StaleOptions are options for finding stale issues.
+type StaleOptions struct {
+	// Days without human activity before an issue is stale.
+	Days int
+	Now  time.Time
+	SIG  string
+	SIGs *SIGMap
+	Bots Bots
+	// ExcludeLifecycles are lifecycle states which are never reported as
+	// stale. It defaults to frozen.
+	ExcludeLifecycles []string
+	Taxonomy          *Taxonomy
+}
+
+// StaleIssue is an issue or pull request without recent human activity.
+type StaleIssue struct {
+	Issue        Issue
+	LastActivity time.Time
+	LastActor    string
+	// WaitingOnReviewer is true for pull requests where the author was the
+	// last human to comment, so a reviewer is holding things up.
+	WaitingOnReviewer bool
+}
+
+// Idle returns how long the issue has been without human activity.
+func (s StaleIssue) Idle(now time.Time) time.Duration {
+	return now.Sub(s.LastActivity)
+}
+
+// StaleGroup is the stale issues for a SIG and assignee.
+type StaleGroup struct {
+	SIG      string
+	Assignee string
+	Issues   []StaleIssue
+}
+
+// StaleReport lists stale issues grouped by SIG and assignee.
+type StaleReport struct {
+	Repository string
+	Days       int
+	Now        time.Time
+	Groups     []StaleGroup
+}
+
+// unassigned is the assignee used to group issues without an assignee.
+const unassigned = "unassigned"
+
+// FindStale finds open issues and pull requests in a history without human
+// activity for opts.Days.
+func FindStale(repository string, h *History, opts StaleOptions) *StaleReport {
+	sigs := opts.SIGs.orDefault()
+
+	taxonomy := opts.Taxonomy
+	if taxonomy == nil {
+		taxonomy = NewTaxonomy(nil)
+	}
+
+	bots := opts.Bots
+	if bots == nil {
+		bots = NewBots(nil)
+	}
+
+	excluded := opts.ExcludeLifecycles
+	if excluded == nil {
+		excluded = []string{"frozen"}
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	cutoff := now.AddDate(0, 0, -opts.Days)
+
+	groups := map[[2]string][]StaleIssue{}
+	for _, issue := range h.Issues {
+		if issue.State != "open" || issue.CreatedAt == nil {
+			continue
+		}
+
+		if hasLifecycle(taxonomy.Classify(issue), excluded) {
+			continue
+		}
+
+		stale := lastHumanActivity(issue, h.Comments[issue.Number], h.Events[issue.Number], bots)
+		if !stale.LastActivity.Before(cutoff) {
+			continue
+		}
+
+		assignee := issue.Assignee
+		if assignee == "" {
+			assignee = unassigned
+		}
+
+		for _, sig := range sigs.SIGs(issue) {
+			if opts.SIG != "" && sig != sigs.Canonical(opts.SIG) {
+				continue
+			}
+
+			key := [2]string{sig, assignee}
+			groups[key] = append(groups[key], stale)
+		}
+	}
+
+	report := &StaleReport{Repository: repository, Days: opts.Days, Now: now}
+
+	sigCounts := SIGCounts{}
+	for key := range groups {
+		sigCounts[key[0]]++
+	}
+
+	for _, sig := range sigCounts.Names() {
+		assignees := []string{}
+		for key := range groups {
+			if key[0] == sig {
+				assignees = append(assignees, key[1])
+			}
+		}
+		sort.Strings(assignees)
+
+		for _, assignee := range assignees {
+			issues := groups[[2]string{sig, assignee}]
+			sort.Slice(issues, func(i, j int) bool {
+				return issues[i].LastActivity.Before(issues[j].LastActivity)
+			})
+
+			report.Groups = append(report.Groups, StaleGroup{SIG: sig, Assignee: assignee, Issues: issues})
+		}
+	}
+
+	return report
+}
+
+func hasLifecycle(d Dimensions, lifecycles []string) bool {
+	for _, lifecycle := range lifecycles {
+		if d.Has(DimensionLifecycle, lifecycle) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lastHumanActivity finds the last time a human commented on or changed an
+// issue. Comments and events must be ordered oldest first.
+func lastHumanActivity(issue Issue, comments []Comment, events []IssueEvent, bots Bots) StaleIssue {
+	stale := StaleIssue{
+		Issue:        issue,
+		LastActivity: *issue.CreatedAt,
+		LastActor:    issue.User,
+	}
+
+	lastAuthorComment := time.Time{}
+	lastReviewerComment := time.Time{}
+
+	for _, c := range comments {
+		if c.CreatedAt == nil || bots.IsBot(c.User) {
+			continue
+		}
+
+		if c.User == issue.User {
+			lastAuthorComment = *c.CreatedAt
+		} else {
+			lastReviewerComment = *c.CreatedAt
+		}
+
+		if c.CreatedAt.After(stale.LastActivity) {
+			stale.LastActivity = *c.CreatedAt
+			stale.LastActor = c.User
+		}
+	}
+
+	for _, e := range events {
+		if e.CreatedAt == nil || e.Actor == "" || bots.IsBot(e.Actor) {
+			continue
+		}
+
+		if e.CreatedAt.After(stale.LastActivity) {
+			stale.LastActivity = *e.CreatedAt
+			stale.LastActor = e.Actor
+		}
+	}
+
+	if issue.PullRequest {
+		stale.WaitingOnReviewer = lastReviewerComment.IsZero() || lastAuthorComment.After(lastReviewerComment)
+	}
+
+	return stale
+}
+
+// Title returns the title of the report.
+func (r *StaleReport) Title() string {
+	return fmt.Sprintf("%s: no human activity for %d days", r.Repository, r.Days)
+}
+
+// WriteMarkdown writes the report to w as Markdown.
+func (r *StaleReport) WriteMarkdown(w io.Writer) error {
+	p := &printer{w: w}
+	p.printf("# %s\n\n", escapeMarkdown(r.Title()))
+
+	if len(r.Groups) == 0 {
+		p.printf("None\n")
+		return p.err
+	}
+
+	sig := ""
+	for _, g := range r.Groups {
+		if g.SIG != sig {
+			sig = g.SIG
+			if sig == NoSIG {
+				p.printf("## No SIG\n\n")
+			} else {
+				p.printf("## sig/%s\n\n", sig)
+			}
+		}
+
+		p.printf("### %s\n\n", g.Assignee)
+		for _, s := range g.Issues {
+			kind := "issue"
+			if s.Issue.PullRequest {
+				kind = "PR"
+			}
+
+			waiting := ""
+			if s.WaitingOnReviewer {
+				waiting = " **waiting on reviewer**"
+			}
+
+			p.printf("* %s (%s, idle %s, last @%s)%s\n", issueLink(s.Issue), kind,
+				FormatDuration(s.Idle(r.Now)), escapeMarkdown(s.LastActor), waiting)
+		}
+		p.printf("\n")
+	}
+
+	return p.err
+}
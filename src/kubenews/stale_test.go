@@ -0,0 +1,70 @@
+package kubenews
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindStale(t *testing.T) {
+	now := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+	ago := func(days int) *time.Time {
+		t := now.AddDate(0, 0, -days)
+		return &t
+	}
+
+	idle := labeledIssue(1, "sig/node")
+	idle.State = "open"
+	idle.User = "author"
+	idle.Assignee = "alice"
+	idle.CreatedAt = ago(100)
+
+	frozen := labeledIssue(2, "sig/node", "lifecycle/frozen")
+	frozen.State = "open"
+	frozen.CreatedAt = ago(100)
+
+	active := labeledIssue(3, "sig/node")
+	active.State = "open"
+	active.CreatedAt = ago(100)
+
+	pr := labeledIssue(4, "sig/storage")
+	pr.State = "open"
+	pr.User = "author"
+	pr.PullRequest = true
+	pr.CreatedAt = ago(100)
+
+	comments := []Comment{
+		{IssueNumber: 1, User: "k8s-merge-robot", CreatedAt: ago(1)},
+		{IssueNumber: 3, User: "bob", CreatedAt: ago(2)},
+		{IssueNumber: 4, User: "reviewer", CreatedAt: ago(60)},
+		{IssueNumber: 4, User: "author", CreatedAt: ago(50)},
+	}
+
+	events := []IssueEvent{
+		{IssueNumber: 1, Event: "labeled", Actor: "k8s-ci-robot", CreatedAt: ago(1)},
+		{IssueNumber: 1, Event: "labeled", Actor: "carol", CreatedAt: ago(40)},
+	}
+
+	h := NewHistory([]Issue{idle, frozen, active, pr}, comments, events)
+	report := FindStale("org/repo", h, StaleOptions{Days: 30, Now: now})
+
+	require.Len(t, report.Groups, 2)
+
+	g := report.Groups[0]
+	require.Equal(t, "node", g.SIG)
+	require.Equal(t, "alice", g.Assignee)
+	require.Len(t, g.Issues, 1)
+	require.Equal(t, 1, g.Issues[0].Issue.Number)
+	require.Equal(t, "carol", g.Issues[0].LastActor)
+	require.Equal(t, *ago(40), g.Issues[0].LastActivity)
+	require.False(t, g.Issues[0].WaitingOnReviewer)
+
+	g = report.Groups[1]
+	require.Equal(t, "storage", g.SIG)
+	require.Equal(t, unassigned, g.Assignee)
+	require.True(t, g.Issues[0].WaitingOnReviewer)
+
+	report = FindStale("org/repo", h, StaleOptions{Days: 30, Now: now, SIG: "storage"})
+	require.Len(t, report.Groups, 1)
+}
@@ -0,0 +1,141 @@
+package kubenews
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Status is the state of kubenews jobs for `kubenews status`.
+type Status struct {
+	// Freshness is how up to date each synced repository resource is, and
+	// Runs are the most recent syncs, newest first.
+	Freshness []SyncFreshness `json:"freshness"`
+	Runs      []SyncRun       `json:"runs"`
+	// Leases are the repository locks held by running jobs.
+	Leases []Lease `json:"leases"`
+
+	// StaleAfter is how long since its last successful sync a resource is
+	// shown as stale. Zero never shows resources as stale.
+	StaleAfter time.Duration `json:"-"`
+}
+
+// Write writes the status in a format: text or json.
+func (s *Status) Write(w io.Writer, format string) error {
+	switch format {
+	case "text", "":
+		return s.WriteText(w)
+	case "json":
+		return s.WriteJSON(w)
+	default:
+		return errors.Errorf("unknown format %q", format)
+	}
+}
+
+// WriteText writes the status as aligned text tables.
+func (s *Status) WriteText(w io.Writer) error {
+	for _, section := range []func(io.Writer) error{s.writeFreshness, s.writeRuns, s.writeLeases} {
+		if err := section(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Status) writeFreshness(w io.Writer) error {
+	fmt.Fprintln(w, "FRESHNESS")
+	if len(s.Freshness) == 0 {
+		fmt.Fprintln(w, "no syncs recorded")
+		fmt.Fprintln(w)
+		return nil
+	}
+
+	now := time.Now()
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPOSITORY\tRESOURCE\tLAST SUCCESS\tFAILURES\tSTATE")
+	for _, f := range s.Freshness {
+		lastSuccess := "never"
+		if f.LastSuccess != nil {
+			lastSuccess = now.Sub(*f.LastSuccess).Round(time.Second).String() + " ago"
+		}
+
+		states := []string{}
+		if s.StaleAfter > 0 && f.Stale(now, s.StaleAfter) {
+			states = append(states, "stale")
+		}
+		if f.Failures > 0 {
+			states = append(states, "failing: "+oneLine(f.LastError, 60))
+		}
+		if len(states) == 0 {
+			states = append(states, "ok")
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", f.Repository, f.Resource, lastSuccess,
+			f.Failures, strings.Join(states, ", "))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+func (s *Status) writeRuns(w io.Writer) error {
+	fmt.Fprintln(w, "RUNS")
+	if len(s.Runs) == 0 {
+		fmt.Fprintln(w, "no syncs recorded")
+		fmt.Fprintln(w)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tREPOSITORY\tRESOURCE\tDURATION\tPAGES\tAPI CALLS\tFETCHED\tINSERTED\tUPDATED\tERROR")
+	for _, run := range s.Runs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n",
+			run.StartedAt.UTC().Format("2006-01-02 15:04:05"), run.Repository, run.Resource,
+			run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond), run.Pages, run.APICalls,
+			run.Fetched, run.Inserted, run.Updated, oneLine(run.Error, 60))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+func (s *Status) writeLeases(w io.Writer) error {
+	fmt.Fprintln(w, "LOCKS")
+	if len(s.Leases) == 0 {
+		fmt.Fprintln(w, "no repository is locked")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPOSITORY\tJOB\tHOLDER\tHELD FOR")
+	for _, lease := range s.Leases {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", lease.Repository, lease.Job, lease.Holder,
+			time.Since(lease.AcquiredAt).Round(time.Second))
+	}
+
+	return tw.Flush()
+}
+
+// oneLine shortens text to a line of at most n characters.
+func oneLine(text string, n int) string {
+	return truncate(strings.Join(strings.Fields(text), " "), n)
+}
+
+// WriteJSON writes the status as JSON.
+func (s *Status) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
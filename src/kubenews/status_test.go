@@ -0,0 +1,59 @@
+package kubenews
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusWriteText(t *testing.T) {
+	status := &Status{}
+
+	var buf bytes.Buffer
+	require.NoError(t, status.Write(&buf, "text"))
+	require.Contains(t, buf.String(), "no syncs recorded")
+	require.Contains(t, buf.String(), "no repository is locked")
+
+	now := time.Now()
+	lastSuccess := now.Add(-3 * time.Hour)
+	status.StaleAfter = time.Hour
+	status.Freshness = []SyncFreshness{
+		{Repository: "org/repo", Resource: "events", LastRun: now, LastSuccess: &now},
+		{Repository: "org/repo", Resource: "issues", LastRun: now, LastSuccess: &lastSuccess,
+			Failures: 2, LastError: "list issues: 502\nBad Gateway"},
+	}
+	status.Runs = []SyncRun{
+		{Repository: "org/repo", Resource: "issues", StartedAt: now, FinishedAt: now.Add(2 * time.Second),
+			APICalls: 4, Error: "list issues: 502\nBad Gateway"},
+	}
+	status.Leases = []Lease{
+		{Repository: "org/repo", Job: "update", Holder: "host:42", AcquiredAt: now.Add(-time.Minute)},
+	}
+
+	buf.Reset()
+	require.NoError(t, status.Write(&buf, "text"))
+	sections := strings.Split(strings.TrimSpace(buf.String()), "\n\n")
+	require.Len(t, sections, 3)
+
+	freshness := strings.Split(sections[0], "\n")
+	require.Len(t, freshness, 4)
+	require.Contains(t, freshness[2], "ok")
+	require.Contains(t, freshness[3], "3h0m0s ago")
+	require.Contains(t, freshness[3], "stale, failing: list issues: 502 Bad Gateway")
+
+	runs := strings.Split(sections[1], "\n")
+	require.Len(t, runs, 3)
+	require.Contains(t, runs[2], "2s")
+	require.Contains(t, runs[2], "list issues: 502 Bad Gateway")
+
+	locks := strings.Split(sections[2], "\n")
+	require.Len(t, locks, 3)
+	require.True(t, strings.HasPrefix(locks[2], "org/repo"))
+	require.Contains(t, locks[2], "host:42")
+	require.Contains(t, locks[2], "1m0s")
+
+	require.Error(t, status.Write(&buf, "yaml"))
+}
@@ -0,0 +1,205 @@
+package kubenews
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Store persists the issues, comments and events synced from Github, and
+// answers the queries reports, the API, the dashboard and feeds are built
+// from. Only full-text search needs a PostgresStore.
+type Store interface {
+	// Migrate creates the tables the store needs if they don't exist.
+	Migrate() error
+
+	// LastIssueUpdate, LastCommentUpdate and LastEventID are the cursors
+	// incremental updates resume from.
+	LastIssueUpdate(repository string) (*LastUpdate, error)
+	LastCommentUpdate(repository string) (*LastUpdate, error)
+	LastEventID(repository string) (int, error)
+
+	// SaveIssues, SaveComments and SaveEvents insert or update records.
+	// Saving issues also records the labels of open issues.
+	SaveIssues(issues []Issue) (Saved, error)
+	SaveComments(comments []Comment) (Saved, error)
+	SaveEvents(events []IssueEvent) (Saved, error)
+	// SetIssueState sets the state of an issue without changing its update
+	// time, which incremental updates resume from.
+	SetIssueState(repository string, number int, state string) error
+
+	ActiveLabels() ([]StoredLabel, error)
+	IssuesActiveBetween(repository string, from, to time.Time) ([]Issue, error)
+	OpenIssues(repository string) ([]Issue, error)
+
+	LoadHistory(repository string, from, to time.Time) (*History, error)
+	LoadOpenHistory(repository string) (*History, error)
+	LoadLabeledHistory(repository string, labels []string) (*History, error)
+	LoadRepositoryHistory(repository string) (*History, error)
+	LoadMergedPullRequests(repository, milestone string, from, to time.Time) ([]MergedPullRequest, error)
+
+	SaveSnapshots(snapshots []Snapshot) error
+	LoadTrend(repository, dimension, value string, from, to time.Time) (*Trend, error)
+
+	// SaveSyncRun, LoadSyncRuns and LoadSyncFreshness record syncs, so
+	// stale data is noticed.
+	SaveSyncRun(run SyncRun) error
+	LoadSyncRuns(repository string, limit int) ([]SyncRun, error)
+	LoadSyncFreshness() ([]SyncFreshness, error)
+
+	// SaveReferences and LoadFixes record the references between issues
+	// and pull requests parsed from their bodies and comments.
+	SaveReferences(sources []ReferenceSource) error
+	LoadFixes(repository string) ([]Fix, error)
+
+	// QueryIssues, Milestones, LoadIssue, LoadIssueEvents and RecentIssues
+	// answer the API, the dashboard and feeds.
+	QueryIssues(q IssueQuery) ([]Issue, int, error)
+	Milestones(repository string) ([]MilestoneSummary, error)
+	LoadIssue(repository string, number int) (*Issue, error)
+	LoadIssueEvents(repository string, number int) ([]IssueEvent, error)
+	RecentIssues(repository string, since time.Time) ([]Issue, error)
+
+	// FindDigestDelivery, LoadDigestDelivery, LoadDigestDeliveries,
+	// RecordDigestDelivery and DeleteDigestDelivery record the digests
+	// emailed to mailing lists, so a digest isn't sent twice.
+	FindDigestDelivery(repository, list string, from, to time.Time) (*DigestDelivery, error)
+	LoadDigestDelivery(id int) (*DigestDelivery, error)
+	LoadDigestDeliveries(repository, list string, limit, offset int) ([]DigestDelivery, int, error)
+	RecordDigestDelivery(d *DigestDelivery) error
+	DeleteDigestDelivery(repository, list string, from, to time.Time) error
+
+	// AlertSent and RecordAlert record the webhook alerts sent for issues,
+	// so an alert isn't sent twice.
+	AlertSent(repository string, number int, alert, route string) (bool, error)
+	RecordAlert(repository string, number int, alert, route string, sentAt time.Time) error
+}
+
+// Saved counts the records a save inserted and updated.
+type Saved struct {
+	Inserted int
+	Updated  int
+}
+
+func (s *Saved) add(o Saved) {
+	s.Inserted += o.Inserted
+	s.Updated += o.Updated
+}
+
+// count counts a record as inserted or updated.
+func (s *Saved) count(inserted bool) {
+	if inserted {
+		s.Inserted++
+		return
+	}
+
+	s.Updated++
+}
+
+// sqlStore implements Store with SQL shared by the SQL databases. Queries are
+// written for Postgres and rebound for other databases.
+type sqlStore struct {
+	db     *sqlx.DB
+	schema []string
+	// rebind rewrites a Postgres query for the database.
+	rebind func(query string) string
+	// arg converts an argument for the database.
+	arg func(v interface{}) interface{}
+	// xmax is set if upserts can return whether they inserted, from the
+	// Postgres xmax system column.
+	xmax bool
+}
+
+// upsert inserts a record, or updates it if it exists.
+type upsert struct {
+	// insert is an INSERT ... ON CONFLICT DO UPDATE.
+	insert string
+	// update is the UPDATE the insert does on conflict, with the same
+	// arguments.
+	update string
+}
+
+func (s *sqlStore) args(args []interface{}) []interface{} {
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		out[i] = s.arg(arg)
+	}
+
+	return out
+}
+
+func (s *sqlStore) selectx(dest interface{}, query string, args ...interface{}) error {
+	return s.db.Select(dest, s.rebind(query), s.args(args)...)
+}
+
+func (s *sqlStore) get(dest interface{}, query string, args ...interface{}) error {
+	return s.db.Get(dest, s.rebind(query), s.args(args)...)
+}
+
+// exec runs a statement in a transaction, or on its own with s.db.
+func (s *sqlStore) exec(e sqlx.Execer, query string, args ...interface{}) error {
+	_, err := e.Exec(s.rebind(query), s.args(args)...)
+	return err
+}
+
+// upsert runs an upsert in a transaction and returns whether it inserted
+// the record. Without xmax, the update is tried first and the record is
+// inserted if it changed no row, which is safe as SQLite has one writer.
+func (s *sqlStore) upsert(tx *sqlx.Tx, u upsert, args ...interface{}) (bool, error) {
+	if s.xmax {
+		var inserted bool
+		err := tx.Get(&inserted, s.rebind(u.insert+"\n  RETURNING (xmax = 0) AS inserted"), s.args(args)...)
+		return inserted, err
+	}
+
+	n, err := s.affected(tx, u.update, args...)
+	if err != nil || n > 0 {
+		return false, err
+	}
+
+	return true, s.exec(tx, u.insert, args...)
+}
+
+// affected runs a statement in a transaction and returns the number of rows
+// it changed.
+func (s *sqlStore) affected(tx *sqlx.Tx, query string, args ...interface{}) (int64, error) {
+	res, err := tx.Exec(s.rebind(query), s.args(args)...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// Migrate creates the tables kubenews needs if they don't exist.
+func (s *sqlStore) Migrate() error {
+	for _, stmt := range s.schema {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return errors.Wrap(err, "migrate schema")
+		}
+	}
+
+	return nil
+}
+
+// PostgresStore is a Store in Postgres. DB is used directly by full-text
+// search, which needs Postgres.
+type PostgresStore struct {
+	*sqlStore
+	DB *sqlx.DB
+}
+
+// NewPostgresStore creates a PostgresStore over a connection.
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{
+		sqlStore: &sqlStore{
+			db:     db,
+			schema: schemaSQL,
+			rebind: func(query string) string { return query },
+			arg:    func(v interface{}) interface{} { return v },
+			xmax:   true,
+		},
+		DB: db,
+	}
+}
@@ -0,0 +1,468 @@
+package kubenews
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+// forEachStore runs a test against each Store which doesn't need a server.
+func forEachStore(t *testing.T, test func(t *testing.T, s Store)) {
+	t.Run("sqlite", func(t *testing.T) {
+		s, err := NewSQLiteStore(":memory:")
+		require.NoError(t, err)
+		require.NoError(t, s.Migrate())
+		test(t, s)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore())
+	})
+}
+
+func TestStoreIssues(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		day := func(d int) *time.Time {
+			t := time.Date(2017, 3, d, 12, 0, 0, 0, time.FixedZone("PST", -8*3600))
+			return &t
+		}
+
+		cursor, err := s.LastIssueUpdate("org/repo")
+		require.NoError(t, err)
+		require.Nil(t, cursor.At)
+
+		saved, err := s.SaveIssues([]Issue{
+			{Number: 1, State: "open", Title: "flaky test", User: "alice", Repository: "org/repo",
+				Labels: Labels{{Name: "kind/flake", Color: "fff"}}, CreatedAt: day(1), UpdatedAt: day(2)},
+			{Number: 2, State: "closed", Title: "fixed", User: "bob", Repository: "org/repo",
+				Labels: Labels{{Name: "sig/node"}}, CreatedAt: day(3), UpdatedAt: day(5), ClosedAt: day(5)},
+			{Number: 3, State: "open", Title: "merged", Repository: "org/repo", PullRequest: true,
+				Milestone: "v1.7", Labels: Labels{}, CreatedAt: day(4), UpdatedAt: day(4)},
+		})
+		require.NoError(t, err)
+		require.Equal(t, Saved{Inserted: 3}, saved)
+
+		// Saving again updates rather than duplicates.
+		saved, err = s.SaveIssues([]Issue{
+			{Number: 1, State: "open", Title: "flaky test in e2e", User: "alice", Repository: "org/repo",
+				Labels: Labels{{Name: "kind/flake", Color: "fff"}}, CreatedAt: day(1), UpdatedAt: day(6)},
+		})
+		require.NoError(t, err)
+		require.Equal(t, Saved{Updated: 1}, saved)
+
+		cursor, err = s.LastIssueUpdate("org/repo")
+		require.NoError(t, err)
+		require.True(t, day(6).Equal(*cursor.At))
+
+		open, err := s.OpenIssues("org/repo")
+		require.NoError(t, err)
+		require.Len(t, open, 2)
+		require.Equal(t, "flaky test in e2e", open[0].Title)
+		require.Equal(t, Labels{{Name: "kind/flake", Color: "fff"}}, open[0].Labels)
+		require.True(t, open[1].PullRequest)
+
+		active, err := s.IssuesActiveBetween("org/repo", time.Date(2017, 3, 3, 0, 0, 0, 0, time.UTC),
+			time.Date(2017, 3, 6, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		require.Len(t, active, 2)
+		require.Equal(t, 2, active[0].Number)
+		require.True(t, day(5).Equal(*active[0].ClosedAt))
+
+		labels, err := s.ActiveLabels()
+		require.NoError(t, err)
+		require.Len(t, labels, 1)
+		require.Equal(t, "kind/flake", labels[0].Name)
+
+		_, err = s.SaveComments([]Comment{
+			{ID: 10, Repository: "org/repo", IssueNumber: 1, User: "carol", Body: "seen again", CreatedAt: day(2), UpdatedAt: day(2)},
+		})
+		require.NoError(t, err)
+
+		h, err := s.LoadLabeledHistory("org/repo", []string{"kind/flake", "kind/failing-test"})
+		require.NoError(t, err)
+		require.Len(t, h.Issues, 1)
+		require.Len(t, h.Comments[1], 1)
+
+		cursor, err = s.LastCommentUpdate("org/repo")
+		require.NoError(t, err)
+		require.True(t, day(2).Equal(*cursor.At))
+	})
+}
+
+func TestStoreEvents(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		merged := time.Date(2017, 3, 4, 0, 0, 0, 0, time.UTC)
+		_, err := s.SaveIssues([]Issue{
+			{Number: 3, State: "closed", Title: "Add feature", Repository: "org/repo", PullRequest: true,
+				Milestone: "v1.7", Labels: Labels{}, CreatedAt: &merged, UpdatedAt: &merged},
+		})
+		require.NoError(t, err)
+
+		events := []IssueEvent{
+			{ID: 100, Repository: "org/repo", IssueNumber: 3, Event: "labeled", Label: "sig/node", CreatedAt: &merged},
+			{ID: 101, Repository: "org/repo", IssueNumber: 3, Event: "merged", CreatedAt: &merged},
+		}
+		saved, err := s.SaveEvents(events)
+		require.NoError(t, err)
+		require.Equal(t, Saved{Inserted: 2}, saved)
+
+		// events never change, so saving again skips them
+		saved, err = s.SaveEvents(events)
+		require.NoError(t, err)
+		require.Equal(t, Saved{}, saved)
+
+		id, err := s.LastEventID("org/repo")
+		require.NoError(t, err)
+		require.Equal(t, 101, id)
+
+		prs, err := s.LoadMergedPullRequests("org/repo", "v1.7", time.Time{}, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, prs, 1)
+		require.True(t, merged.Equal(prs[0].MergedAt))
+
+		prs, err = s.LoadMergedPullRequests("org/repo", "", merged.AddDate(0, 0, 1), merged.AddDate(0, 0, 2))
+		require.NoError(t, err)
+		require.Len(t, prs, 0)
+
+		h, err := s.LoadRepositoryHistory("org/repo")
+		require.NoError(t, err)
+		require.Len(t, h.Events[3], 2)
+	})
+}
+
+func TestStoreSnapshots(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		day := func(d int) time.Time { return time.Date(2017, 3, d, 0, 0, 0, 0, time.UTC) }
+		require.NoError(t, s.SaveSnapshots([]Snapshot{
+			{Day: day(1), Repository: "org/repo", Dimension: "sig", Value: "node", Open: 4},
+			{Day: day(2), Repository: "org/repo", Dimension: "sig", Value: "node", Open: 5},
+		}))
+		require.NoError(t, s.SaveSnapshots([]Snapshot{
+			{Day: day(2), Repository: "org/repo", Dimension: "sig", Value: "node", Open: 6},
+		}))
+
+		trend, err := s.LoadTrend("org/repo", "sig", "node", day(1), day(3))
+		require.NoError(t, err)
+		require.Len(t, trend.Snapshots, 2)
+		require.Equal(t, 6, trend.Snapshots[1].Open)
+		require.True(t, day(2).Equal(trend.Snapshots[1].Day))
+
+		// snapshotting a day again removes values which are no longer open,
+		// and days without a value count as 0 rather than being skipped
+		require.NoError(t, s.SaveSnapshots([]Snapshot{
+			{Day: day(2), Repository: "org/repo", Dimension: "repository", Value: "org/repo", Open: 1},
+			{Day: day(3), Repository: "org/repo", Dimension: "repository", Value: "org/repo", Open: 1},
+		}))
+
+		trend, err = s.LoadTrend("org/repo", "sig", "node", day(1), day(5))
+		require.NoError(t, err)
+		require.Len(t, trend.Snapshots, 3)
+		require.Equal(t, []int{4, 0, 0}, []int{trend.Snapshots[0].Open, trend.Snapshots[1].Open, trend.Snapshots[2].Open})
+		require.True(t, day(3).Equal(trend.Snapshots[2].Day))
+	})
+}
+
+func TestStoreSyncRuns(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		at := func(h int) time.Time { return time.Date(2017, 3, 1, h, 0, 0, 0, time.UTC) }
+		for _, run := range []SyncRun{
+			{Repository: "org/repo", Resource: "issues", StartedAt: at(1), FinishedAt: at(1), Pages: 2, APICalls: 3, Fetched: 150, Inserted: 100, Updated: 50},
+			{Repository: "org/repo", Resource: "issues", StartedAt: at(2), FinishedAt: at(2), APICalls: 4, Error: "list issues: 502"},
+			{Repository: "org/repo", Resource: "issues", StartedAt: at(3), FinishedAt: at(3), APICalls: 4, Error: "list issues: 403"},
+			{Repository: "org/repo", Resource: "events", StartedAt: at(2), FinishedAt: at(2), Pages: 1, APICalls: 1},
+			{Repository: "org/other", Resource: "issues", StartedAt: at(2), FinishedAt: at(2), Error: "list issues: 404"},
+		} {
+			require.NoError(t, s.SaveSyncRun(run))
+		}
+
+		runs, err := s.LoadSyncRuns("org/repo", 2)
+		require.NoError(t, err)
+		require.Len(t, runs, 2)
+		require.True(t, at(3).Equal(runs[0].StartedAt))
+		require.Equal(t, "events", runs[1].Resource)
+
+		runs, err = s.LoadSyncRuns("", 10)
+		require.NoError(t, err)
+		require.Len(t, runs, 5)
+		first := runs[4]
+		require.Equal(t, 2, first.Pages)
+		require.Equal(t, 3, first.APICalls)
+		require.Equal(t, 150, first.Fetched)
+		require.Equal(t, 100, first.Inserted)
+		require.Equal(t, 50, first.Updated)
+		require.False(t, first.Failed())
+
+		freshness, err := s.LoadSyncFreshness()
+		require.NoError(t, err)
+		require.Len(t, freshness, 3)
+
+		other := freshness[0]
+		require.Equal(t, "org/other", other.Repository)
+		require.Nil(t, other.LastSuccess)
+		require.Equal(t, 1, other.Failures)
+
+		events, issues := freshness[1], freshness[2]
+		require.Equal(t, "events", events.Resource)
+		require.Equal(t, 0, events.Failures)
+		require.True(t, at(2).Equal(*events.LastSuccess))
+
+		require.True(t, at(3).Equal(issues.LastRun))
+		require.True(t, at(1).Equal(*issues.LastSuccess))
+		require.Equal(t, 2, issues.Failures)
+		require.Equal(t, "list issues: 403", issues.LastError)
+		require.True(t, issues.Stale(at(4), 2*time.Hour))
+		require.False(t, events.Stale(at(4), 2*time.Hour))
+	})
+}
+
+func TestStoreFixes(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		merged := time.Date(2017, 3, 2, 12, 0, 0, 0, time.UTC)
+		later := merged.AddDate(0, 0, 1)
+
+		_, err := s.SaveIssues([]Issue{
+			{Number: 1, State: "open", Title: "broken", Repository: "org/repo"},
+			{Number: 2, State: "closed", Title: "fix", Repository: "org/repo", PullRequest: true, Milestone: "v1.7"},
+			{Number: 3, State: "open", Title: "another fix", Repository: "org/repo", PullRequest: true},
+			{Number: 4, State: "open", Title: "not a pull request", Repository: "org/repo"},
+			{Number: 9, State: "closed", Title: "fix elsewhere", Repository: "org/other", PullRequest: true},
+		})
+		require.NoError(t, err)
+
+		_, err = s.SaveEvents([]IssueEvent{
+			{ID: 1, Repository: "org/repo", IssueNumber: 2, Event: "merged", CreatedAt: &merged},
+			{ID: 2, Repository: "org/repo", IssueNumber: 2, Event: "merged", CreatedAt: &later},
+			{ID: 3, Repository: "org/repo", IssueNumber: 3, Event: "closed", CreatedAt: &merged},
+		})
+		require.NoError(t, err)
+
+		fix := func(repo string, n, target int) ReferenceSource {
+			return ReferenceSource{Repository: repo, Number: n, References: []Reference{
+				{TargetRepository: "org/repo", TargetNumber: target, Kind: RefFixes},
+			}}
+		}
+		require.NoError(t, s.SaveReferences([]ReferenceSource{
+			fix("org/repo", 2, 1),
+			fix("org/repo", 3, 1),
+			fix("org/repo", 4, 1),
+			fix("org/other", 9, 5),
+			{Repository: "org/repo", Number: 3, CommentID: 4000000000, References: []Reference{
+				{TargetRepository: "org/repo", TargetNumber: 5, Kind: RefReferences},
+			}},
+		}))
+
+		fixes, err := s.LoadFixes("org/repo")
+		require.NoError(t, err)
+		require.Len(t, fixes, 3)
+
+		require.Equal(t, 1, fixes[0].Number)
+		require.Equal(t, 2, fixes[0].PullRequest.Number)
+		require.Equal(t, "v1.7", fixes[0].PullRequest.Milestone)
+		require.True(t, fixes[0].Merged())
+		require.True(t, later.Equal(*fixes[0].MergedAt))
+
+		require.Equal(t, 3, fixes[1].PullRequest.Number)
+		require.False(t, fixes[1].Merged())
+		require.True(t, fixes[1].Open())
+
+		require.Equal(t, 5, fixes[2].Number)
+		require.Equal(t, "org/other", fixes[2].PullRequest.Repository)
+
+		// saving a source again replaces its references
+		require.NoError(t, s.SaveReferences([]ReferenceSource{{Repository: "org/repo", Number: 3}}))
+		fixes, err = s.LoadFixes("org/repo")
+		require.NoError(t, err)
+		require.Len(t, fixes, 2)
+	})
+}
+
+func TestStoreIssueQueries(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		day := func(d int) *time.Time {
+			t := time.Date(2017, 3, d, 12, 0, 0, 0, time.UTC)
+			return &t
+		}
+		numbers := func(issues []Issue) []int {
+			n := []int{}
+			for _, issue := range issues {
+				n = append(n, issue.Number)
+			}
+			return n
+		}
+
+		_, err := s.SaveIssues([]Issue{
+			{Number: 1, State: "open", Title: "crash", Repository: "org/repo", Milestone: "v1.7",
+				Labels: Labels{{Name: "sig/node"}}, CreatedAt: day(1), UpdatedAt: day(4)},
+			{Number: 2, State: "closed", Title: "leak", Repository: "org/repo", Milestone: "v1.7",
+				Labels: Labels{{Name: "sig/node"}}, CreatedAt: day(2), UpdatedAt: day(3), ClosedAt: day(3)},
+			{Number: 3, State: "open", Title: "fix", Repository: "org/repo", PullRequest: true,
+				Labels: Labels{}, CreatedAt: day(3), UpdatedAt: day(3)},
+			{Number: 4, State: "open", Title: "other", Repository: "org/other",
+				Labels: Labels{}, CreatedAt: day(1), UpdatedAt: day(1)},
+		})
+		require.NoError(t, err)
+
+		issues, total, err := s.QueryIssues(IssueQuery{Repository: "org/repo", Labels: []string{"sig/node"},
+			Sort: "updated", PerPage: 1})
+		require.NoError(t, err)
+		require.Equal(t, 2, total)
+		require.Len(t, issues, 1)
+		require.Equal(t, 1, issues[0].Number)
+
+		issues, total, err = s.QueryIssues(IssueQuery{State: "open"})
+		require.NoError(t, err)
+		require.Equal(t, 3, total)
+		require.Equal(t, []int{4, 3, 1}, numbers(issues))
+
+		_, _, err = s.QueryIssues(IssueQuery{Sort: "color"})
+		require.Error(t, err)
+
+		milestones, err := s.Milestones("org/repo")
+		require.NoError(t, err)
+		require.Equal(t, []MilestoneSummary{{Milestone: "v1.7", Open: 1, Closed: 1}}, milestones)
+
+		issue, err := s.LoadIssue("org/repo", 2)
+		require.NoError(t, err)
+		require.Equal(t, "leak", issue.Title)
+
+		issue, err = s.LoadIssue("org/repo", 5)
+		require.NoError(t, err)
+		require.Nil(t, issue)
+
+		issues, err = s.RecentIssues("org/repo", *day(2))
+		require.NoError(t, err)
+		require.Equal(t, []int{2}, numbers(issues))
+	})
+}
+
+func TestStoreDigestDeliveries(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		from := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 0, 7)
+
+		d, err := s.FindDigestDelivery("org/repo", "dev", from, to)
+		require.NoError(t, err)
+		require.Nil(t, d)
+
+		delivery := &DigestDelivery{Repository: "org/repo", List: "dev", From: from, To: to, Title: "digest",
+			Markdown: "# digest", Recipients: "dev@example.com", MessageID: "<1@example.com>", SentAt: to,
+			Status: DeliveryPending}
+		require.NoError(t, s.RecordDigestDelivery(delivery))
+
+		d, err = s.FindDigestDelivery("org/repo", "dev", from.Add(time.Hour), to)
+		require.NoError(t, err)
+		require.Equal(t, DeliveryPending, d.Status)
+
+		// pending deliveries aren't listed
+		deliveries, total, err := s.LoadDigestDeliveries("org/repo", "", 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, 0, total)
+		require.Empty(t, deliveries)
+
+		delivery.Status = DeliverySent
+		require.NoError(t, s.RecordDigestDelivery(delivery))
+		delivery.List, delivery.Status = "users", ""
+		require.NoError(t, s.RecordDigestDelivery(delivery))
+
+		deliveries, total, err = s.LoadDigestDeliveries("org/repo", "", 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, 2, total)
+		require.Len(t, deliveries, 1)
+		require.Equal(t, "users", deliveries[0].List)
+		require.True(t, from.Equal(deliveries[0].From))
+
+		d, err = s.LoadDigestDelivery(deliveries[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, "# digest", d.Markdown)
+
+		require.NoError(t, s.DeleteDigestDelivery("org/repo", "dev", from, to))
+		deliveries, total, err = s.LoadDigestDeliveries("org/repo", "dev", 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, 0, total)
+		require.Empty(t, deliveries)
+	})
+}
+
+func TestStoreAlerts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		sent, err := s.AlertSent("org/repo", 1, "critical", "slack")
+		require.NoError(t, err)
+		require.False(t, sent)
+
+		require.NoError(t, s.RecordAlert("org/repo", 1, "critical", "slack", time.Now()))
+		require.NoError(t, s.RecordAlert("org/repo", 1, "critical", "slack", time.Now()))
+
+		sent, err = s.AlertSent("org/repo", 1, "critical", "slack")
+		require.NoError(t, err)
+		require.True(t, sent)
+
+		sent, err = s.AlertSent("org/repo", 1, "critical", "email")
+		require.NoError(t, err)
+		require.False(t, sent)
+	})
+}
+
+func TestPostgresSaveCounts(t *testing.T) {
+	stdlibdb, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	s := NewPostgresStore(sqlx.NewDb(stdlibdb, "mockdriver"))
+
+	mock.ExpectBegin()
+	for _, inserted := range []bool{true, false} {
+		mock.ExpectQuery(`INSERT INTO comments .* RETURNING \(xmax = 0\) AS inserted`).
+			WillReturnRows(sqlmock.NewRows([]string{"inserted"}).AddRow(inserted))
+	}
+	mock.ExpectCommit()
+
+	saved, err := s.SaveComments([]Comment{
+		{ID: 1, Repository: "org/repo", IssueNumber: 1, Body: "new"},
+		{ID: 2, Repository: "org/repo", IssueNumber: 1, Body: "edited"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, Saved{Inserted: 1, Updated: 1}, saved)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLiteMigrateIssues(t *testing.T) {
+	s, err := NewSQLiteStore(":memory:")
+	require.NoError(t, err)
+
+	// issues were numbered once across repositories
+	_, err = s.DB.Exec(`CREATE TABLE issues (
+    id integer PRIMARY KEY,
+    number integer NOT NULL UNIQUE,
+    state text NOT NULL,
+    title text NOT NULL,
+    body text NOT NULL DEFAULT '',
+    created_by text NOT NULL DEFAULT '',
+    labels text NOT NULL DEFAULT '[]',
+    assignee text NOT NULL DEFAULT '',
+    closed_at timestamp,
+    created_at timestamp,
+    updated_at timestamp,
+    milestone text NOT NULL DEFAULT '',
+    repository text NOT NULL,
+    pull_request boolean NOT NULL DEFAULT false
+  )`)
+	require.NoError(t, err)
+	_, err = s.DB.Exec(`INSERT INTO issues (number, state, title, repository) VALUES (1, 'open', 'old', 'org/repo')`)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Migrate())
+	require.NoError(t, s.Migrate())
+
+	saved, err := s.SaveIssues([]Issue{
+		{Number: 1, State: "closed", Title: "old", Repository: "org/repo", Labels: Labels{}},
+		{Number: 1, State: "open", Title: "transferred", Repository: "org/other", Labels: Labels{}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, Saved{Inserted: 1, Updated: 1}, saved)
+
+	issue, err := s.LoadIssue("org/repo", 1)
+	require.NoError(t, err)
+	require.Equal(t, "closed", issue.State)
+}
@@ -0,0 +1,129 @@
+package kubenews
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+)
+
+// SyncResources are the resources synced from Github, in the order Sync
+// updates them.
+var SyncResources = []string{"issues", "comments", "events"}
+
+// Sync fetches the issues, comments and events changed in a repository since
+// the last sync and saves them to a store.
+func Sync(gh *Github, s Store, repository string) error {
+	for _, resource := range SyncResources {
+		if _, err := SyncResource(gh, s, repository, resource); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SyncResource fetches one resource changed in a repository since the last
+// sync and saves it to a store. It returns the number of records fetched.
+// Each sync is recorded as a SyncRun, whether or not it succeeds.
+func SyncResource(gh *Github, s Store, repository, resource string) (int, error) {
+	if !isSyncResource(resource) {
+		return 0, errors.Errorf("unknown resource %s", resource)
+	}
+
+	return recordSyncRun(gh, s, repository, resource, func(gh *Github) (int, Saved, error) {
+		return syncResource(gh, s, repository, resource)
+	})
+}
+
+// recordSyncRun runs a sync, counting its Github requests, and records it as
+// a SyncRun. It returns the number of records fetched.
+func recordSyncRun(gh *Github, s Store, repository, resource string, sync func(gh *Github) (int, Saved, error)) (int, error) {
+	stats := &APIStats{}
+	run := SyncRun{Repository: repository, Resource: resource, StartedAt: time.Now().UTC()}
+
+	fetched, saved, err := sync(gh.WithStats(stats))
+
+	run.FinishedAt = time.Now().UTC()
+	run.Pages = stats.Pages()
+	run.APICalls = stats.Calls()
+	run.Fetched = fetched
+	run.Inserted = saved.Inserted
+	run.Updated = saved.Updated
+	if err != nil {
+		run.Error = err.Error()
+	}
+	observeSyncRun(run)
+
+	if saveErr := s.SaveSyncRun(run); saveErr != nil {
+		if err != nil {
+			log.WithError(saveErr).WithField("repo", repository).Error("unable to record sync run")
+			return fetched, err
+		}
+		return fetched, errors.Wrap(saveErr, "record sync run")
+	}
+
+	return fetched, err
+}
+
+func isSyncResource(resource string) bool {
+	for _, r := range SyncResources {
+		if r == resource {
+			return true
+		}
+	}
+
+	return false
+}
+
+func syncResource(gh *Github, s Store, repository, resource string) (int, Saved, error) {
+	logger := log.WithFields(log.Fields{"repo": repository, "resource": resource})
+
+	switch resource {
+	case "issues":
+		lastUpdate, err := s.LastIssueUpdate(repository)
+		if err != nil {
+			return 0, Saved{}, err
+		}
+		logger.WithField("lastUpdate", lastUpdate.At).Info("issues last update")
+
+		issues, err := gh.ListRepoIssues(repository, lastUpdate.At)
+		if err != nil {
+			return 0, Saved{}, errors.Wrap(err, "list issues")
+		}
+
+		logger.WithField("issueCount", len(issues)).Info("triaging issues")
+		saved, err := ImportIssues(s, repository, issues)
+		return len(issues), saved, errors.Wrap(err, "import issues")
+
+	case "comments":
+		lastUpdate, err := s.LastCommentUpdate(repository)
+		if err != nil {
+			return 0, Saved{}, err
+		}
+
+		comments, err := gh.ListRepoComments(repository, lastUpdate.At)
+		if err != nil {
+			return 0, Saved{}, errors.Wrap(err, "list comments")
+		}
+
+		saved, err := ImportComments(s, repository, comments)
+		return len(comments), saved, errors.Wrap(err, "import comments")
+
+	case "events":
+		lastEventID, err := s.LastEventID(repository)
+		if err != nil {
+			return 0, Saved{}, err
+		}
+
+		events, err := gh.ListRepoEvents(repository, lastEventID)
+		if err != nil {
+			return 0, Saved{}, errors.Wrap(err, "list events")
+		}
+
+		saved, err := ImportEvents(s, repository, events)
+		return len(events), saved, errors.Wrap(err, "import events")
+	}
+
+	return 0, Saved{}, errors.Errorf("unknown resource %s", resource)
+}
@@ -0,0 +1,124 @@
+package kubenews
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncDigest(t *testing.T) {
+	f, gh := newTestGithub(t)
+	s := NewMemoryStore()
+
+	f.fail(fakeIssuesPath, fakeFailure{status: http.StatusBadGateway, page: 2})
+	f.fail(fakeCommentsPath, fakeFailure{status: http.StatusForbidden, rateLimited: true})
+	require.NoError(t, Sync(gh, s, "org/repo"))
+
+	runs, err := s.LoadSyncRuns("org/repo", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+	issues := runs[2]
+	require.Equal(t, "issues", issues.Resource)
+	require.False(t, issues.Failed())
+	require.Equal(t, 5, issues.Fetched)
+	require.Equal(t, 5, issues.Inserted)
+	require.Equal(t, 3, issues.Pages)
+	// the failed page is retried
+	require.Equal(t, 4, issues.APICalls)
+
+	from := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	d, err := BuildDigest(s, DigestOptions{
+		Repository: "org/repo",
+		From:       from,
+		To:         from.AddDate(0, 0, 7),
+		FlakeLimit: 5,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, d.Opened, 3)
+	require.Len(t, d.Closed, 2)
+	require.Equal(t, 3, d.OpenCount)
+	require.Equal(t, []SIGSummary{
+		{Name: "node", Opened: 2, Closed: 1, Open: 1},
+		{Name: "storage", Closed: 1},
+		{Name: "none", Opened: 1, Open: 2},
+	}, d.SIGs)
+	require.Len(t, d.TopFlakes, 1)
+	require.Equal(t, "[k8s.io] Networking should function for intra-pod communication", d.TopFlakes[0].Name)
+
+	h, err := s.LoadOpenHistory("org/repo")
+	require.NoError(t, err)
+	require.Len(t, h.Comments[1], 1)
+	require.Len(t, h.Events[1], 1)
+
+	prs, err := s.LoadMergedPullRequests("org/repo", "v1.7", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	rn := NewReleaseNotes(prs, ReleaseNotesOptions{Repository: "org/repo", Milestone: "v1.7"})
+	require.Len(t, rn.Notes, 1)
+	require.True(t, strings.Contains(rn.Notes[0].Text, "kubelet panic"))
+}
+
+func TestSyncIncremental(t *testing.T) {
+	f, gh := newTestGithub(t)
+	s := NewMemoryStore()
+
+	require.NoError(t, Sync(gh, s, "org/repo"))
+
+	f.add(fakeIssuesPath, map[string]interface{}{
+		"number":     1,
+		"state":      "closed",
+		"title":      "kubelet crashes on restart",
+		"user":       map[string]interface{}{"login": "alice"},
+		"labels":     []interface{}{},
+		"created_at": "2017-03-02T10:00:00Z",
+		"updated_at": "2017-03-07T10:00:00Z",
+		"closed_at":  "2017-03-07T10:00:00Z",
+	})
+	f.add(fakeEventsPath, map[string]interface{}{
+		"id":         2005,
+		"event":      "closed",
+		"issue":      map[string]interface{}{"number": 1},
+		"created_at": "2017-03-07T10:00:00Z",
+	})
+
+	issues, err := SyncResource(gh, s, "org/repo", "issues")
+	require.NoError(t, err)
+	// since is inclusive, so the last updated issue is fetched again
+	require.Equal(t, 2, issues)
+	require.True(t, strings.Contains(lastQuery(f, fakeIssuesPath), "since=2017-03-06T07"))
+
+	events, err := SyncResource(gh, s, "org/repo", "events")
+	require.NoError(t, err)
+	require.Equal(t, 1, events)
+
+	open, err := s.OpenIssues("org/repo")
+	require.NoError(t, err)
+	require.Len(t, open, 2)
+
+	id, err := s.LastEventID("org/repo")
+	require.NoError(t, err)
+	require.Equal(t, 2005, id)
+
+	_, err = SyncResource(gh, s, "org/repo", "pulls")
+	require.Error(t, err)
+
+	f.fail(fakeEventsPath, fakeFailure{status: http.StatusNotFound})
+	_, err = SyncResource(gh, s, "org/repo", "events")
+	require.Error(t, err)
+
+	freshness, err := s.LoadSyncFreshness()
+	require.NoError(t, err)
+	require.Len(t, freshness, 3)
+	require.Equal(t, "events", freshness[1].Resource)
+	require.Equal(t, 1, freshness[1].Failures)
+	require.True(t, strings.Contains(freshness[1].LastError, "404"))
+	require.NotNil(t, freshness[1].LastSuccess)
+}
+
+func lastQuery(f *fakeGithub, path string) string {
+	requests := f.requested(path)
+	return requests[len(requests)-1]
+}
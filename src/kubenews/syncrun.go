@@ -0,0 +1,162 @@
+package kubenews
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SyncRun is a record of one sync of a repository resource.
+type SyncRun struct {
+	ID         int       `db:"id" json:"id"`
+	Repository string    `db:"repository" json:"repository"`
+	Resource   string    `db:"resource" json:"resource"`
+	StartedAt  time.Time `db:"started_at" json:"started_at"`
+	FinishedAt time.Time `db:"finished_at" json:"finished_at"`
+	// Pages is the number of pages fetched and APICalls the number of
+	// requests made, including retries.
+	Pages    int `db:"pages" json:"pages"`
+	APICalls int `db:"api_calls" json:"api_calls"`
+	// Fetched is the number of records fetched, of which Inserted were new
+	// and Updated already stored.
+	Fetched  int    `db:"fetched" json:"fetched"`
+	Inserted int    `db:"inserted" json:"inserted"`
+	Updated  int    `db:"updated" json:"updated"`
+	Error    string `db:"error" json:"error,omitempty"`
+}
+
+// Failed returns true if the sync failed.
+func (r SyncRun) Failed() bool {
+	return r.Error != ""
+}
+
+// SyncFreshness is how up to date a repository resource is.
+type SyncFreshness struct {
+	Repository string `json:"repository"`
+	Resource   string `json:"resource"`
+	// LastRun is when the last sync started, and LastSuccess when the last
+	// successful one did. LastSuccess is nil if no sync has succeeded.
+	LastRun     time.Time  `json:"last_run"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	// Failures is the number of syncs which failed since the last success.
+	Failures  int    `json:"failures"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Stale returns true if the resource hasn't synced successfully within age
+// of now.
+func (f SyncFreshness) Stale(now time.Time, age time.Duration) bool {
+	return f.LastSuccess == nil || now.Sub(*f.LastSuccess) > age
+}
+
+// SaveSyncRun records a sync run.
+func (s *sqlStore) SaveSyncRun(run SyncRun) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "save sync run failure")
+	}
+
+	if err := s.exec(tx, insertSyncRunSQL, run.Repository, run.Resource, run.StartedAt, run.FinishedAt,
+		run.Pages, run.APICalls, run.Fetched, run.Inserted, run.Updated, run.Error); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "insert sync run")
+	}
+
+	return tx.Commit()
+}
+
+// LoadSyncRuns loads the most recent sync runs, newest first. If repository
+// is empty, runs of every repository are loaded.
+func (s *sqlStore) LoadSyncRuns(repository string, limit int) ([]SyncRun, error) {
+	runs := []SyncRun{}
+	if err := s.selectx(&runs, syncRunsSQL, repository, limit); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve sync runs")
+	}
+
+	return runs, nil
+}
+
+// LoadSyncFreshness loads the freshness of each synced repository resource,
+// ordered by repository and resource.
+func (s *sqlStore) LoadSyncFreshness() ([]SyncFreshness, error) {
+	synced := []struct {
+		Repository string `db:"repository"`
+		Resource   string `db:"resource"`
+	}{}
+	if err := s.selectx(&synced, syncedResourcesSQL); err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve synced resources")
+	}
+
+	freshness := []SyncFreshness{}
+	for _, r := range synced {
+		f := SyncFreshness{Repository: r.Repository, Resource: r.Resource}
+
+		last := SyncRun{}
+		if err := s.get(&last, lastSyncRunSQL, r.Repository, r.Resource); err != nil {
+			return nil, errors.Wrap(err, "unable to retrieve last sync run")
+		}
+		f.LastRun = last.StartedAt
+		f.LastError = last.Error
+
+		since := time.Time{}
+		success := SyncRun{}
+		err := s.get(&success, lastSuccessfulSyncRunSQL, r.Repository, r.Resource)
+		switch {
+		case err == nil:
+			f.LastSuccess = &success.StartedAt
+			since = success.StartedAt
+		case err != sql.ErrNoRows:
+			return nil, errors.Wrap(err, "unable to retrieve last successful sync run")
+		}
+
+		if err := s.get(&f.Failures, syncFailuresSQL, r.Repository, r.Resource, since); err != nil {
+			return nil, errors.Wrap(err, "unable to count sync failures")
+		}
+
+		freshness = append(freshness, f)
+	}
+
+	return freshness, nil
+}
+
+var (
+	insertSyncRunSQL = `
+  INSERT INTO sync_runs
+  (repository, resource, started_at, finished_at, pages, api_calls, fetched, inserted, updated, error)
+
+  VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	syncRunsSQL = `
+  SELECT id, repository, resource, started_at, finished_at, pages, api_calls, fetched,
+    inserted, updated, error
+  FROM sync_runs
+  WHERE $1 = '' OR repository = $1
+  ORDER BY started_at DESC, id DESC
+  LIMIT $2`
+
+	syncedResourcesSQL = `
+  SELECT DISTINCT repository, resource FROM sync_runs
+  ORDER BY repository, resource`
+
+	lastSyncRunSQL = `
+  SELECT id, repository, resource, started_at, finished_at, pages, api_calls, fetched,
+    inserted, updated, error
+  FROM sync_runs
+  WHERE repository = $1 AND resource = $2
+  ORDER BY started_at DESC, id DESC
+  LIMIT 1`
+
+	lastSuccessfulSyncRunSQL = `
+  SELECT id, repository, resource, started_at, finished_at, pages, api_calls, fetched,
+    inserted, updated, error
+  FROM sync_runs
+  WHERE repository = $1 AND resource = $2 AND error = ''
+  ORDER BY started_at DESC, id DESC
+  LIMIT 1`
+
+	syncFailuresSQL = `
+  SELECT COUNT(*) FROM sync_runs
+  WHERE repository = $1 AND resource = $2 AND error <> '' AND started_at > $3`
+)
@@ -0,0 +1,307 @@
+package kubenews
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Label dimensions understood by the default taxonomy.
+const (
+	DimensionKind      = "kind"
+	DimensionPriority  = "priority"
+	DimensionArea      = "area"
+	DimensionLifecycle = "lifecycle"
+	DimensionTriage    = "triage"
+	DimensionNeeds     = "needs"
+)
+
+// TaxonomyRule maps labels starting with a prefix to a dimension.
+type TaxonomyRule struct {
+	// Dimension is the name of the dimension, e.g. priority.
+	Dimension string `mapstructure:"dimension"`
+	// Prefix is the label prefix, e.g. priority/. The remainder of the label
+	// is the value.
+	Prefix string `mapstructure:"prefix"`
+	// Order lists the values of the dimension from lowest to highest. It is
+	// required to compare values with < and >.
+	Order []string `mapstructure:"order"`
+}
+
+// DefaultTaxonomyRules are the label conventions used by Kubernetes.
+var DefaultTaxonomyRules = []TaxonomyRule{
+	{Dimension: DimensionKind, Prefix: "kind/"},
+	{
+		Dimension: DimensionPriority,
+		Prefix:    "priority/",
+		Order: []string{
+			"backlog",
+			"awaiting-more-evidence",
+			"important-longterm",
+			"important-soon",
+			"critical-urgent",
+		},
+	},
+	{Dimension: DimensionArea, Prefix: "area/"},
+	{Dimension: DimensionLifecycle, Prefix: "lifecycle/"},
+	{Dimension: DimensionTriage, Prefix: "triage/"},
+	{Dimension: DimensionNeeds, Prefix: "needs-"},
+}
+
+// Taxonomy parses labels into dimensions.
+type Taxonomy struct {
+	rules []TaxonomyRule
+}
+
+// NewTaxonomy creates an instance of Taxonomy. If no rules are given, the
+// default rules are used.
+func NewTaxonomy(rules []TaxonomyRule) *Taxonomy {
+	if len(rules) == 0 {
+		rules = DefaultTaxonomyRules
+	}
+
+	// match the longest prefix first so overlapping prefixes work
+	sorted := make([]TaxonomyRule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Prefix) > len(sorted[j].Prefix)
+	})
+
+	return &Taxonomy{rules: sorted}
+}
+
+// Dimensions are the values of an issue's labels grouped by dimension.
+type Dimensions map[string][]string
+
+// Classify parses an issue's labels into dimensions.
+func (t *Taxonomy) Classify(issue Issue) Dimensions {
+	d := Dimensions{}
+	for _, label := range issue.Labels {
+		for _, rule := range t.rules {
+			if !strings.HasPrefix(label.Name, rule.Prefix) {
+				continue
+			}
+
+			value := strings.TrimPrefix(label.Name, rule.Prefix)
+			if value != "" {
+				d[rule.Dimension] = append(d[rule.Dimension], value)
+			}
+			break
+		}
+	}
+
+	for _, rule := range t.rules {
+		values := d[rule.Dimension]
+		if len(rule.Order) == 0 {
+			sort.Strings(values)
+			continue
+		}
+
+		// ordered dimensions list the highest value first
+		sort.SliceStable(values, func(i, j int) bool {
+			return rank(rule.Order, values[i]) > rank(rule.Order, values[j])
+		})
+	}
+
+	return d
+}
+
+// Level returns the rank of a value in an ordered dimension. Higher is more
+// important. It returns -1 if the dimension isn't ordered or the value is
+// unknown.
+func (t *Taxonomy) Level(dimension, value string) int {
+	rule, ok := t.rule(dimension)
+	if !ok {
+		return -1
+	}
+
+	return rank(rule.Order, value)
+}
+
+func (t *Taxonomy) rule(dimension string) (TaxonomyRule, bool) {
+	for _, rule := range t.rules {
+		if rule.Dimension == dimension {
+			return rule, true
+		}
+	}
+
+	return TaxonomyRule{}, false
+}
+
+func rank(order []string, value string) int {
+	for i, v := range order {
+		if v == value {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Get returns the first value of a dimension. For ordered dimensions, this
+// is the highest value.
+func (d Dimensions) Get(dimension string) string {
+	if values := d[dimension]; len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// Has returns true if the dimension contains a value.
+func (d Dimensions) Has(dimension, value string) bool {
+	for _, v := range d[dimension] {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Kinds returns the kinds of an issue, e.g. bug or feature.
+func (d Dimensions) Kinds() []string { return d[DimensionKind] }
+
+// Priority returns the highest priority of an issue.
+func (d Dimensions) Priority() string { return d.Get(DimensionPriority) }
+
+// Lifecycle returns the lifecycle state of an issue, e.g. frozen or stale.
+func (d Dimensions) Lifecycle() string { return d.Get(DimensionLifecycle) }
+
+// Areas returns the areas of an issue.
+func (d Dimensions) Areas() []string { return d[DimensionArea] }
+
+// IssueFilter selects issues.
+type IssueFilter interface {
+	Match(issue Issue) bool
+}
+
+// LabelFilter is a filter over label dimensions, e.g.
+// "priority>=important-soon kind=bug".
+type LabelFilter struct {
+	taxonomy *Taxonomy
+	terms    []labelTerm
+}
+
+type labelTerm struct {
+	dimension string
+	op        string
+	value     string
+}
+
+var labelFilterOps = []string{">=", "<=", "!=", "=", ">", "<"}
+
+// ParseLabelFilter parses a label filter. Terms are separated by spaces or
+// commas and all terms must match. A term is a dimension, an operator
+// (=, !=, <, <=, >, >=) and a value. Comparisons other than = and != are only
+// allowed on ordered dimensions.
+func ParseLabelFilter(t *Taxonomy, expr string) (*LabelFilter, error) {
+	f := &LabelFilter{taxonomy: t}
+
+	fields := strings.FieldsFunc(expr, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+
+	for _, field := range fields {
+		term, err := parseLabelTerm(t, field)
+		if err != nil {
+			return nil, err
+		}
+
+		f.terms = append(f.terms, term)
+	}
+
+	return f, nil
+}
+
+func parseLabelTerm(t *Taxonomy, s string) (labelTerm, error) {
+	for _, op := range labelFilterOps {
+		i := strings.Index(s, op)
+		if i < 1 {
+			continue
+		}
+
+		term := labelTerm{dimension: s[:i], op: op, value: s[i+len(op):]}
+		if term.value == "" {
+			return labelTerm{}, errors.Errorf("missing value in filter %q", s)
+		}
+
+		rule, ok := t.rule(term.dimension)
+		if !ok {
+			return labelTerm{}, errors.Errorf("unknown dimension %q in filter %q", term.dimension, s)
+		}
+
+		if op != "=" && op != "!=" {
+			if len(rule.Order) == 0 {
+				return labelTerm{}, errors.Errorf("dimension %q is not ordered", term.dimension)
+			}
+			if rank(rule.Order, term.value) < 0 {
+				return labelTerm{}, errors.Errorf("unknown %s %q", term.dimension, term.value)
+			}
+		}
+
+		return term, nil
+	}
+
+	return labelTerm{}, errors.Errorf("invalid filter %q", s)
+}
+
+// Match returns true if the issue matches all terms in the filter.
+func (f *LabelFilter) Match(issue Issue) bool {
+	d := f.taxonomy.Classify(issue)
+	for _, term := range f.terms {
+		if !f.matchTerm(d, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f *LabelFilter) matchTerm(d Dimensions, term labelTerm) bool {
+	switch term.op {
+	case "=":
+		return d.Has(term.dimension, term.value)
+	case "!=":
+		return !d.Has(term.dimension, term.value)
+	}
+
+	value := d.Get(term.dimension)
+	if value == "" {
+		return false
+	}
+
+	level := f.taxonomy.Level(term.dimension, value)
+	want := f.taxonomy.Level(term.dimension, term.value)
+	if level < 0 {
+		return false
+	}
+
+	switch term.op {
+	case ">=":
+		return level >= want
+	case "<=":
+		return level <= want
+	case ">":
+		return level > want
+	default:
+		return level < want
+	}
+}
+
+// FilterIssues returns the issues which match a filter.
+func FilterIssues(issues []Issue, filter IssueFilter) []Issue {
+	if filter == nil {
+		return issues
+	}
+
+	filtered := []Issue{}
+	for _, issue := range issues {
+		if filter.Match(issue) {
+			filtered = append(filtered, issue)
+		}
+	}
+
+	return filtered
+}
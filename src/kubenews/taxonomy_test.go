@@ -0,0 +1,70 @@
+package kubenews
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaxonomyClassify(t *testing.T) {
+	tax := NewTaxonomy(nil)
+
+	issue := labeledIssue(1, "kind/bug", "priority/important-soon", "priority/critical-urgent",
+		"area/kubelet", "area/api", "lifecycle/frozen", "needs-rebase", "sig/node")
+	d := tax.Classify(issue)
+
+	require.Equal(t, []string{"bug"}, d.Kinds())
+	require.Equal(t, "critical-urgent", d.Priority())
+	require.Equal(t, "frozen", d.Lifecycle())
+	require.Equal(t, []string{"api", "kubelet"}, d.Areas())
+	require.Equal(t, []string{"rebase"}, d[DimensionNeeds])
+	require.Equal(t, 4, tax.Level(DimensionPriority, d.Priority()))
+}
+
+func TestTaxonomyCustomRules(t *testing.T) {
+	tax := NewTaxonomy([]TaxonomyRule{
+		{Dimension: DimensionPriority, Prefix: "P", Order: []string{"3", "2", "1", "0"}},
+		{Dimension: DimensionKind, Prefix: "type: "},
+	})
+
+	d := tax.Classify(labeledIssue(1, "P1", "type: bug"))
+	require.Equal(t, "1", d.Priority())
+	require.Equal(t, []string{"bug"}, d.Kinds())
+}
+
+func TestParseLabelFilter(t *testing.T) {
+	tax := NewTaxonomy(nil)
+
+	urgentBug := labeledIssue(1, "kind/bug", "priority/critical-urgent")
+	soonFeature := labeledIssue(2, "kind/feature", "priority/important-soon")
+	frozenBug := labeledIssue(3, "kind/bug", "priority/backlog", "lifecycle/frozen")
+	unprioritized := labeledIssue(4, "kind/bug")
+	issues := []Issue{urgentBug, soonFeature, frozenBug, unprioritized}
+
+	cases := []struct {
+		expr    string
+		numbers []int
+	}{
+		{expr: "priority>=important-soon", numbers: []int{1, 2}},
+		{expr: "priority<important-soon", numbers: []int{3}},
+		{expr: "kind=bug,lifecycle!=frozen", numbers: []int{1, 4}},
+		{expr: "kind=bug priority>backlog", numbers: []int{1}},
+		{expr: "", numbers: []int{1, 2, 3, 4}},
+	}
+
+	for _, c := range cases {
+		f, err := ParseLabelFilter(tax, c.expr)
+		require.NoError(t, err, c.expr)
+
+		numbers := []int{}
+		for _, issue := range FilterIssues(issues, f) {
+			numbers = append(numbers, issue.Number)
+		}
+		require.Equal(t, c.numbers, numbers, c.expr)
+	}
+
+	for _, expr := range []string{"priority", "kind>=bug", "color=red", "priority>=unknown", "kind="} {
+		_, err := ParseLabelFilter(tax, expr)
+		require.Error(t, err, expr)
+	}
+}
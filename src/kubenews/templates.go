@@ -0,0 +1,315 @@
+package kubenews
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Templates customize how reports are written. A template is executed with
+// the report as its data, so the fields available are those of the report
+// type:
+//
+//   digest          Digest: Repository, SIG, From, To, Opened, Closed,
+//                   OpenCount, SIGs, Metrics, TopFlakes, PossibleDuplicates,
+//                   Fixes and a FixedBy method
+//   report fixes    FixReport: Repository, Pending, Issues
+//   report stale    StaleReport: Repository, Days, Now, Groups
+//   duplicates      DuplicateReport: Repository, Threshold, Clusters
+//   flakes          FlakeReport: Repository, From, To, Flakes
+//   release-notes   ReleaseNotes: Repository, Milestone, From, To, Notes
+//
+// Every report also has a Title method. Issues and pull requests are both
+// Issue values, with Number, Title, Body, User, State, Labels, Assignee,
+// Milestone, CreatedAt, ClosedAt, PullRequest and an HTMLURL method.
+//
+// Templates whose names end in .html or .htm are parsed with html/template
+// and produce a complete HTML document. Other templates are parsed with
+// text/template and produce Markdown, which is rendered to HTML when HTML
+// output is requested.
+//
+// The functions available to templates are listed in TemplateFuncs.
+
+// BuiltinTemplates are the templates which ship with kubenews, by name.
+var BuiltinTemplates = map[string]string{
+	"digest.md":         digestTemplate,
+	"digest-summary.md": digestSummaryTemplate,
+	"digest.html":       digestHTMLTemplate,
+}
+
+// BuiltinTemplateNames returns the names of the built in templates, sorted.
+func BuiltinTemplateNames() []string {
+	names := []string{}
+	for name := range BuiltinTemplates {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Template is a report template.
+type Template struct {
+	name string
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// NewTemplate parses a template. Names ending in .html or .htm are parsed as
+// HTML templates.
+func NewTemplate(name, source string, funcs map[string]interface{}) (*Template, error) {
+	t := &Template{name: name}
+
+	var err error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".html", ".htm":
+		t.html, err = htmltemplate.New(name).Funcs(htmltemplate.FuncMap(funcs)).Parse(source)
+	default:
+		t.text, err = texttemplate.New(name).Funcs(texttemplate.FuncMap(funcs)).Parse(source)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse template %s", name)
+	}
+
+	return t, nil
+}
+
+// LoadTemplate loads a template from a file, or a built in template by name
+// if no such file exists.
+func LoadTemplate(path string, funcs map[string]interface{}) (*Template, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if source, ok := BuiltinTemplates[path]; ok {
+			return NewTemplate(path, source, funcs)
+		}
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read template %s", path)
+	}
+
+	return NewTemplate(filepath.Base(path), string(b), funcs)
+}
+
+// Name returns the name of the template.
+func (t *Template) Name() string {
+	return t.name
+}
+
+// IsHTML returns true if the template produces HTML rather than Markdown.
+func (t *Template) IsHTML() bool {
+	return t.html != nil
+}
+
+// Execute applies the template to data and writes the output to w.
+func (t *Template) Execute(w io.Writer, data interface{}) error {
+	var err error
+	if t.html != nil {
+		err = t.html.Execute(w, data)
+	} else {
+		err = t.text.Execute(w, data)
+	}
+
+	return errors.Wrapf(err, "unable to execute template %s", t.name)
+}
+
+// templateReport is a report written with a template.
+type templateReport struct {
+	Report
+	template *Template
+}
+
+// WithTemplate returns a report which is written using t.
+func WithTemplate(r Report, t *Template) Report {
+	return &templateReport{Report: r, template: t}
+}
+
+func (r *templateReport) WriteMarkdown(w io.Writer) error {
+	if r.template.IsHTML() {
+		return errors.Errorf("template %s produces HTML, not Markdown", r.template.name)
+	}
+
+	return r.template.Execute(w, r.Report)
+}
+
+// TemplateFuncs returns the functions available to templates. sigs and
+// taxonomy are used by the functions which look at labels; nil uses the
+// defaults.
+//
+//	md s                 escape text so it displays literally in Markdown
+//	cell s               escape text for a Markdown table cell
+//	link issue           Markdown link to an issue followed by its title
+//	refs issues          Markdown links to issues, e.g. [#1](...) [#2](...)
+//	anchor heading       anchor name of a heading
+//	snippet body n       the start of an issue body as one line of text
+//	truncate s n         s cut to at most n characters
+//	plural n one many    one or many depending on n, e.g. plural 2 "issue" "issues"
+//	date t               format a time as 2006-01-02
+//	datetime t           format a time as 2006-01-02 15:04 MST
+//	duration d           format a duration as e.g. 3d4h
+//	since t              duration since t, formatted as e.g. 3d4h
+//	median stats         median of DurationStats, or - if there are none
+//	p90 stats            90th percentile of DurationStats, or - if there are none
+//	issues list          issues which aren't pull requests
+//	pulls list           pull requests
+//	filter expr list     issues matching a label filter, e.g. "kind=bug"
+//	label name list      issues with a label
+//	labels issue         names of an issue's labels
+//	sigs issue           SIGs of an issue
+//	kinds issue          kinds of an issue
+//	priority issue       priority of an issue
+//	milestones list      issues grouped by milestone
+//	join list sep        join strings
+//	lower s, upper s     change case
+func TemplateFuncs(sigs *SIGMap, taxonomy *Taxonomy) map[string]interface{} {
+	sigs = sigs.orDefault()
+	if taxonomy == nil {
+		taxonomy = NewTaxonomy(nil)
+	}
+
+	return map[string]interface{}{
+		"md":       escapeMarkdown,
+		"cell":     escapeTableCell,
+		"link":     issueLink,
+		"refs":     issueRefs,
+		"anchor":   Anchor,
+		"snippet":  func(body string, n int) string { return Snippet(body, n) },
+		"truncate": truncate,
+		"plural": func(n int, one, many string) string {
+			if n == 1 {
+				return one
+			}
+			return many
+		},
+		"date":     func(t time.Time) string { return t.Format(dateFormat) },
+		"datetime": func(t time.Time) string { return t.Format("2006-01-02 15:04 MST") },
+		"duration": FormatDuration,
+		"since":    func(t time.Time) string { return FormatDuration(time.Since(t)) },
+		"median":   func(s DurationStats) string { return formatStat(s, s.Median) },
+		"p90":      func(s DurationStats) string { return formatStat(s, s.P90) },
+		"issues": func(issues []Issue) []Issue {
+			return FilterIssues(issues, pullRequestFilter(false))
+		},
+		"pulls": func(issues []Issue) []Issue {
+			return FilterIssues(issues, pullRequestFilter(true))
+		},
+		"filter": func(expr string, issues []Issue) ([]Issue, error) {
+			f, err := ParseLabelFilter(taxonomy, expr)
+			if err != nil {
+				return nil, err
+			}
+			return FilterIssues(issues, f), nil
+		},
+		"label": func(name string, issues []Issue) []Issue {
+			return FilterIssues(issues, hasLabelFilter(name))
+		},
+		"labels": func(issue Issue) []string {
+			names := []string{}
+			for _, label := range issue.Labels {
+				names = append(names, label.Name)
+			}
+			return names
+		},
+		"sigs":       sigs.SIGs,
+		"kinds":      func(issue Issue) []string { return taxonomy.Classify(issue).Kinds() },
+		"priority":   func(issue Issue) string { return taxonomy.Classify(issue).Priority() },
+		"milestones": GroupByMilestone,
+		"join":       strings.Join,
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+	}
+}
+
+// truncate cuts s to at most n characters, ending in "…" if it was cut.
+func truncate(s string, n int) string {
+	switch {
+	case n <= 0:
+		return ""
+	case utf8.RuneCountInString(s) <= n:
+		return s
+	}
+
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+type pullRequestFilter bool
+
+func (f pullRequestFilter) Match(issue Issue) bool {
+	return issue.PullRequest == bool(f)
+}
+
+type hasLabelFilter string
+
+func (f hasLabelFilter) Match(issue Issue) bool {
+	for _, label := range issue.Labels {
+		if label.Name == string(f) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MilestoneGroup is the issues in a milestone.
+type MilestoneGroup struct {
+	Milestone string
+	Issues    []Issue
+}
+
+// GroupByMilestone groups issues by milestone, sorted by milestone name.
+// Issues without a milestone come last.
+func GroupByMilestone(issues []Issue) []MilestoneGroup {
+	byMilestone := map[string][]Issue{}
+	for _, issue := range issues {
+		byMilestone[issue.Milestone] = append(byMilestone[issue.Milestone], issue)
+	}
+
+	names := []string{}
+	for name := range byMilestone {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := byMilestone[""]; ok {
+		names = append(names, "")
+	}
+
+	groups := []MilestoneGroup{}
+	for _, name := range names {
+		groups = append(groups, MilestoneGroup{Milestone: name, Issues: byMilestone[name]})
+	}
+
+	return groups
+}
+
+// issueRefs formats Markdown links to issues, separated by spaces.
+func issueRefs(issues []Issue) string {
+	refs := []string{}
+	for _, issue := range issues {
+		refs = append(refs, fmt.Sprintf("[#%d](%s)", issue.Number, issue.HTMLURL()))
+	}
+
+	return strings.Join(refs, " ")
+}
+
+// digestMarkdown is the built in digest template.
+var digestMarkdown = mustTemplate("digest.md")
+
+func mustTemplate(name string) *Template {
+	t, err := NewTemplate(name, BuiltinTemplates[name], TemplateFuncs(nil, nil))
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
@@ -0,0 +1,74 @@
+package kubenews
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func templateDigest() *Digest {
+	from := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	during := from.AddDate(0, 0, 1)
+
+	bug := labeledIssue(1, "sig/node", "kind/bug", "priority/critical-urgent")
+	bug.Title = "Kubelet <crash>"
+	bug.Milestone = "v1.7"
+	bug.CreatedAt = &during
+	bug.State = "open"
+
+	pr := labeledIssue(2, "sig/node")
+	pr.PullRequest = true
+	pr.CreatedAt = &during
+	pr.State = "open"
+
+	return NewDigest(DigestOptions{Repository: "org/repo", From: from, To: from.AddDate(0, 0, 7)},
+		[]Issue{bug, pr}, []Issue{bug, pr})
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	d := templateDigest()
+	for _, name := range BuiltinTemplateNames() {
+		tmpl, err := LoadTemplate(name, TemplateFuncs(nil, nil))
+		require.NoError(t, err, name)
+
+		var buf bytes.Buffer
+		require.NoError(t, tmpl.Execute(&buf, d), name)
+		require.Contains(t, buf.String(), "org/repo digest", name)
+	}
+
+	tmpl, err := LoadTemplate("digest.html", TemplateFuncs(nil, nil))
+	require.NoError(t, err)
+	require.True(t, tmpl.IsHTML())
+
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Execute(&buf, d))
+	require.Contains(t, buf.String(), "Kubelet &lt;crash&gt;")
+
+	buf.Reset()
+	tmpl, err = LoadTemplate("digest-summary.md", TemplateFuncs(nil, nil))
+	require.NoError(t, err)
+	require.NoError(t, tmpl.Execute(&buf, d))
+	require.Contains(t, buf.String(), "1 issue and 1 pull request opened")
+	require.Contains(t, buf.String(), "New critical issues:")
+
+	_, err = LoadTemplate("missing.md", nil)
+	require.Error(t, err)
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	source := `{{range milestones .Opened}}{{or .Milestone "none"}}: {{len .Issues}}
+{{end}}{{range filter "kind=bug" .Opened}}{{.Number}} {{priority .}} {{join (sigs .) ","}}
+{{end}}{{len (label "sig/node" .Opened)}} {{plural 1 "flake" "flakes"}} {{truncate "abcdef" 4}}{{truncate "abc" 0}}
+`
+	tmpl, err := NewTemplate("custom.md", source, TemplateFuncs(nil, nil))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteReport(&buf, WithTemplate(templateDigest(), tmpl), FormatMarkdown, nil))
+	require.Equal(t, "v1.7: 1\nnone: 1\n1 critical-urgent node\n2 flake abc…\n", buf.String())
+
+	_, err = NewTemplate("bad.md", "{{filter}", TemplateFuncs(nil, nil))
+	require.Error(t, err)
+}
@@ -0,0 +1,335 @@
+package kubenews
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// WebhookRoute sends messages to a Slack compatible incoming webhook. A route
+// with SIGs or labels only gets messages about issues with one of them. A
+// route with neither gets every message.
+type WebhookRoute struct {
+	Name   string   `mapstructure:"name"`
+	URL    string   `mapstructure:"url"`
+	SIGs   []string `mapstructure:"sigs"`
+	Labels []string `mapstructure:"labels"`
+}
+
+// Matches returns true if the route wants messages about an issue.
+func (r WebhookRoute) Matches(issue Issue, sigs *SIGMap) bool {
+	if len(r.SIGs) == 0 && len(r.Labels) == 0 {
+		return true
+	}
+
+	sigs = sigs.orDefault()
+	for _, sig := range r.SIGs {
+		if sigs.HasSIG(issue, sig) {
+			return true
+		}
+	}
+
+	for _, label := range r.Labels {
+		if hasLabelFilter(label).Match(issue) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter returns a filter selecting the issues the route wants messages about.
+func (r WebhookRoute) Filter(sigs *SIGMap) IssueFilter {
+	return routeFilter{route: r, sigs: sigs}
+}
+
+type routeFilter struct {
+	route WebhookRoute
+	sigs  *SIGMap
+}
+
+func (f routeFilter) Match(issue Issue) bool {
+	return f.route.Matches(issue, f.sigs)
+}
+
+// AlertRule selects issues to alert about as soon as they are seen. Filter is
+// a label filter and Query a query or the name of a saved query. Issues must
+// match both.
+type AlertRule struct {
+	Name   string `mapstructure:"name"`
+	Filter string `mapstructure:"filter"`
+	Query  string `mapstructure:"query"`
+}
+
+// DefaultAlertRules alert on new critical issues.
+var DefaultAlertRules = []AlertRule{
+	{Name: "critical-urgent", Filter: "priority=critical-urgent"},
+}
+
+// WebhookMessage is a message in the Slack incoming webhook format. Text is
+// shown in notifications and by clients which don't support blocks.
+type WebhookMessage struct {
+	Text   string  `json:"text"`
+	Blocks []Block `json:"blocks,omitempty"`
+}
+
+// Block is a Slack layout block.
+type Block struct {
+	Type     string      `json:"type"`
+	Text     *BlockText  `json:"text,omitempty"`
+	Elements []BlockText `json:"elements,omitempty"`
+}
+
+// BlockText is a text object in a block.
+type BlockText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func headerBlock(text string) Block {
+	return Block{Type: "header", Text: &BlockText{Type: "plain_text", Text: text}}
+}
+
+func sectionBlock(mrkdwn string) Block {
+	return Block{Type: "section", Text: &BlockText{Type: "mrkdwn", Text: mrkdwn}}
+}
+
+func contextBlock(mrkdwn string) Block {
+	return Block{Type: "context", Elements: []BlockText{{Type: "mrkdwn", Text: mrkdwn}}}
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeSlack escapes text for Slack mrkdwn.
+func escapeSlack(s string) string {
+	return slackEscaper.Replace(s)
+}
+
+// slackLink formats a Slack link to an issue followed by its title.
+func slackLink(issue Issue) string {
+	return fmt.Sprintf("<%s|#%d> %s", issue.HTMLURL(), issue.Number, escapeSlack(issue.Title))
+}
+
+// slackList formats up to limit issues as a bulleted list.
+func slackList(issues []Issue, limit int) string {
+	lines := []string{}
+	for i, issue := range issues {
+		if i == limit {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(issues)-limit))
+			break
+		}
+		lines = append(lines, "• "+slackLink(issue))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// NewDigestWebhookMessage condenses a digest into a webhook message, listing
+// at most limit opened and closed issues.
+func NewDigestWebhookMessage(d *Digest, limit int) WebhookMessage {
+	summary := fmt.Sprintf("%s to %s: %d opened, %d closed, %d open", d.From.Format(dateFormat),
+		d.To.Format(dateFormat), len(d.Opened), len(d.Closed), d.OpenCount)
+
+	msg := WebhookMessage{
+		Text:   d.Title() + ": " + summary,
+		Blocks: []Block{headerBlock(d.Title()), contextBlock(summary)},
+	}
+
+	if d.SIG == "" && len(d.SIGs) > 0 {
+		lines := []string{}
+		for _, s := range d.SIGs {
+			if s.Opened > 0 || s.Closed > 0 {
+				lines = append(lines, fmt.Sprintf("*%s* +%d -%d (%d open)", escapeSlack(s.Name), s.Opened, s.Closed, s.Open))
+			}
+		}
+		if len(lines) > 0 {
+			msg.Blocks = append(msg.Blocks, sectionBlock(strings.Join(lines, "\n")))
+		}
+	}
+
+	if len(d.Opened) > 0 {
+		msg.Blocks = append(msg.Blocks, sectionBlock("*Opened*\n"+slackList(d.Opened, limit)))
+	}
+	if len(d.Closed) > 0 {
+		msg.Blocks = append(msg.Blocks, sectionBlock("*Closed*\n"+slackList(d.Closed, limit)))
+	}
+
+	if len(d.TopFlakes) > 0 {
+		lines := []string{}
+		for _, ft := range d.TopFlakes {
+			lines = append(lines, fmt.Sprintf("• %s (%d)", escapeSlack(ft.Name), ft.Occurrences))
+		}
+		msg.Blocks = append(msg.Blocks, sectionBlock("*Top flakes*\n"+strings.Join(lines, "\n")))
+	}
+
+	return msg
+}
+
+// NewAlertWebhookMessage creates a message alerting about an issue.
+func NewAlertWebhookMessage(issue Issue, rule AlertRule, sigs *SIGMap) WebhookMessage {
+	kind := "issue"
+	if issue.PullRequest {
+		kind = "pull request"
+	}
+
+	details := []string{fmt.Sprintf("%s in %s", escapeSlack(rule.Name), escapeSlack(issue.Repository))}
+	if names := sigs.orDefault().SIGs(issue); len(names) > 0 && names[0] != NoSIG {
+		details = append(details, "sig/"+strings.Join(names, ", sig/"))
+	}
+	if issue.Assignee != "" {
+		details = append(details, "assigned to @"+escapeSlack(issue.Assignee))
+	}
+
+	return WebhookMessage{
+		Text: fmt.Sprintf("%s %s #%d: %s", rule.Name, kind, issue.Number, issue.Title),
+		Blocks: []Block{
+			sectionBlock(fmt.Sprintf(":rotating_light: *%s*", slackLink(issue))),
+			contextBlock(strings.Join(details, " · ")),
+		},
+	}
+}
+
+// WebhookPublisher posts messages to incoming webhooks, retrying failed
+// requests with exponential backoff.
+type WebhookPublisher struct {
+	Client *http.Client
+	// MaxAttempts is the number of times a message is posted before giving up.
+	MaxAttempts int
+	// Backoff is the delay before the first retry. It doubles on each retry.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries, including one asked for
+	// with Retry-After.
+	MaxBackoff time.Duration
+}
+
+// NewWebhookPublisher creates an instance of WebhookPublisher.
+func NewWebhookPublisher() *WebhookPublisher {
+	return &WebhookPublisher{
+		Client:      &http.Client{Timeout: 30 * time.Second},
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Minute,
+	}
+}
+
+// Publish posts a message to a webhook URL. Network errors, rate limiting and
+// server errors are retried until ctx is done. A Retry-After header is
+// respected, up to MaxBackoff.
+func (p *WebhookPublisher) Publish(ctx context.Context, url string, msg WebhookMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "encode webhook message")
+	}
+
+	delay := p.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, wait, err := p.post(ctx, url, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		if wait > delay {
+			delay = wait
+		}
+		if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+			delay = p.MaxBackoff
+		}
+
+		log.WithError(err).WithFields(log.Fields{
+			"attempt": attempt,
+			"delay":   delay,
+		}).Warn("webhook failed, retrying")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Wrap(ctx.Err(), "stopped retrying webhook")
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// post makes a single request. It returns whether a failure can be retried and
+// how long the server asked to wait.
+func (p *WebhookPublisher) post(ctx context.Context, url string, body []byte) (bool, time.Duration, error) {
+	resp, err := ctxhttp.Post(ctx, p.Client, url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return ctx.Err() == nil, 0, errors.Wrap(err, "post webhook")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return false, 0, nil
+	}
+
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = errors.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(b)))
+
+	var wait time.Duration
+	if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil {
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, wait, err
+}
+
+// AlertCandidates returns the open issues matching an alert rule which were
+// updated since a time.
+func AlertCandidates(issues []Issue, filter IssueFilter, since time.Time) []Issue {
+	out := []Issue{}
+	for _, issue := range issues {
+		if issue.State != "open" || issue.UpdatedAt == nil || issue.UpdatedAt.Before(since) {
+			continue
+		}
+		if filter.Match(issue) {
+			out = append(out, issue)
+		}
+	}
+
+	return out
+}
+
+// AlertSent returns true if an alert for an issue was already sent to a route.
+func (s *sqlStore) AlertSent(repository string, number int, alert, route string) (bool, error) {
+	var count int
+	if err := s.get(&count, alertSentSQL, repository, number, alert, route); err != nil {
+		return false, errors.Wrap(err, "unable to check alert")
+	}
+
+	return count > 0, nil
+}
+
+// RecordAlert records that an alert for an issue was sent to a route.
+func (s *sqlStore) RecordAlert(repository string, number int, alert, route string, sentAt time.Time) error {
+	err := s.exec(s.db, insertAlertSQL, repository, number, alert, route, sentAt)
+	return errors.Wrap(err, "record alert")
+}
+
+var (
+	alertSentSQL = `
+  SELECT COUNT(*) FROM webhook_alerts
+  WHERE repository = $1 AND issue_number = $2 AND alert = $3 AND route = $4`
+
+	insertAlertSQL = `
+  INSERT INTO webhook_alerts (repository, issue_number, alert, route, sent_at)
+  VALUES ($1, $2, $3, $4, $5)
+  ON CONFLICT DO NOTHING`
+)
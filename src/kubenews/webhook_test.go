@@ -0,0 +1,101 @@
+package kubenews
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestWebhookPublisher(t *testing.T) {
+	attempts := 0
+	received := WebhookMessage{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	p := NewWebhookPublisher()
+	p.Backoff = time.Millisecond
+
+	msg := NewDigestWebhookMessage(templateDigest(), 1)
+	require.NoError(t, p.Publish(context.Background(), server.URL, msg))
+	require.Equal(t, 3, attempts)
+	require.Equal(t, msg, received)
+	require.Contains(t, msg.Blocks[3].Text.Text, "<https://github.com/org/repo/issues/1|#1> Kubelet &lt;crash&gt;")
+	require.Contains(t, msg.Blocks[3].Text.Text, "…and 1 more")
+
+	attempts = 0
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "invalid_payload", http.StatusBadRequest)
+	}))
+	defer bad.Close()
+
+	err := p.Publish(context.Background(), bad.URL, msg)
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "invalid_payload"))
+	require.Equal(t, 1, attempts)
+}
+
+func TestWebhookPublisherRetryAfter(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	// Retry-After is capped at MaxBackoff
+	p := NewWebhookPublisher()
+	p.MaxAttempts = 2
+	p.MaxBackoff = time.Millisecond
+	require.Error(t, p.Publish(context.Background(), server.URL, WebhookMessage{}))
+	require.Equal(t, 2, attempts)
+
+	// waiting for a retry stops when the context is done
+	attempts = 0
+	p.MaxBackoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := p.Publish(ctx, server.URL, WebhookMessage{})
+	require.Error(t, err)
+	require.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+	require.Equal(t, 1, attempts)
+}
+
+func TestWebhookRouteMatches(t *testing.T) {
+	issue := labeledIssue(1, "sig/node", "priority/critical-urgent")
+
+	require.True(t, WebhookRoute{}.Matches(issue, nil))
+	require.True(t, WebhookRoute{SIGs: []string{"node"}}.Matches(issue, nil))
+	require.False(t, WebhookRoute{SIGs: []string{"storage"}}.Matches(issue, nil))
+	require.True(t, WebhookRoute{SIGs: []string{"storage"}, Labels: []string{"priority/critical-urgent"}}.Matches(issue, nil))
+
+	filter, err := ParseLabelFilter(NewTaxonomy(nil), DefaultAlertRules[0].Filter)
+	require.NoError(t, err)
+
+	now := time.Now()
+	issue.State = "open"
+	issue.UpdatedAt = &now
+	require.Len(t, AlertCandidates([]Issue{issue, labeledIssue(2, "sig/node")}, filter, now.Add(-time.Hour)), 1)
+	require.Empty(t, AlertCandidates([]Issue{issue}, filter, now.Add(time.Hour)))
+
+	msg := NewAlertWebhookMessage(issue, DefaultAlertRules[0], nil)
+	require.Contains(t, msg.Text, "critical-urgent issue #1")
+	require.Contains(t, msg.Blocks[1].Elements[0].Text, "sig/node")
+}